@@ -0,0 +1,70 @@
+package integration_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/RichardKnop/machinery/v1/config"
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"github.com/streadway/amqp"
+)
+
+func TestAmqpDrainQueue(t *testing.T) {
+	amqpURL := os.Getenv("AMQP_URL")
+	if amqpURL == "" {
+		return
+	}
+
+	// AMQP broker, AMQP result backend
+	server := testSetup(&config.Config{
+		Broker:        amqpURL,
+		DefaultQueue:  "test_drain_queue",
+		ResultBackend: amqpURL,
+		AMQP: &config.AMQPConfig{
+			Exchange:     "test_exchange",
+			ExchangeType: "direct",
+			BindingKey:   "test_drain_queue",
+		},
+	})
+	queue := server.GetConfig().DefaultQueue
+
+	for i := 0; i < 2; i++ {
+		if _, err := server.SendTask(newAddTask(1, 1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	conn, err := amqp.Dial(amqpURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	channel, err := conn.Channel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer channel.Close()
+	if err := channel.Publish("", queue, false, false, amqp.Publishing{Body: []byte("not a task")}); err != nil {
+		t.Fatal(err)
+	}
+
+	var drained []*tasks.Signature
+	count, err := server.DrainQueue(queue, func(signature *tasks.Signature) {
+		drained = append(drained, signature)
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if count != 2 || len(drained) != 2 {
+		t.Errorf("%d tasks drained, should be %d", count, 2)
+	}
+
+	// The message which cannot be decoded is left in the queue
+	purged, err := server.PurgeQueue(queue)
+	if err != nil {
+		t.Error(err)
+	}
+	if purged != 1 {
+		t.Errorf("%d messages purged, should be %d", purged, 1)
+	}
+}
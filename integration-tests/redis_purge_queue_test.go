@@ -0,0 +1,60 @@
+package integration_test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/RichardKnop/machinery/v1/config"
+	"github.com/RichardKnop/machinery/v1/tasks"
+)
+
+func TestRedisPurgeQueue(t *testing.T) {
+	redisURL := os.Getenv("REDIS_URL")
+	if redisURL == "" {
+		return
+	}
+
+	// Redis broker, Redis result backend
+	server := testSetup(&config.Config{
+		Broker:        fmt.Sprintf("redis://%v", redisURL),
+		DefaultQueue:  "test_purge_queue",
+		ResultBackend: fmt.Sprintf("redis://%v", redisURL),
+	})
+	queue := server.GetConfig().DefaultQueue
+
+	if _, err := server.PurgeQueue(queue); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := server.SendTask(newAddTask(1, 1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var drained []*tasks.Signature
+	count, err := server.DrainQueue(queue, func(signature *tasks.Signature) {
+		drained = append(drained, signature)
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if count != 3 || len(drained) != 3 {
+		t.Errorf("%d tasks drained, should be %d", count, 3)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := server.SendTask(newAddTask(1, 1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	purged, err := server.PurgeQueue(queue)
+	if err != nil {
+		t.Error(err)
+	}
+	if purged != 2 {
+		t.Errorf("%d tasks purged, should be %d", purged, 2)
+	}
+}
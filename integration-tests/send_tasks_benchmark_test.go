@@ -0,0 +1,90 @@
+package integration_test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/RichardKnop/machinery/v1"
+	"github.com/RichardKnop/machinery/v1/config"
+	"github.com/RichardKnop/machinery/v1/tasks"
+)
+
+// benchmarkBatchSize is the number of tasks sent per benchmark iteration, the
+// in-memory eager backend is used so that only publishing is measured
+const benchmarkBatchSize = 100
+
+func BenchmarkSendTaskAMQP(b *testing.B) {
+	benchmarkSendTask(b, amqpBenchmarkServer(b))
+}
+
+func BenchmarkSendTasksAMQP(b *testing.B) {
+	benchmarkSendTasks(b, amqpBenchmarkServer(b))
+}
+
+func BenchmarkSendTaskRedis(b *testing.B) {
+	benchmarkSendTask(b, redisBenchmarkServer(b))
+}
+
+func BenchmarkSendTasksRedis(b *testing.B) {
+	benchmarkSendTasks(b, redisBenchmarkServer(b))
+}
+
+// benchmarkSendTask sends batches of tasks one by one
+func benchmarkSendTask(b *testing.B, server *machinery.Server) {
+	for i := 0; i < b.N; i++ {
+		for _, signature := range newBenchmarkBatch() {
+			if _, err := server.SendTask(signature); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// benchmarkSendTasks sends batches of tasks at once
+func benchmarkSendTasks(b *testing.B, server *machinery.Server) {
+	for i := 0; i < b.N; i++ {
+		if _, err := server.SendTasks(newBenchmarkBatch()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func newBenchmarkBatch() []*tasks.Signature {
+	signatures := make([]*tasks.Signature, benchmarkBatchSize)
+	for i := range signatures {
+		signatures[i] = newAddTask(i, i)
+	}
+	return signatures
+}
+
+func amqpBenchmarkServer(b *testing.B) *machinery.Server {
+	amqpURL := os.Getenv("AMQP_URL")
+	if amqpURL == "" {
+		b.Skip("AMQP_URL is not set")
+	}
+
+	return testSetup(&config.Config{
+		Broker:        amqpURL,
+		DefaultQueue:  "benchmark_queue",
+		ResultBackend: "eager",
+		AMQP: &config.AMQPConfig{
+			Exchange:     "benchmark_exchange",
+			ExchangeType: "direct",
+			BindingKey:   "benchmark_task",
+		},
+	})
+}
+
+func redisBenchmarkServer(b *testing.B) *machinery.Server {
+	redisURL := os.Getenv("REDIS_URL")
+	if redisURL == "" {
+		b.Skip("REDIS_URL is not set")
+	}
+
+	return testSetup(&config.Config{
+		Broker:        fmt.Sprintf("redis://%v", redisURL),
+		DefaultQueue:  "benchmark_queue",
+		ResultBackend: "eager",
+	})
+}
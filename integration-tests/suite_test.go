@@ -22,6 +22,7 @@ func (a ascendingInt64s) Less(i, j int) bool { return a[i] < a[j] }
 
 func testAll(server *machinery.Server, t *testing.T) {
 	testSendTask(server, t)
+	testSendTasks(server, t)
 	testSendGroup(server, t, 0) // with unlimited concurrency
 	testSendGroup(server, t, 2) // with limited concurrency (2 parallel tasks at the most)
 	testSendChord(server, t)
@@ -81,6 +82,38 @@ func testSendTask(server *machinery.Server, t *testing.T) {
 	}
 }
 
+func testSendTasks(server *machinery.Server, t *testing.T) {
+	asyncResults, err := server.SendTasks([]*tasks.Signature{
+		newAddTask(1, 1),
+		newAddTask(2, 2),
+		newAddTask(3, 3),
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	for i, asyncResult := range asyncResults {
+		results, err := asyncResult.Get(time.Duration(time.Millisecond * 5))
+		if err != nil {
+			t.Error(err)
+		}
+
+		if len(results) != 1 {
+			t.Errorf("Number of results returned = %d. Wanted %d", len(results), 1)
+		}
+
+		expected := int64(2 * (i + 1))
+		if results[0].Interface() != expected {
+			t.Errorf(
+				"result = %v(%v), want int64(%d)",
+				results[0].Type().String(),
+				results[0].Interface(),
+				expected,
+			)
+		}
+	}
+}
+
 func testSendGroup(server *machinery.Server, t *testing.T, sendConcurrency int) {
 	t1, t2, t3 := newAddTask(1, 1), newAddTask(2, 2), newAddTask(5, 6)
 
@@ -281,7 +314,7 @@ func testPanic(server *machinery.Server, t *testing.T) {
 	if len(results) != 0 {
 		t.Errorf("Number of results returned = %d. Wanted %d", len(results), 0)
 	}
-	assert.Equal(t, "oops", err.Error())
+	assert.Equal(t, "Invoking task caused a panic: task panic: oops", err.Error())
 }
 
 func testDelay(server *machinery.Server, t *testing.T) {
@@ -0,0 +1,196 @@
+package machinery
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/RichardKnop/machinery/v1/log"
+	"github.com/RichardKnop/machinery/v1/tasks"
+)
+
+// AuditRedacted replaces values of redacted args in audit entries
+const AuditRedacted = "[REDACTED]"
+
+// AuditSink receives an entry for every outcome of a task processed by
+// workers, i.e. each time the task function returned. Unlike Metrics it is
+// meant for a durable record of task history, e.g. for compliance.
+type AuditSink interface {
+	Record(entry AuditEntry)
+}
+
+// AuditEntry records who ran a task, with which args and how it ended
+type AuditEntry struct {
+	TaskUUID string      `json:"task_uuid"`
+	TaskName string      `json:"task_name"`
+	Args     []tasks.Arg `json:"args"`
+	// Outcome is the state the task ended in, SUCCESS, FAILURE or RETRY
+	Outcome    string    `json:"outcome"`
+	Error      string    `json:"error,omitempty"`
+	Retries    int       `json:"retries"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	// Worker identifies the worker like WorkerInfo.ID
+	Worker string `json:"worker"`
+}
+
+// SetAuditSink sets the sink receiving audit entries of processed tasks, nil
+// disables auditing
+func (server *Server) SetAuditSink(sink AuditSink) {
+	server.auditSink = sink
+}
+
+// GetAuditSink returns the sink receiving audit entries, nil if none
+func (server *Server) GetAuditSink() AuditSink {
+	return server.auditSink
+}
+
+// WithAuditRedaction makes audit entries of the task hide values of args
+// with the names, as well as values of the keys with the names in args
+// holding structs or maps, so personal data does not end up in the audit log
+func WithAuditRedaction(names ...string) TaskOption {
+	return func(defaults *taskDefaults) {
+		defaults.auditRedaction = append(defaults.auditRedaction, names...)
+	}
+}
+
+// audit records the outcome of the task started at startedAt with the audit
+// sink of the server, if any
+func (worker *Worker) audit(signature *tasks.Signature, startedAt time.Time, outcome string, taskErr error) {
+	sink := worker.server.GetAuditSink()
+	if sink == nil {
+		return
+	}
+
+	var redaction []string
+	if defaults := worker.server.getTaskDefaults(signature.Name); defaults != nil {
+		redaction = defaults.auditRedaction
+	}
+
+	entry := AuditEntry{
+		TaskUUID:   signature.UUID,
+		TaskName:   signature.Name,
+		Args:       redactArgs(signature.Args, redaction),
+		Outcome:    outcome,
+		Retries:    retries(signature),
+		StartedAt:  startedAt.UTC(),
+		FinishedAt: time.Now().UTC(),
+		Worker:     worker.info().ID,
+	}
+	if taskErr != nil {
+		entry.Error = taskErr.Error()
+	}
+	sink.Record(entry)
+}
+
+// redactArgs returns a copy of the args with values of the named args, and
+// of the named keys of map values, replaced by AuditRedacted
+func redactArgs(args []tasks.Arg, names []string) []tasks.Arg {
+	redacted := make([]tasks.Arg, len(args))
+	copy(redacted, args)
+	if len(names) == 0 {
+		return redacted
+	}
+
+	redact := make(map[string]bool, len(names))
+	for _, name := range names {
+		redact[name] = true
+	}
+	for i, arg := range redacted {
+		if redact[arg.Name] {
+			redacted[i].Value = AuditRedacted
+			continue
+		}
+		redacted[i].Value = redactValue(arg.Value, redact)
+	}
+	return redacted
+}
+
+// redactValue returns a copy of the value with the named keys of maps,
+// nested ones included, replaced by AuditRedacted. Structs are encoded to
+// JSON first like they are when the task is sent.
+func redactValue(value interface{}, redact map[string]bool) interface{} {
+	switch value.(type) {
+	case nil, string, bool, json.Number, float64, float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return value
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return value
+	}
+	return redactDecoded(decoded, redact)
+}
+
+func redactDecoded(value interface{}, redact map[string]bool) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, item := range value {
+			if redact[key] {
+				value[key] = AuditRedacted
+			} else {
+				value[key] = redactDecoded(item, redact)
+			}
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = redactDecoded(item, redact)
+		}
+	}
+	return value
+}
+
+// JSONLinesAuditSink writes audit entries to a writer, one JSON object per
+// line, it is safe to use from multiple workers
+type JSONLinesAuditSink struct {
+	mu     sync.Mutex
+	writer io.Writer
+	closer io.Closer
+}
+
+// NewJSONLinesAuditSink creates JSONLinesAuditSink instance writing to the
+// writer
+func NewJSONLinesAuditSink(writer io.Writer) *JSONLinesAuditSink {
+	return &JSONLinesAuditSink{writer: writer}
+}
+
+// OpenJSONLinesAuditFile creates JSONLinesAuditSink instance appending to the
+// file at the path, it is created if it does not exist
+func OpenJSONLinesAuditFile(path string) (*JSONLinesAuditSink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONLinesAuditSink{writer: file, closer: file}, nil
+}
+
+// Record writes the entry as a line of JSON, errors are logged as the outcome
+// of the task is already decided
+func (sink *JSONLinesAuditSink) Record(entry AuditEntry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		log.ERROR.Printf("Marshal audit entry of task %s error: %s", entry.TaskUUID, err)
+		return
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if _, err := sink.writer.Write(append(line, '\n')); err != nil {
+		log.ERROR.Printf("Write audit entry of task %s error: %s", entry.TaskUUID, err)
+	}
+}
+
+// Close closes the file opened by OpenJSONLinesAuditFile, it does nothing for
+// sinks writing to other writers
+func (sink *JSONLinesAuditSink) Close() error {
+	if sink.closer == nil {
+		return nil
+	}
+	return sink.closer.Close()
+}
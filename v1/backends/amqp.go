@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/RichardKnop/machinery/v1/common"
 	"github.com/RichardKnop/machinery/v1/config"
@@ -184,6 +185,21 @@ func (b *AMQPBackend) SetStateFailure(signature *tasks.Signature, err string) er
 	return b.markTaskCompleted(signature, taskState)
 }
 
+// SetStateExpired updates task state to EXPIRED
+func (b *AMQPBackend) SetStateExpired(signature *tasks.Signature) error {
+	taskState := tasks.NewExpiredTaskState(signature)
+
+	if err := b.updateState(taskState); err != nil {
+		return err
+	}
+
+	if signature.GroupUUID == "" {
+		return nil
+	}
+
+	return b.markTaskCompleted(signature, taskState)
+}
+
 // GetState returns the latest task state. It will only return the status once
 // as the message will get consumed and removed from the queue.
 func (b *AMQPBackend) GetState(taskUUID string) (*tasks.TaskState, error) {
@@ -318,12 +334,7 @@ func (b *AMQPBackend) updateState(taskState *tasks.TaskState) error {
 
 // getExpiresIn returns expiration time
 func (b *AMQPBackend) getExpiresIn() int {
-	resultsExpireIn := b.cnf.ResultsExpireIn * 1000
-	if resultsExpireIn == 0 {
-		// // expire results after 1 hour by default
-		resultsExpireIn = 3600 * 1000
-	}
-	return resultsExpireIn
+	return int(resultsExpireIn(b.cnf) / time.Millisecond)
 }
 
 // markTaskCompleted marks task as completed in either groupdUUID_success
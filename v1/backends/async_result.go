@@ -1,7 +1,9 @@
 package backends
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"reflect"
 	"time"
 
@@ -13,6 +15,14 @@ var (
 	ErrBackendNotConfigured = errors.New("Result backend not configured")
 	// ErrTimeoutReached ...
 	ErrTimeoutReached = errors.New("Timeout reached")
+	// ErrTaskExpired ...
+	ErrTaskExpired = errors.New("Task expired")
+	// ErrResultExpired ...
+	ErrResultExpired = errors.New("Result expired or not found")
+	// ErrTaskRevoked ...
+	ErrTaskRevoked = errors.New("Task revoked")
+	// ErrResultNotReady ...
+	ErrResultNotReady = errors.New("Result not ready")
 )
 
 // AsyncResult represents a task result
@@ -71,11 +81,62 @@ func NewChainAsyncResult(tasks []*tasks.Signature, backend Interface) *ChainAsyn
 
 // Touch the state and don't wait
 func (asyncResult *AsyncResult) Touch() ([]reflect.Value, error) {
+	succeeded, err := asyncResult.touch()
+	if !succeeded {
+		return nil, err
+	}
+	return tasks.ReflectTaskResults(asyncResult.taskState.Results)
+}
+
+// GetInto decodes results of the task into dest without waiting, the way
+// json.Unmarshal does, so dest can be of the type the task returned, e.g. a
+// struct, instead of the JSON decoded values Get returns. A single result is
+// decoded as it is, several results are decoded as a JSON array, e.g. into a
+// slice. It returns ErrResultNotReady if the task has not succeeded yet and
+// the error of the task if it failed.
+func (asyncResult *AsyncResult) GetInto(dest interface{}) error {
+	succeeded, err := asyncResult.touch()
+	if err != nil {
+		return err
+	}
+	if !succeeded {
+		return ErrResultNotReady
+	}
+
+	values := make([]interface{}, len(asyncResult.taskState.Results))
+	for i, taskResult := range asyncResult.taskState.Results {
+		values[i] = taskResult.Value
+	}
+	var encoded []byte
+	if len(values) == 1 {
+		encoded, err = json.Marshal(values[0])
+	} else {
+		encoded, err = json.Marshal(values)
+	}
+	if err != nil {
+		return fmt.Errorf("Marshal task results error: %s", err)
+	}
+
+	if err := json.Unmarshal(encoded, dest); err != nil {
+		return fmt.Errorf("Unmarshal task results error: %s", err)
+	}
+	return nil
+}
+
+// touch updates the task state without waiting, it returns true once the
+// task succeeded or an error if it failed or was dropped
+func (asyncResult *AsyncResult) touch() (bool, error) {
 	if asyncResult.backend == nil {
-		return nil, ErrBackendNotConfigured
+		return false, ErrBackendNotConfigured
 	}
 
-	asyncResult.GetState()
+	if err := asyncResult.getState(); err != nil {
+		// The state is stored before the task is sent, so once it is missing it
+		// has either expired after ResultsExpireIn or been purged
+		if _, ok := err.(ErrTasknotFound); ok {
+			return false, ErrResultExpired
+		}
+	}
 
 	// Purge state if we are using AMQP backend
 	if IsAMQP(asyncResult.backend) && asyncResult.taskState.IsCompleted() {
@@ -83,14 +144,18 @@ func (asyncResult *AsyncResult) Touch() ([]reflect.Value, error) {
 	}
 
 	if asyncResult.taskState.IsFailure() {
-		return nil, errors.New(asyncResult.taskState.Error)
+		return false, errors.New(asyncResult.taskState.Error)
+	}
+
+	if asyncResult.taskState.IsExpired() {
+		return false, ErrTaskExpired
 	}
 
-	if asyncResult.taskState.IsSuccess() {
-		return tasks.ReflectTaskResults(asyncResult.taskState.Results)
+	if asyncResult.taskState.IsRevoked() {
+		return false, ErrTaskRevoked
 	}
 
-	return nil, nil
+	return asyncResult.taskState.IsSuccess(), nil
 }
 
 // Get returns task results (synchronous blocking call)
@@ -128,16 +193,22 @@ func (asyncResult *AsyncResult) GetWithTimeout(timeoutDuration, sleepDuration ti
 
 // GetState returns latest task state
 func (asyncResult *AsyncResult) GetState() *tasks.TaskState {
+	asyncResult.getState()
+	return asyncResult.taskState
+}
+
+// getState updates the task state from the backend unless it is completed
+func (asyncResult *AsyncResult) getState() error {
 	if asyncResult.taskState.IsCompleted() {
-		return asyncResult.taskState
+		return nil
 	}
 
 	taskState, err := asyncResult.backend.GetState(asyncResult.Signature.UUID)
-	if err == nil {
-		asyncResult.taskState = taskState
+	if err != nil {
+		return err
 	}
-
-	return asyncResult.taskState
+	asyncResult.taskState = taskState
+	return nil
 }
 
 // Get returns results of a chain of tasks (synchronous blocking call)
@@ -1,6 +1,9 @@
 package backends
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/RichardKnop/machinery/v1/config"
 )
 
@@ -19,3 +22,36 @@ func IsAMQP(b Interface) bool {
 	_, isAMQPBackend := b.(*AMQPBackend)
 	return isAMQPBackend
 }
+
+// resultsExpireIn returns how long task states and results are stored for,
+// falling back to 1 hour so they never accumulate forever
+func resultsExpireIn(cnf *config.Config) time.Duration {
+	if cnf.ResultsExpireIn > 0 {
+		return time.Duration(cnf.ResultsExpireIn) * time.Second
+	}
+	return time.Hour
+}
+
+// dedupKey returns key used by deduplicating backends to mark the task as seen
+func dedupKey(taskUUID string) string {
+	return "dedup_" + taskUUID
+}
+
+// revokedKey returns key used by revoking backends to mark the task as revoked
+func revokedKey(taskUUID string) string {
+	return "revoked_" + taskUUID
+}
+
+// workerKeyPrefix prefixes keys used by backends to store worker heartbeats
+const workerKeyPrefix = "worker_"
+
+// workerKey returns key used by backends to store the heartbeat of the worker
+func workerKey(workerID string) string {
+	return workerKeyPrefix + workerID
+}
+
+// rateLimitKey returns key used by rate limiting backends to count tokens
+// taken for the task within the given second
+func rateLimitKey(taskName string, second int64) string {
+	return fmt.Sprintf("rate_limit_%s_%d", taskName, second)
+}
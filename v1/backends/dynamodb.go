@@ -153,6 +153,11 @@ func (b *DynamoDBBackend) SetStateFailure(signature *tasks.Signature, err string
 	return b.updateToFailureStateWithError(taskState)
 }
 
+func (b *DynamoDBBackend) SetStateExpired(signature *tasks.Signature) error {
+	taskState := tasks.NewExpiredTaskState(signature)
+	return b.setTaskState(taskState)
+}
+
 func (b *DynamoDBBackend) GetState(taskUUID string) (*tasks.TaskState, error) {
 	result, err := b.client.GetItem(&dynamodb.GetItemInput{
 		TableName: aws.String(b.cnf.DynamoDB.TaskStatesTable),
@@ -165,6 +170,9 @@ func (b *DynamoDBBackend) GetState(taskUUID string) (*tasks.TaskState, error) {
 	if err != nil {
 		return nil, err
 	}
+	if result != nil && len(result.Item) == 0 {
+		return nil, NewErrTasknotFound(taskUUID)
+	}
 	return b.unmarshalTaskStateGetItemResult(result)
 }
 
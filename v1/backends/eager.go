@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/RichardKnop/machinery/v1/tasks"
 )
@@ -42,13 +45,38 @@ func (e ErrTasknotFound) Error() string {
 type EagerBackend struct {
 	groups map[string][]string
 	tasks  map[string][]byte
+	seen   map[string]time.Time
+	// revoked holds UUIDs of revoked tasks
+	revoked map[string]bool
+	// rateLimits counts tokens taken per task within the current second
+	rateLimits map[string]rateLimitWindow
+	// workers holds heartbeats of workers, they are recorded from the
+	// heartbeat goroutine of each worker so access is guarded by workersMu
+	workers   map[string]workerHeartbeat
+	workersMu sync.Mutex
+}
+
+// workerHeartbeat is the last heartbeat of a worker and when it expires
+type workerHeartbeat struct {
+	info      WorkerInfo
+	expiresAt time.Time
+}
+
+// rateLimitWindow counts tokens of a task taken within a second
+type rateLimitWindow struct {
+	second int64
+	taken  int
 }
 
 // NewEagerBackend creates EagerBackend instance
 func NewEagerBackend() Interface {
 	return &EagerBackend{
-		groups: make(map[string][]string),
-		tasks:  make(map[string][]byte),
+		groups:     make(map[string][]string),
+		tasks:      make(map[string][]byte),
+		seen:       make(map[string]time.Time),
+		revoked:    make(map[string]bool),
+		rateLimits: make(map[string]rateLimitWindow),
+		workers:    make(map[string]workerHeartbeat),
 	}
 }
 
@@ -150,6 +178,12 @@ func (b *EagerBackend) SetStateFailure(signature *tasks.Signature, err string) e
 	return b.updateState(state)
 }
 
+// SetStateExpired updates task state to EXPIRED
+func (b *EagerBackend) SetStateExpired(signature *tasks.Signature) error {
+	state := tasks.NewExpiredTaskState(signature)
+	return b.updateState(state)
+}
+
 // GetState returns the latest task state
 func (b *EagerBackend) GetState(taskUUID string) (*tasks.TaskState, error) {
 	tasktStateBytes, ok := b.tasks[taskUUID]
@@ -167,6 +201,22 @@ func (b *EagerBackend) GetState(taskUUID string) (*tasks.TaskState, error) {
 	return state, nil
 }
 
+// GetStates returns states of the tasks which were found
+func (b *EagerBackend) GetStates(taskUUIDs ...string) (map[string]*tasks.TaskState, error) {
+	taskStates := make(map[string]*tasks.TaskState, len(taskUUIDs))
+	for _, taskUUID := range taskUUIDs {
+		state, err := b.GetState(taskUUID)
+		if _, ok := err.(ErrTasknotFound); ok {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		taskStates[taskUUID] = state
+	}
+	return taskStates, nil
+}
+
 // PurgeState deletes stored task state
 func (b *EagerBackend) PurgeState(taskUUID string) error {
 	_, ok := b.tasks[taskUUID]
@@ -189,6 +239,87 @@ func (b *EagerBackend) PurgeGroupMeta(groupUUID string) error {
 	return nil
 }
 
+// MarkTaskSeen marks the task as seen for the window duration,
+// it returns false if the task has already been marked
+func (b *EagerBackend) MarkTaskSeen(taskUUID string, window time.Duration) (bool, error) {
+	if expiresAt, ok := b.seen[taskUUID]; ok && time.Now().Before(expiresAt) {
+		return false, nil
+	}
+
+	b.seen[taskUUID] = time.Now().Add(window)
+	return true, nil
+}
+
+// UnmarkTaskSeen removes the mark so the task can be processed again
+func (b *EagerBackend) UnmarkTaskSeen(taskUUID string) error {
+	delete(b.seen, taskUUID)
+	return nil
+}
+
+// RevokeTask records that the task has been revoked
+func (b *EagerBackend) RevokeTask(taskUUID string) error {
+	b.revoked[taskUUID] = true
+	return nil
+}
+
+// UnrevokeTask removes the record of the task having been revoked
+func (b *EagerBackend) UnrevokeTask(taskUUID string) error {
+	delete(b.revoked, taskUUID)
+	return nil
+}
+
+// IsTaskRevoked returns true if the task has been revoked
+func (b *EagerBackend) IsTaskRevoked(taskUUID string) (bool, error) {
+	return b.revoked[taskUUID], nil
+}
+
+// SetStateRevoked updates task state to REVOKED
+func (b *EagerBackend) SetStateRevoked(signature *tasks.Signature) error {
+	state := tasks.NewRevokedTaskState(signature)
+	return b.updateState(state)
+}
+
+// TakeRateLimitToken takes one of perSecond tokens of the task for the
+// current second, it returns false if all of them have been taken already
+func (b *EagerBackend) TakeRateLimitToken(taskName string, perSecond int) (bool, error) {
+	window := b.rateLimits[taskName]
+	if second := time.Now().Unix(); window.second != second {
+		window = rateLimitWindow{second: second}
+	}
+
+	window.taken++
+	b.rateLimits[taskName] = window
+	return window.taken <= perSecond, nil
+}
+
+// RecordWorkerHeartbeat stores info of the worker, it expires after ttl
+// unless the worker records another heartbeat first
+func (b *EagerBackend) RecordWorkerHeartbeat(info WorkerInfo, ttl time.Duration) error {
+	b.workersMu.Lock()
+	defer b.workersMu.Unlock()
+
+	b.workers[info.ID] = workerHeartbeat{info: info, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+// ActiveWorkers returns info of workers whose heartbeat has not expired
+func (b *EagerBackend) ActiveWorkers() ([]WorkerInfo, error) {
+	b.workersMu.Lock()
+	defer b.workersMu.Unlock()
+
+	now := time.Now()
+	workers := make([]WorkerInfo, 0, len(b.workers))
+	for id, heartbeat := range b.workers {
+		if !now.Before(heartbeat.expiresAt) {
+			delete(b.workers, id)
+			continue
+		}
+		workers = append(workers, heartbeat.info)
+	}
+	sort.Slice(workers, func(i, j int) bool { return workers[i].ID < workers[j].ID })
+	return workers, nil
+}
+
 func (b *EagerBackend) updateState(s *tasks.TaskState) error {
 	// simulate the behavior of json marshal/unmarshal
 	msg, err := json.Marshal(s)
@@ -3,6 +3,7 @@ package backends_test
 import (
 	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/RichardKnop/machinery/v1/backends"
 	"github.com/RichardKnop/machinery/v1/tasks"
@@ -302,6 +303,22 @@ func (s *EagerBackendTestSuite) TestPurgeState() {
 	}
 }
 
+func (s *EagerBackendTestSuite) TestAsyncResultExpired() {
+	t := &tasks.Signature{UUID: "expired_task"}
+	s.Nil(s.backend.SetStatePending(t))
+
+	asyncResult := backends.NewAsyncResult(t, s.backend)
+	results, err := asyncResult.Touch()
+	s.Nil(results)
+	s.Nil(err)
+
+	// the state is gone once its results expired
+	s.Nil(s.backend.PurgeState(t.UUID))
+	results, err = asyncResult.Get(time.Millisecond)
+	s.Nil(results)
+	s.Equal(backends.ErrResultExpired, err)
+}
+
 func (s *EagerBackendTestSuite) TestPurgeGroupMeta() {
 	// group4
 	{
@@ -325,6 +342,70 @@ func (s *EagerBackendTestSuite) TestPurgeGroupMeta() {
 	}
 }
 
+func (s *EagerBackendTestSuite) TestMarkTaskSeen() {
+	deduplicator, ok := s.backend.(backends.Deduplicator)
+	s.True(ok)
+
+	firstSeen, err := deduplicator.MarkTaskSeen("dedup-1", time.Hour)
+	s.Nil(err)
+	s.True(firstSeen)
+
+	// seen within the window
+	firstSeen, err = deduplicator.MarkTaskSeen("dedup-1", time.Hour)
+	s.Nil(err)
+	s.False(firstSeen)
+
+	// unmarked tasks can be seen again
+	s.Nil(deduplicator.UnmarkTaskSeen("dedup-1"))
+	firstSeen, err = deduplicator.MarkTaskSeen("dedup-1", time.Nanosecond)
+	s.Nil(err)
+	s.True(firstSeen)
+
+	// the window has passed
+	time.Sleep(time.Millisecond)
+	firstSeen, err = deduplicator.MarkTaskSeen("dedup-1", time.Hour)
+	s.Nil(err)
+	s.True(firstSeen)
+}
+
+func (s *EagerBackendTestSuite) TestTakeRateLimitToken() {
+	limiter, ok := s.backend.(backends.RateLimiter)
+	s.True(ok)
+
+	second := time.Now().Unix()
+	var taken []bool
+	for i := 0; i < 3; i++ {
+		ok, err := limiter.TakeRateLimitToken("limited", 2)
+		s.Nil(err)
+		taken = append(taken, ok)
+	}
+	// Tokens are only exhausted if all of them were taken within one second
+	if time.Now().Unix() == second {
+		s.Equal([]bool{true, true, false}, taken)
+	}
+
+	// Other tasks have their own tokens
+	ok, err := limiter.TakeRateLimitToken("other", 1)
+	s.Nil(err)
+	s.True(ok)
+}
+
+func (s *EagerBackendTestSuite) TestActiveWorkers() {
+	registry, ok := s.backend.(backends.WorkerRegistry)
+	s.True(ok)
+
+	s.Nil(registry.RecordWorkerHeartbeat(backends.WorkerInfo{ID: "worker_2", ActiveTasks: 1}, time.Minute))
+	s.Nil(registry.RecordWorkerHeartbeat(backends.WorkerInfo{ID: "worker_1"}, time.Minute))
+	s.Nil(registry.RecordWorkerHeartbeat(backends.WorkerInfo{ID: "stopped_worker"}, time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+
+	// Heartbeats are replaced by newer ones and expire after their ttl
+	s.Nil(registry.RecordWorkerHeartbeat(backends.WorkerInfo{ID: "worker_2", ActiveTasks: 2}, time.Minute))
+	workers, err := registry.ActiveWorkers()
+	s.Nil(err)
+	s.Equal([]backends.WorkerInfo{{ID: "worker_1"}, {ID: "worker_2", ActiveTasks: 2}}, workers)
+}
+
 //
 // internal method
 //
@@ -1,6 +1,8 @@
 package backends
 
 import (
+	"time"
+
 	"github.com/RichardKnop/machinery/v1/tasks"
 )
 
@@ -25,3 +27,69 @@ type Interface interface {
 	PurgeState(taskUUID string) error
 	PurgeGroupMeta(groupUUID string) error
 }
+
+// Deduplicator is implemented by backends which can guard tasks against being
+// processed more than once, e.g. when a message is redelivered by the broker
+type Deduplicator interface {
+	// MarkTaskSeen marks the task as seen for the window duration,
+	// it returns false if the task has already been marked
+	MarkTaskSeen(taskUUID string, window time.Duration) (bool, error)
+	// UnmarkTaskSeen removes the mark so the task can be processed again
+	UnmarkTaskSeen(taskUUID string) error
+}
+
+// StatesGetter is implemented by backends which can get states of many tasks
+// in a single round trip
+type StatesGetter interface {
+	// GetStates returns states of the tasks which were found by task UUID
+	GetStates(taskUUIDs ...string) (map[string]*tasks.TaskState, error)
+}
+
+// Revoker is implemented by backends which can record that tasks have been
+// revoked, so workers drop them instead of processing them
+type Revoker interface {
+	// RevokeTask records that the task has been revoked
+	RevokeTask(taskUUID string) error
+	// UnrevokeTask removes the record of the task having been revoked
+	UnrevokeTask(taskUUID string) error
+	// IsTaskRevoked returns true if the task has been revoked
+	IsTaskRevoked(taskUUID string) (bool, error)
+	// SetStateRevoked updates task state to REVOKED
+	SetStateRevoked(signature *tasks.Signature) error
+}
+
+// Expirer is implemented by backends which can record that tasks expired
+// before they were started, other backends record a failure instead
+type Expirer interface {
+	// SetStateExpired updates task state to EXPIRED
+	SetStateExpired(signature *tasks.Signature) error
+}
+
+// RateLimiter is implemented by backends which can limit how many times per
+// second a task is started across all workers sharing the backend
+type RateLimiter interface {
+	// TakeRateLimitToken takes one of perSecond tokens of the task for the
+	// current second, it returns false if all of them have been taken already
+	TakeRateLimitToken(taskName string, perSecond int) (bool, error)
+}
+
+// WorkerRegistry is implemented by backends which can keep track of live
+// workers from the heartbeats they periodically record
+type WorkerRegistry interface {
+	// RecordWorkerHeartbeat stores info of the worker, it expires after ttl
+	// unless the worker records another heartbeat first
+	RecordWorkerHeartbeat(info WorkerInfo, ttl time.Duration) error
+	// ActiveWorkers returns info of workers whose heartbeat has not expired
+	ActiveWorkers() ([]WorkerInfo, error)
+}
+
+// WorkerInfo describes a worker as of its last heartbeat
+type WorkerInfo struct {
+	// ID identifies the worker, it is made of the hostname, process ID and
+	// consumer tag
+	ID          string    `json:"id"`
+	ConsumerTag string    `json:"consumer_tag"`
+	Hostname    string    `json:"hostname"`
+	ActiveTasks int       `json:"active_tasks"`
+	LastSeen    time.Time `json:"last_seen"`
+}
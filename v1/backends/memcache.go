@@ -159,9 +159,18 @@ func (b *MemcacheBackend) SetStateFailure(signature *tasks.Signature, err string
 	return b.updateState(taskState)
 }
 
+// SetStateExpired updates task state to EXPIRED
+func (b *MemcacheBackend) SetStateExpired(signature *tasks.Signature) error {
+	taskState := tasks.NewExpiredTaskState(signature)
+	return b.updateState(taskState)
+}
+
 // GetState returns the latest task state
 func (b *MemcacheBackend) GetState(taskUUID string) (*tasks.TaskState, error) {
 	item, err := b.getClient().Get(taskUUID)
+	if err == memcache.ErrCacheMiss {
+		return nil, NewErrTasknotFound(taskUUID)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -176,6 +185,28 @@ func (b *MemcacheBackend) GetState(taskUUID string) (*tasks.TaskState, error) {
 	return state, nil
 }
 
+// GetStates returns states of the tasks which were found with a single
+// multi get
+func (b *MemcacheBackend) GetStates(taskUUIDs ...string) (map[string]*tasks.TaskState, error) {
+	items, err := b.getClient().GetMulti(taskUUIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	taskStates := make(map[string]*tasks.TaskState, len(items))
+	for taskUUID, item := range items {
+		state := new(tasks.TaskState)
+		decoder := json.NewDecoder(bytes.NewReader(item.Value))
+		decoder.UseNumber()
+		if err := decoder.Decode(state); err != nil {
+			return nil, err
+		}
+		taskStates[taskUUID] = state
+	}
+
+	return taskStates, nil
+}
+
 // PurgeState deletes stored task state
 func (b *MemcacheBackend) PurgeState(taskUUID string) error {
 	return b.getClient().Delete(taskUUID)
@@ -200,6 +231,92 @@ func (b *MemcacheBackend) updateState(taskState *tasks.TaskState) error {
 	})
 }
 
+// MarkTaskSeen marks the task as seen for the window duration,
+// it returns false if the task has already been marked
+func (b *MemcacheBackend) MarkTaskSeen(taskUUID string, window time.Duration) (bool, error) {
+	err := b.getClient().Add(&memcache.Item{
+		Key:        dedupKey(taskUUID),
+		Value:      []byte("1"),
+		Expiration: int32(time.Now().Add(window).Unix()),
+	})
+	if err == memcache.ErrNotStored {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UnmarkTaskSeen removes the mark so the task can be processed again
+func (b *MemcacheBackend) UnmarkTaskSeen(taskUUID string) error {
+	err := b.getClient().Delete(dedupKey(taskUUID))
+	if err == memcache.ErrCacheMiss {
+		return nil
+	}
+	return err
+}
+
+// RevokeTask records that the task has been revoked, the record expires
+// together with task states
+func (b *MemcacheBackend) RevokeTask(taskUUID string) error {
+	return b.getClient().Set(&memcache.Item{
+		Key:        revokedKey(taskUUID),
+		Value:      []byte("1"),
+		Expiration: b.getExpirationTimestamp(),
+	})
+}
+
+// UnrevokeTask removes the record of the task having been revoked
+func (b *MemcacheBackend) UnrevokeTask(taskUUID string) error {
+	err := b.getClient().Delete(revokedKey(taskUUID))
+	if err == memcache.ErrCacheMiss {
+		return nil
+	}
+	return err
+}
+
+// IsTaskRevoked returns true if the task has been revoked
+func (b *MemcacheBackend) IsTaskRevoked(taskUUID string) (bool, error) {
+	_, err := b.getClient().Get(revokedKey(taskUUID))
+	if err == memcache.ErrCacheMiss {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SetStateRevoked updates task state to REVOKED
+func (b *MemcacheBackend) SetStateRevoked(signature *tasks.Signature) error {
+	taskState := tasks.NewRevokedTaskState(signature)
+	return b.updateState(taskState)
+}
+
+// TakeRateLimitToken takes one of perSecond tokens of the task for the
+// current second, it returns false if all of them have been taken already
+func (b *MemcacheBackend) TakeRateLimitToken(taskName string, perSecond int) (bool, error) {
+	now := time.Now()
+	key := rateLimitKey(taskName, now.Unix())
+
+	// Make sure the counter exists before incrementing it
+	err := b.getClient().Add(&memcache.Item{
+		Key:        key,
+		Value:      []byte("0"),
+		Expiration: int32(now.Add(2 * time.Second).Unix()),
+	})
+	if err != nil && err != memcache.ErrNotStored {
+		return false, err
+	}
+
+	taken, err := b.getClient().Increment(key, 1)
+	if err != nil {
+		return false, err
+	}
+	return taken <= uint64(perSecond), nil
+}
+
 // lockGroupMeta acquires lock on group meta data
 func (b *MemcacheBackend) lockGroupMeta(groupMeta *tasks.GroupMeta) error {
 	groupMeta.Lock = true
@@ -272,12 +389,7 @@ func (b *MemcacheBackend) getStates(taskUUIDs ...string) ([]*tasks.TaskState, er
 
 // getExpirationTimestamp returns expiration timestamp
 func (b *MemcacheBackend) getExpirationTimestamp() int32 {
-	expiresIn := b.cnf.ResultsExpireIn
-	if expiresIn == 0 {
-		// // expire results after 1 hour by default
-		expiresIn = 3600
-	}
-	return int32(time.Now().Unix() + int64(expiresIn))
+	return int32(time.Now().Add(resultsExpireIn(b.cnf)).Unix())
 }
 
 // getClient returns or creates instance of Memcache client
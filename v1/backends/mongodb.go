@@ -158,15 +158,22 @@ func (b *MongodbBackend) SetStateSuccess(signature *tasks.Signature, results []*
 		}
 	}
 	update := bson.M{
-		"state":   tasks.StateSuccess,
-		"results": bsonResults,
+		"state":        tasks.StateSuccess,
+		"results":      bsonResults,
+		"completed_at": time.Now().UTC(),
 	}
 	return b.updateState(signature, update)
 }
 
 // SetStateFailure updates task state to FAILURE
 func (b *MongodbBackend) SetStateFailure(signature *tasks.Signature, err string) error {
-	update := bson.M{"state": tasks.StateFailure, "error": err}
+	update := bson.M{"state": tasks.StateFailure, "error": err, "completed_at": time.Now().UTC()}
+	return b.updateState(signature, update)
+}
+
+// SetStateExpired updates task state to EXPIRED
+func (b *MongodbBackend) SetStateExpired(signature *tasks.Signature) error {
+	update := bson.M{"state": tasks.StateExpired, "completed_at": time.Now().UTC()}
 	return b.updateState(signature, update)
 }
 
@@ -178,11 +185,33 @@ func (b *MongodbBackend) GetState(taskUUID string) (*tasks.TaskState, error) {
 
 	state := new(tasks.TaskState)
 	if err := b.tasksCollection.FindId(taskUUID).One(state); err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, NewErrTasknotFound(taskUUID)
+		}
 		return nil, err
 	}
 	return state, nil
 }
 
+// GetStates returns states of the tasks which were found with a single $in
+// query
+func (b *MongodbBackend) GetStates(taskUUIDs ...string) (map[string]*tasks.TaskState, error) {
+	if err := b.connect(); err != nil {
+		return nil, err
+	}
+
+	var states []*tasks.TaskState
+	if err := b.tasksCollection.Find(bson.M{"_id": bson.M{"$in": taskUUIDs}}).All(&states); err != nil {
+		return nil, err
+	}
+
+	taskStates := make(map[string]*tasks.TaskState, len(states))
+	for _, state := range states {
+		taskStates[state.TaskUUID] = state
+	}
+	return taskStates, nil
+}
+
 // PurgeState deletes stored task state
 func (b *MongodbBackend) PurgeState(taskUUID string) error {
 	if err := b.connect(); err != nil {
@@ -303,14 +332,20 @@ func (b *MongodbBackend) connect() error {
 func (b *MongodbBackend) createMongoIndexes() error {
 	indexes := []mgo.Index{
 		{
-			Key:         []string{"state"},
+			Key:        []string{"state"},
+			Background: true, // can be used while index is being built
+		},
+		{
+			// TTL index, finished task states expire ResultsExpireIn seconds
+			// after completion (TTL indexes only work with date fields)
+			Key:         []string{"completed_at"},
 			Background:  true, // can be used while index is being built
-			ExpireAfter: time.Duration(b.cnf.ResultsExpireIn) * time.Second,
+			ExpireAfter: resultsExpireIn(b.cnf),
 		},
 		{
 			Key:         []string{"lock"},
 			Background:  true, // can be used while index is being built
-			ExpireAfter: time.Duration(b.cnf.ResultsExpireIn) * time.Second,
+			ExpireAfter: resultsExpireIn(b.cnf),
 		},
 	}
 
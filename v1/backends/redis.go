@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/RichardKnop/machinery/v1/common"
@@ -171,12 +172,21 @@ func (b *RedisBackend) SetStateFailure(signature *tasks.Signature, err string) e
 	return b.updateState(taskState)
 }
 
+// SetStateExpired updates task state to EXPIRED
+func (b *RedisBackend) SetStateExpired(signature *tasks.Signature) error {
+	taskState := tasks.NewExpiredTaskState(signature)
+	return b.updateState(taskState)
+}
+
 // GetState returns the latest task state
 func (b *RedisBackend) GetState(taskUUID string) (*tasks.TaskState, error) {
 	conn := b.open()
 	defer conn.Close()
 
 	item, err := redis.Bytes(conn.Do("GET", taskUUID))
+	if err == redis.ErrNil {
+		return nil, NewErrTasknotFound(taskUUID)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -191,6 +201,44 @@ func (b *RedisBackend) GetState(taskUUID string) (*tasks.TaskState, error) {
 	return state, nil
 }
 
+// GetStates returns states of the tasks which were found with a single MGET
+func (b *RedisBackend) GetStates(taskUUIDs ...string) (map[string]*tasks.TaskState, error) {
+	taskStates := make(map[string]*tasks.TaskState, len(taskUUIDs))
+	if len(taskUUIDs) == 0 {
+		return taskStates, nil
+	}
+
+	conn := b.open()
+	defer conn.Close()
+
+	keys := make([]interface{}, len(taskUUIDs))
+	for i, taskUUID := range taskUUIDs {
+		keys[i] = taskUUID
+	}
+
+	reply, err := redis.ByteSlices(conn.Do("MGET", keys...))
+	if err != nil {
+		return nil, err
+	}
+
+	for i, stateBytes := range reply {
+		// Missing keys are returned as nil
+		if stateBytes == nil {
+			continue
+		}
+
+		taskState := new(tasks.TaskState)
+		decoder := json.NewDecoder(bytes.NewReader(stateBytes))
+		decoder.UseNumber()
+		if err := decoder.Decode(taskState); err != nil {
+			return nil, err
+		}
+		taskStates[taskUUIDs[i]] = taskState
+	}
+
+	return taskStates, nil
+}
+
 // PurgeState deletes stored task state
 func (b *RedisBackend) PurgeState(taskUUID string) error {
 	conn := b.open()
@@ -217,6 +265,160 @@ func (b *RedisBackend) PurgeGroupMeta(groupUUID string) error {
 	return nil
 }
 
+// MarkTaskSeen marks the task as seen for the window duration,
+// it returns false if the task has already been marked
+func (b *RedisBackend) MarkTaskSeen(taskUUID string, window time.Duration) (bool, error) {
+	conn := b.open()
+	defer conn.Close()
+
+	reply, err := conn.Do("SET", dedupKey(taskUUID), 1, "NX", "PX", int64(window/time.Millisecond))
+	if err != nil {
+		return false, err
+	}
+
+	// Nil reply means the key already exists
+	return reply != nil, nil
+}
+
+// UnmarkTaskSeen removes the mark so the task can be processed again
+func (b *RedisBackend) UnmarkTaskSeen(taskUUID string) error {
+	conn := b.open()
+	defer conn.Close()
+
+	_, err := conn.Do("DEL", dedupKey(taskUUID))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RevokeTask records that the task has been revoked, the record expires
+// together with task states
+func (b *RedisBackend) RevokeTask(taskUUID string) error {
+	conn := b.open()
+	defer conn.Close()
+
+	_, err := conn.Do("SET", revokedKey(taskUUID), 1, "PX", int64(resultsExpireIn(b.cnf)/time.Millisecond))
+	return err
+}
+
+// UnrevokeTask removes the record of the task having been revoked
+func (b *RedisBackend) UnrevokeTask(taskUUID string) error {
+	conn := b.open()
+	defer conn.Close()
+
+	_, err := conn.Do("DEL", revokedKey(taskUUID))
+	return err
+}
+
+// IsTaskRevoked returns true if the task has been revoked
+func (b *RedisBackend) IsTaskRevoked(taskUUID string) (bool, error) {
+	conn := b.open()
+	defer conn.Close()
+
+	return redis.Bool(conn.Do("EXISTS", revokedKey(taskUUID)))
+}
+
+// SetStateRevoked updates task state to REVOKED
+func (b *RedisBackend) SetStateRevoked(signature *tasks.Signature) error {
+	taskState := tasks.NewRevokedTaskState(signature)
+	return b.updateState(taskState)
+}
+
+// TakeRateLimitToken takes one of perSecond tokens of the task for the
+// current second, it returns false if all of them have been taken already
+func (b *RedisBackend) TakeRateLimitToken(taskName string, perSecond int) (bool, error) {
+	conn := b.open()
+	defer conn.Close()
+
+	key := rateLimitKey(taskName, time.Now().Unix())
+	conn.Send("MULTI")
+	conn.Send("INCR", key)
+	conn.Send("EXPIRE", key, 2)
+	reply, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return false, err
+	}
+
+	taken, err := redis.Int(reply[0], nil)
+	if err != nil {
+		return false, err
+	}
+	return taken <= perSecond, nil
+}
+
+// RecordWorkerHeartbeat stores info of the worker, it expires after ttl
+// unless the worker records another heartbeat first
+func (b *RedisBackend) RecordWorkerHeartbeat(info WorkerInfo, ttl time.Duration) error {
+	conn := b.open()
+	defer conn.Close()
+
+	encoded, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.Do("SET", workerKey(info.ID), encoded, "PX", int64(ttl/time.Millisecond))
+	return err
+}
+
+// ActiveWorkers returns info of workers whose heartbeat has not expired
+func (b *RedisBackend) ActiveWorkers() ([]WorkerInfo, error) {
+	conn := b.open()
+	defer conn.Close()
+
+	var keys []interface{}
+	cursor := 0
+	for {
+		reply, err := redis.Values(conn.Do("SCAN", cursor, "MATCH", workerKeyPrefix+"*", "COUNT", 100))
+		if err != nil {
+			return nil, err
+		}
+		if cursor, err = redis.Int(reply[0], nil); err != nil {
+			return nil, err
+		}
+		found, err := redis.Strings(reply[1], nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range found {
+			keys = append(keys, key)
+		}
+		if cursor == 0 {
+			break
+		}
+	}
+
+	workers := make([]WorkerInfo, 0, len(keys))
+	if len(keys) == 0 {
+		return workers, nil
+	}
+
+	reply, err := redis.Values(conn.Do("MGET", keys...))
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range reply {
+		// The heartbeat expired since it was scanned
+		if value == nil {
+			continue
+		}
+		item, ok := value.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("Expected byte array, instead got: %v", value)
+		}
+
+		var info WorkerInfo
+		if err := json.Unmarshal(item, &info); err != nil {
+			return nil, err
+		}
+		workers = append(workers, info)
+	}
+	sort.Slice(workers, func(i, j int) bool { return workers[i].ID < workers[j].ID })
+	return workers, nil
+}
+
 // getGroupMeta retrieves group meta data, convenience function to avoid repetition
 func (b *RedisBackend) getGroupMeta(groupUUID string) (*tasks.GroupMeta, error) {
 	conn := b.open()
@@ -295,12 +497,7 @@ func (b *RedisBackend) updateState(taskState *tasks.TaskState) error {
 
 // setExpirationTime sets expiration timestamp on a stored task state
 func (b *RedisBackend) setExpirationTime(key string) error {
-	expiresIn := b.cnf.ResultsExpireIn
-	if expiresIn == 0 {
-		// // expire results after 1 hour by default
-		expiresIn = 3600
-	}
-	expirationTimestamp := int32(time.Now().Unix() + int64(expiresIn))
+	expirationTimestamp := int32(time.Now().Add(resultsExpireIn(b.cnf)).Unix())
 
 	conn := b.open()
 	defer conn.Close()
@@ -1,6 +1,7 @@
 package backends_test
 
 import (
+	"fmt"
 	"os"
 	"testing"
 	"time"
@@ -143,3 +144,137 @@ func TestPurgeStateRedis(t *testing.T) {
 	assert.Nil(t, taskState)
 	assert.Error(t, err)
 }
+
+func TestMarkTaskSeenRedis(t *testing.T) {
+	redisURL := os.Getenv("REDIS_URL")
+	redisPassword := os.Getenv("REDIS_PASSWORD")
+	if redisURL == "" {
+		return
+	}
+
+	backend := backends.NewRedisBackend(new(config.Config), redisURL, redisPassword, "", 0)
+	deduplicator := backend.(backends.Deduplicator)
+
+	// Cleanup before the test
+	deduplicator.UnmarkTaskSeen("testTaskUUID")
+
+	firstSeen, err := deduplicator.MarkTaskSeen("testTaskUUID", time.Minute)
+	if assert.NoError(t, err) {
+		assert.True(t, firstSeen)
+	}
+
+	firstSeen, err = deduplicator.MarkTaskSeen("testTaskUUID", time.Minute)
+	if assert.NoError(t, err) {
+		assert.False(t, firstSeen)
+	}
+
+	assert.NoError(t, deduplicator.UnmarkTaskSeen("testTaskUUID"))
+	firstSeen, err = deduplicator.MarkTaskSeen("testTaskUUID", time.Minute)
+	if assert.NoError(t, err) {
+		assert.True(t, firstSeen)
+	}
+	deduplicator.UnmarkTaskSeen("testTaskUUID")
+}
+
+func TestRevokeTaskRedis(t *testing.T) {
+	redisURL := os.Getenv("REDIS_URL")
+	redisPassword := os.Getenv("REDIS_PASSWORD")
+	if redisURL == "" {
+		return
+	}
+
+	backend := backends.NewRedisBackend(new(config.Config), redisURL, redisPassword, "", 0)
+	revoker := backend.(backends.Revoker)
+	taskUUID := fmt.Sprintf("testRevokeTaskUUID_%d", time.Now().UnixNano())
+
+	revoked, err := revoker.IsTaskRevoked(taskUUID)
+	if assert.NoError(t, err) {
+		assert.False(t, revoked)
+	}
+
+	assert.NoError(t, revoker.RevokeTask(taskUUID))
+	revoked, err = revoker.IsTaskRevoked(taskUUID)
+	if assert.NoError(t, err) {
+		assert.True(t, revoked)
+	}
+
+	assert.NoError(t, revoker.UnrevokeTask(taskUUID))
+	revoked, err = revoker.IsTaskRevoked(taskUUID)
+	if assert.NoError(t, err) {
+		assert.False(t, revoked)
+	}
+}
+
+func TestGetStatesRedis(t *testing.T) {
+	redisURL := os.Getenv("REDIS_URL")
+	redisPassword := os.Getenv("REDIS_PASSWORD")
+	if redisURL == "" {
+		return
+	}
+
+	backend := backends.NewRedisBackend(new(config.Config), redisURL, redisPassword, "", 0)
+	signature := &tasks.Signature{UUID: fmt.Sprintf("testGetStatesUUID_%d", time.Now().UnixNano())}
+	assert.NoError(t, backend.SetStateStarted(signature))
+	defer backend.PurgeState(signature.UUID)
+
+	// Missing tasks are left out
+	taskStates, err := backend.(backends.StatesGetter).GetStates(signature.UUID, "testGetStatesMissingUUID")
+	if assert.NoError(t, err) && assert.Len(t, taskStates, 1) {
+		assert.Equal(t, tasks.StateStarted, taskStates[signature.UUID].State)
+	}
+}
+
+// benchmarkRedisStates stores states of count tasks for the benchmark and
+// returns a function purging them
+func benchmarkRedisStates(b *testing.B, count int) (backends.Interface, []string, func()) {
+	redisURL := os.Getenv("REDIS_URL")
+	if redisURL == "" {
+		b.Skip("REDIS_URL is not set")
+	}
+
+	backend := backends.NewRedisBackend(new(config.Config), redisURL, os.Getenv("REDIS_PASSWORD"), "", 0)
+	taskUUIDs := make([]string, count)
+	for i := range taskUUIDs {
+		taskUUIDs[i] = fmt.Sprintf("benchmarkStatesUUID_%d", i)
+		results := []*tasks.TaskResult{{Type: "int64", Value: i}}
+		if err := backend.SetStateSuccess(&tasks.Signature{UUID: taskUUIDs[i]}, results); err != nil {
+			b.Fatal(err)
+		}
+	}
+	purge := func() {
+		for _, taskUUID := range taskUUIDs {
+			backend.PurgeState(taskUUID)
+		}
+	}
+	return backend, taskUUIDs, purge
+}
+
+// BenchmarkGetStatesRedis gets states of a group of 500 tasks with a single
+// MGET
+func BenchmarkGetStatesRedis(b *testing.B) {
+	backend, taskUUIDs, purge := benchmarkRedisStates(b, 500)
+	defer purge()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := backend.(backends.StatesGetter).GetStates(taskUUIDs...); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkGetStateLoopRedis gets states of a group of 500 tasks one by one,
+// the way polling each AsyncResult does
+func BenchmarkGetStateLoopRedis(b *testing.B) {
+	backend, taskUUIDs, purge := benchmarkRedisStates(b, 500)
+	defer purge()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, taskUUID := range taskUUIDs {
+			if _, err := backend.GetState(taskUUID); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
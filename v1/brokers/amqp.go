@@ -1,16 +1,16 @@
 package brokers
 
 import (
-	"bytes"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/RichardKnop/machinery/v1/common"
 	"github.com/RichardKnop/machinery/v1/config"
 	"github.com/RichardKnop/machinery/v1/log"
+	"github.com/RichardKnop/machinery/v1/retry"
 	"github.com/RichardKnop/machinery/v1/tasks"
 	"github.com/streadway/amqp"
 )
@@ -20,6 +20,13 @@ type AMQPBroker struct {
 	Broker
 	common.AMQPConnector
 	processingWG sync.WaitGroup // use wait group to make sure task processing completes on interrupt signal
+	// poolConnections counts open connections when consuming over a pool of
+	// them, see ConnectionPoolSize
+	poolConnections int
+	poolMu          sync.Mutex
+	// reconnectAttempts counts attempts to consume over a single connection
+	// since it failed or was lost, it is reported to ConnectionObserver
+	reconnectAttempts int
 }
 
 // NewAMQPBroker creates new AMQPBroker instance
@@ -31,56 +38,283 @@ func NewAMQPBroker(cnf *config.Config) Interface {
 func (b *AMQPBroker) StartConsuming(consumerTag string, concurrency int, taskProcessor TaskProcessor) (bool, error) {
 	b.startConsuming(consumerTag, taskProcessor)
 
+	if b.cnf.AMQP.ConnectionPoolSize > 1 {
+		return b.consumePool(consumerTag, concurrency, taskProcessor)
+	}
+
+	observer := connectionObserver(taskProcessor)
+	if b.reconnectAttempts > 0 {
+		observer.ReconnectAttempt(b.reconnectAttempts)
+	}
+
+	connected, err := b.consumeConnection(consumerTag, concurrency, newWorkerPools(concurrency, taskProcessor), taskProcessor, true, b.stopChan, b.connected, b.disconnected)
+	switch {
+	case err == nil:
+		b.reconnectAttempts = 0
+	case connected:
+		b.reconnectAttempts = 1
+	default:
+		b.reconnectAttempts++
+	}
+
+	retry := b.retry
+	if err != nil && !connected {
+		retry = b.connectFailed(err)
+	}
+	if !retry {
+		b.reconnectAttempts = 0
+	}
+	return retry, err
+}
+
+// consumeConnection consumes over a new connection until consuming stops or
+// the connection fails, it returns true if the connection was opened. With
+// broadcast set broadcast tasks are consumed too if a BroadcastExchange is
+// configured. Connected and disconnected are called once it is opened and
+// closed, the task processor is notified too if it is a ConnectionObserver.
+func (b *AMQPBroker) consumeConnection(consumerTag string, concurrency int, pools *workerPools, taskProcessor TaskProcessor, broadcast bool, stop <-chan int, connected, disconnected func()) (opened bool, err error) {
+	queueNames := b.getQueues(taskProcessor)
+	queueName, bindingKey := queueNames[0], b.queueBindingKey(queueNames[0])
+
 	conn, channel, queue, _, amqpCloseChan, err := b.Connect(
 		b.cnf.Broker,
 		b.cnf.TLSConfig,
-		b.cnf.AMQP.Exchange,     // exchange name
-		b.cnf.AMQP.ExchangeType, // exchange type
-		b.cnf.DefaultQueue,      // queue name
-		true,                    // queue durable
-		false,                   // queue delete when unused
-		b.cnf.AMQP.BindingKey, // queue binding key
-		nil, // exchange declare args
-		nil, // queue declare args
+		b.cnf.AMQP.Exchange,                     // exchange name
+		b.cnf.AMQP.ExchangeType,                 // exchange type
+		queueName,                               // queue name
+		b.queueDurable(),                        // queue durable
+		false,                                   // queue delete when unused
+		bindingKey,                              // queue binding key
+		b.exchangeDeclareArgs(),                 // exchange declare args
+		b.queueDeclareArgs(),                    // queue declare args
 		amqp.Table(b.cnf.AMQP.QueueBindingArgs), // queue binding args
 	)
 	if err != nil {
-		b.retryFunc(b.retryStopChan)
-		return b.retry, err
+		return false, err
 	}
 	defer b.Close(channel, conn)
 
-	if err = channel.Qos(
-		b.cnf.AMQP.PrefetchCount,
-		0,     // prefetch size
-		false, // global
-	); err != nil {
-		return b.retry, fmt.Errorf("Channel qos error: %s", err)
-	}
+	observer := connectionObserver(taskProcessor)
+	connected()
+	observer.ConnectionOpened()
+	defer func() {
+		disconnected()
+		observer.ConnectionClosed(err)
+	}()
 
-	deliveries, err := channel.Consume(
-		queue.Name,  // queue
-		consumerTag, // consumer tag
-		false,       // auto-ack
-		false,       // exclusive
-		false,       // no-local
-		false,       // no-wait
-		nil,         // arguments
+	// The broker closes the channel on some errors, e.g. when acknowledging
+	// an unknown delivery, without closing the connection. Channels are
+	// closed with the error of the connection too, which is notified first.
+	var (
+		channelCloseChan = channel.NotifyClose(make(chan *amqp.Error, 1))
+		connCloseChan    = conn.NotifyClose(make(chan *amqp.Error, 1))
 	)
-	if err != nil {
-		return b.retry, fmt.Errorf("Queue consume error: %s", err)
+	go func() {
+		amqpErr := <-channelCloseChan
+		select {
+		case <-connCloseChan:
+		default:
+			if amqpErr != nil {
+				observer.ChannelError(amqpErr)
+			}
+		}
+	}()
+
+	// Any other queues are consumed over the same channel
+	queueNames[0] = queue.Name
+	for _, queueName := range queueNames[1:] {
+		if err = b.declareQueue(channel, queueName); err != nil {
+			return true, err
+		}
+	}
+
+	// The dead letter queue is declared up front so that it can be inspected
+	// before the first dead letter is published
+	if b.cnf.DeadLetterQueue != "" {
+		if err = b.declareDeadLetterQueue(channel); err != nil {
+			return true, err
+		}
+	}
+
+	// Delayed tasks are routed through the delayed message exchange when the
+	// RabbitMQ plugin is used, bind the queues to it as well
+	if b.cnf.AMQP.UseDelayedMessageExchange {
+		for _, queueName := range queueNames {
+			if err = b.bindDelayedExchange(channel, queueName, b.queueBindingKey(queueName)); err != nil {
+				return true, err
+			}
+		}
+	}
+
+	// Each worker gets broadcast tasks through an exclusive queue of its own
+	if broadcast && b.cnf.AMQP.BroadcastExchange != "" {
+		broadcastQueue, err := b.bindBroadcastQueue(channel)
+		if err != nil {
+			return true, err
+		}
+		queueNames = append(queueNames, broadcastQueue)
+	}
+
+	settings := queueSettings(taskProcessor)
+	consumers := make([]<-chan amqp.Delivery, len(queueNames))
+	for i, queueName := range queueNames {
+		// The prefetch count applies to each consumer started afterwards,
+		// i.e. to each queue
+		if err = channel.Qos(
+			b.prefetchCount(queueName, concurrency, settings[queueName]),
+			0,     // prefetch size
+			false, // global
+		); err != nil {
+			return true, fmt.Errorf("Channel qos error: %s", err)
+		}
+
+		// Consumer tags have to be unique within the channel
+		tag := consumerTag
+		if tag != "" && len(queueNames) > 1 {
+			tag = consumerTag + "-" + queueName
+		}
+
+		consumers[i], err = channel.Consume(
+			queueName, // queue
+			tag,       // consumer tag
+			false,     // auto-ack
+			false,     // exclusive
+			false,     // no-local
+			false,     // no-wait
+			nil,       // arguments
+		)
+		if err != nil {
+			return true, fmt.Errorf("Queue consume error: %s", err)
+		}
 	}
 
 	log.INFO.Print("[*] Waiting for messages. To exit press CTRL+C")
 
-	if err := b.consume(deliveries, concurrency, taskProcessor, amqpCloseChan); err != nil {
-		return b.retry, err
+	groups := groupConsumers(queueNames, consumers, pools)
+	if err := b.consumeGroups(groups, taskProcessor, amqpCloseChan, stop); err != nil {
+		return true, err
 	}
 
 	// Waiting for any tasks being processed to finish
-	b.processingWG.Wait()
+	b.waitForProcessing(&b.processingWG)
+
+	return true, nil
+}
 
-	return b.retry, nil
+// prefetchCount returns how many messages of the queue to prefetch. Unless
+// configured explicitly, it is as many messages as can be processed
+// concurrently, so the worker does not hoard messages it cannot start.
+func (b *AMQPBroker) prefetchCount(queueName string, concurrency int, settings QueueSettings) int {
+	prefetchCount := b.cnf.AMQP.PrefetchCount
+	if settings.Concurrency > 0 {
+		concurrency, prefetchCount = settings.Concurrency, 0
+	}
+	if settings.PrefetchCount > 0 {
+		prefetchCount = settings.PrefetchCount
+	}
+	if prefetchCount == 0 {
+		prefetchCount = concurrency
+	}
+	if prefetchCount > 0 && (concurrency == 0 || prefetchCount < concurrency) {
+		log.WARNING.Printf("Prefetch count %d of queue %s is lower than its concurrency %d, some of the worker slots will stay idle", prefetchCount, queueName, concurrency)
+	}
+	return prefetchCount
+}
+
+// consumePool consumes over ConnectionPoolSize connections, each of them with
+// its own channel and consumers and reconnecting on its own, tasks received
+// over all of them share the concurrency of the worker. Broadcast tasks are
+// only consumed over the first connection, so they are processed once by the
+// worker. Consuming stops once
+// a connection gives up reconnecting after MaxReconnectAttempts.
+func (b *AMQPBroker) consumePool(consumerTag string, concurrency int, taskProcessor TaskProcessor) (bool, error) {
+	var (
+		size   = b.cnf.AMQP.ConnectionPoolSize
+		pools  = newWorkerPools(concurrency, taskProcessor)
+		stop   = make(chan int)
+		gaveUp = make(chan error, size)
+		wg     sync.WaitGroup
+	)
+	wg.Add(size)
+	for i := 0; i < size; i++ {
+		tag := consumerTag
+		if tag != "" {
+			tag = fmt.Sprintf("%s-%d", consumerTag, i+1)
+		}
+
+		go func(tag string, broadcast bool) {
+			defer wg.Done()
+			if err := b.keepConsuming(tag, concurrency, pools, taskProcessor, broadcast, stop); err != nil {
+				gaveUp <- err
+			}
+		}(tag, i == 0)
+	}
+
+	var err error
+	select {
+	case <-b.stopChan:
+	case err = <-gaveUp:
+	}
+	close(stop)
+	wg.Wait()
+
+	return false, err
+}
+
+// keepConsuming consumes over a connection of the pool until stop is closed,
+// reconnecting whenever the connection fails. It returns an error once it
+// gives up reconnecting after MaxReconnectAttempts.
+func (b *AMQPBroker) keepConsuming(consumerTag string, concurrency int, pools *workerPools, taskProcessor TaskProcessor, broadcast bool, stop chan int) error {
+	var (
+		retryFunc  = retry.Closure()
+		attempts   = 0
+		reconnects = 0
+		observer   = connectionObserver(taskProcessor)
+	)
+	for {
+		if reconnects > 0 {
+			observer.ReconnectAttempt(reconnects)
+		}
+		connected, err := b.consumeConnection(consumerTag, concurrency, pools, taskProcessor, broadcast, stop, b.poolConnected, b.poolDisconnected)
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+
+		if connected {
+			log.WARNING.Printf("Consumer %s lost its connection to the broker: %s", consumerTag, err)
+			attempts, reconnects, retryFunc = 0, 1, retry.Closure()
+			continue
+		}
+
+		attempts++
+		reconnects++
+		if max := b.cnf.MaxReconnectAttempts; max > 0 && attempts > max {
+			log.ERROR.Printf("Consumer %s giving up connecting to the broker after %d reconnect attempts: %s", consumerTag, max, err)
+			return err
+		}
+		log.WARNING.Printf("Consumer %s failed connecting to the broker: %s", consumerTag, err)
+		retryFunc(stop)
+	}
+}
+
+// poolConnected and poolDisconnected track connections of the pool, the
+// broker is connected while any of them is
+func (b *AMQPBroker) poolConnected() {
+	b.poolMu.Lock()
+	defer b.poolMu.Unlock()
+	b.poolConnections++
+	atomic.StoreInt32(&b.connection.connected, 1)
+}
+
+func (b *AMQPBroker) poolDisconnected() {
+	b.poolMu.Lock()
+	defer b.poolMu.Unlock()
+	b.poolConnections--
+	if b.poolConnections == 0 {
+		atomic.StoreInt32(&b.connection.connected, 0)
+	}
 }
 
 // StopConsuming quits the loop
@@ -88,7 +322,7 @@ func (b *AMQPBroker) StopConsuming() {
 	b.stopConsuming()
 
 	// Waiting for any tasks being processed to finish
-	b.processingWG.Wait()
+	b.waitForProcessing(&b.processingWG)
 }
 
 // Publish places a new message on the default queue
@@ -96,9 +330,17 @@ func (b *AMQPBroker) Publish(signature *tasks.Signature) error {
 	// Adjust routing key (this decides which queue the message will be published to)
 	AdjustRoutingKey(b, signature)
 
-	msg, err := json.Marshal(signature)
+	if err := b.checkPriority(signature); err != nil {
+		return err
+	}
+
+	msg, contentType, err := b.marshal(signature)
 	if err != nil {
-		return fmt.Errorf("JSON marshal error: %s", err)
+		return err
+	}
+
+	if b.isDeadLetter(signature) {
+		return b.publishDeadLetter(signature, msg, contentType)
 	}
 
 	// Check the ETA signature field, if it is set and it is in the future,
@@ -109,6 +351,10 @@ func (b *AMQPBroker) Publish(signature *tasks.Signature) error {
 		if signature.ETA.After(now) {
 			delayMs := int64(signature.ETA.Sub(now) / time.Millisecond)
 
+			if b.cnf.AMQP.UseDelayedMessageExchange {
+				return b.delayWithPlugin(signature, delayMs)
+			}
+
 			return b.delay(signature, delayMs)
 		}
 	}
@@ -116,14 +362,14 @@ func (b *AMQPBroker) Publish(signature *tasks.Signature) error {
 	conn, channel, _, confirmsChan, _, err := b.Connect(
 		b.cnf.Broker,
 		b.cnf.TLSConfig,
-		b.cnf.AMQP.Exchange,     // exchange name
-		b.cnf.AMQP.ExchangeType, // exchange type
-		signature.RoutingKey,    // queue name
-		true,                    // queue durable
-		false,                   // queue delete when unused
-		b.cnf.AMQP.BindingKey, // queue binding key
-		nil, // exchange declare args
-		nil, // queue declare args
+		b.cnf.AMQP.Exchange,                     // exchange name
+		b.cnf.AMQP.ExchangeType,                 // exchange type
+		signature.RoutingKey,                    // queue name
+		b.queueDurable(),                        // queue durable
+		false,                                   // queue delete when unused
+		b.bindingKey(signature),                 // queue binding key
+		b.exchangeDeclareArgs(),                 // exchange declare args
+		b.queueDeclareArgs(),                    // queue declare args
 		amqp.Table(b.cnf.AMQP.QueueBindingArgs), // queue binding args
 	)
 	if err != nil {
@@ -137,46 +383,599 @@ func (b *AMQPBroker) Publish(signature *tasks.Signature) error {
 		false,                // mandatory
 		false,                // immediate
 		amqp.Publishing{
-			Headers:      amqp.Table(signature.Headers),
-			ContentType:  "application/json",
-			Body:         msg,
-			DeliveryMode: amqp.Persistent,
+			Headers:         amqp.Table(signature.Headers),
+			ContentType:     contentType,
+			ContentEncoding: contentEncoding(msg),
+			Body:            msg,
+			DeliveryMode:    b.deliveryMode(),
+			Priority:        signature.Priority,
 		},
 	); err != nil {
 		return err
 	}
 
-	confirmed := <-confirmsChan
+	return b.awaitConfirm(confirmsChan)
+}
+
+// isDeadLetter returns true if the task is routed to the dead letter queue
+func (b *AMQPBroker) isDeadLetter(signature *tasks.Signature) bool {
+	return b.cnf.DeadLetterQueue != "" && signature.RoutingKey == b.cnf.DeadLetterQueue
+}
+
+// publishDeadLetter publishes the task straight to the dead letter queue via
+// the default exchange, which routes messages by queue name. The dead letter
+// queue is not bound to the configured exchange, so whatever its type, it does
+// not receive other tasks and dead letters do not end up in other queues.
+func (b *AMQPBroker) publishDeadLetter(signature *tasks.Signature, msg []byte, contentType string) error {
+	conn, channel, err := b.Open(b.cnf.Broker, b.cnf.TLSConfig)
+	if err != nil {
+		return err
+	}
+	defer b.Close(channel, conn)
+
+	if err := b.declareDeadLetterQueue(channel); err != nil {
+		return err
+	}
+
+	if err := channel.Confirm(false); err != nil {
+		return fmt.Errorf("Channel could not be put into confirm mode: %s", err)
+	}
+	confirmsChan := channel.NotifyPublish(make(chan amqp.Confirmation, 1))
+
+	if err := channel.Publish(
+		"",                    // default exchange
+		b.cnf.DeadLetterQueue, // routing key
+		false,                 // mandatory
+		false,                 // immediate
+		amqp.Publishing{
+			Headers:         amqp.Table(signature.Headers),
+			ContentType:     contentType,
+			ContentEncoding: contentEncoding(msg),
+			Body:            msg,
+			DeliveryMode:    b.deliveryMode(),
+			Priority:        signature.Priority,
+		},
+	); err != nil {
+		return err
+	}
+
+	return b.awaitConfirm(confirmsChan)
+}
+
+// declareDeadLetterQueue declares the dead letter queue without binding it to
+// the exchange, dead letters are published to it via the default exchange
+func (b *AMQPBroker) declareDeadLetterQueue(channel *amqp.Channel) error {
+	if _, err := channel.QueueDeclare(
+		b.cnf.DeadLetterQueue, // name
+		b.queueDurable(),      // durable
+		false,                 // delete when unused
+		false,                 // exclusive
+		false,                 // no-wait
+		b.queueDeclareArgs(),  // arguments
+	); err != nil {
+		return fmt.Errorf("Queue declare error: %s", err)
+	}
+	return nil
+}
+
+// Broadcast publishes the task to the fanout BroadcastExchange, every worker
+// consuming from the broker gets a copy of it through an exclusive queue of
+// its own. Broadcast tasks are transient and cannot be delayed, workers which
+// are not running when the task is published never get it.
+func (b *AMQPBroker) Broadcast(signature *tasks.Signature) error {
+	if b.cnf.AMQP.BroadcastExchange == "" {
+		return errors.New("AMQP BroadcastExchange is not configured")
+	}
+	if signature.ETA != nil && signature.ETA.After(time.Now().UTC()) {
+		return errors.New("Broadcast tasks cannot be delayed")
+	}
 
-	if confirmed.Ack {
+	msg, contentType, err := b.marshal(signature)
+	if err != nil {
+		return err
+	}
+
+	conn, channel, err := b.Open(b.cnf.Broker, b.cnf.TLSConfig)
+	if err != nil {
+		return err
+	}
+	defer b.Close(channel, conn)
+
+	if err := b.declareBroadcastExchange(channel); err != nil {
+		return err
+	}
+
+	return channel.Publish(
+		b.cnf.AMQP.BroadcastExchange, // exchange name
+		"",                           // routing key, ignored by fanout exchanges
+		false,                        // mandatory
+		false,                        // immediate
+		amqp.Publishing{
+			Headers:         amqp.Table(signature.Headers),
+			ContentType:     contentType,
+			ContentEncoding: contentEncoding(msg),
+			Body:            msg,
+			DeliveryMode:    amqp.Transient,
+		},
+	)
+}
+
+// declareBroadcastExchange declares the fanout exchange broadcast tasks are
+// published to
+func (b *AMQPBroker) declareBroadcastExchange(channel *amqp.Channel) error {
+	if err := channel.ExchangeDeclare(
+		b.cnf.AMQP.BroadcastExchange, // name of the exchange
+		amqp.ExchangeFanout,          // type
+		true,                         // durable
+		false,                        // delete when complete
+		false,                        // internal
+		false,                        // noWait
+		nil,                          // arguments
+	); err != nil {
+		return fmt.Errorf("Broadcast exchange declare error: %s", err)
+	}
+	return nil
+}
+
+// bindBroadcastQueue declares an exclusive, auto deleted queue named by the
+// broker and binds it to the broadcast exchange, it returns the queue name
+func (b *AMQPBroker) bindBroadcastQueue(channel *amqp.Channel) (string, error) {
+	if err := b.declareBroadcastExchange(channel); err != nil {
+		return "", err
+	}
+
+	queue, err := channel.QueueDeclare(
+		"",    // name, generated by the broker
+		false, // durable
+		true,  // delete when unused
+		true,  // exclusive
+		false, // no-wait
+		nil,   // arguments
+	)
+	if err != nil {
+		return "", fmt.Errorf("Broadcast queue declare error: %s", err)
+	}
+
+	if err := channel.QueueBind(
+		queue.Name,                   // name of the queue
+		"",                           // binding key
+		b.cnf.AMQP.BroadcastExchange, // source exchange
+		false,                        // noWait
+		nil,                          // arguments
+	); err != nil {
+		return "", fmt.Errorf("Broadcast queue bind error: %s", err)
+	}
+
+	return queue.Name, nil
+}
+
+// awaitConfirm waits until the broker confirms a published message, with
+// publisher confirms enabled it gives up once the confirm timeout passes
+func (b *AMQPBroker) awaitConfirm(confirmsChan <-chan amqp.Confirmation) error {
+	var timeout <-chan time.Time
+	if b.cnf.AMQP.PublisherConfirms {
+		timer := time.NewTimer(b.publishConfirmTimeout())
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case confirmed, ok := <-confirmsChan:
+		if !ok {
+			return errors.New("Channel closed before delivery was confirmed")
+		}
+		if !confirmed.Ack {
+			return fmt.Errorf("Failed delivery of delivery tag: %v", confirmed.DeliveryTag)
+		}
 		return nil
+	case <-timeout:
+		return fmt.Errorf("Delivery not confirmed within %s", b.publishConfirmTimeout())
 	}
+}
 
-	return fmt.Errorf("Failed delivery of delivery tag: %v", confirmed.DeliveryTag)
+// publishConfirmTimeout returns how long to wait for publisher confirms
+func (b *AMQPBroker) publishConfirmTimeout() time.Duration {
+	if timeout := b.cnf.AMQP.PublishConfirmTimeout; timeout > 0 {
+		return time.Duration(timeout) * time.Second
+	}
+	return 10 * time.Second
 }
 
-// consume takes delivered messages from the channel and manages a worker pool
-// to process tasks concurrently
-func (b *AMQPBroker) consume(deliveries <-chan amqp.Delivery, concurrency int, taskProcessor TaskProcessor, amqpCloseChan <-chan *amqp.Error) error {
-	pool := make(chan struct{}, concurrency)
+// QueueLength returns the number of messages ready to be delivered from the
+// queue, the queue is declared passively so it is neither created nor changed
+func (b *AMQPBroker) QueueLength(queue string) (int, error) {
+	if queue == "" {
+		queue = b.cnf.DefaultQueue
+	}
+
+	conn, channel, err := b.Open(b.cnf.Broker, b.cnf.TLSConfig)
+	if err != nil {
+		return 0, err
+	}
+	defer b.Close(channel, conn)
+
+	q, err := channel.QueueDeclarePassive(
+		queue, // name
+		false, // durable
+		false, // delete when unused
+		false, // exclusive
+		false, // no-wait
+		nil,   // arguments
+	)
+	if err != nil {
+		return 0, fmt.Errorf("Queue declare error: %s", err)
+	}
+	return q.Messages, nil
+}
+
+// PurgeQueue removes all messages ready to be delivered from the queue,
+// messages delivered to consumers but not acknowledged yet are left alone
+func (b *AMQPBroker) PurgeQueue(queue string) (int, error) {
+	if queue == "" {
+		queue = b.cnf.DefaultQueue
+	}
+
+	conn, channel, err := b.Open(b.cnf.Broker, b.cnf.TLSConfig)
+	if err != nil {
+		return 0, err
+	}
+	defer b.Close(channel, conn)
+
+	purged, err := channel.QueuePurge(
+		queue, // name
+		false, // no-wait
+	)
+	if err != nil {
+		return 0, fmt.Errorf("Queue purge error: %s", err)
+	}
+	return purged, nil
+}
+
+// DrainQueue gets the messages ready to be delivered from the queue when it
+// is called and acks each of them once it has been handed to fn, messages
+// which cannot be decoded are requeued
+func (b *AMQPBroker) DrainQueue(queue string, fn func(signature *tasks.Signature)) (int, error) {
+	if queue == "" {
+		queue = b.cnf.DefaultQueue
+	}
+
+	conn, channel, err := b.Open(b.cnf.Broker, b.cnf.TLSConfig)
+	if err != nil {
+		return 0, err
+	}
+	defer b.Close(channel, conn)
+
+	q, err := channel.QueueDeclarePassive(
+		queue, // name
+		false, // durable
+		false, // delete when unused
+		false, // exclusive
+		false, // no-wait
+		nil,   // arguments
+	)
+	if err != nil {
+		return 0, fmt.Errorf("Queue declare error: %s", err)
+	}
+
+	// Only drain messages enqueued so far, producers may keep publishing.
+	// Messages which cannot be decoded are only requeued once draining
+	// finished so they are not got again meanwhile, closing the channel
+	// requeues them if draining fails.
+	drained := 0
+	var undecodable []amqp.Delivery
+	for i := 0; i < q.Messages; i++ {
+		delivery, ok, err := channel.Get(
+			queue, // queue
+			false, // auto-ack
+		)
+		if err != nil {
+			return drained, fmt.Errorf("Queue get error: %s", err)
+		}
+		if !ok {
+			break
+		}
+
+		signature := new(tasks.Signature)
+		if err := b.unmarshalEncoded(delivery.Body, delivery.ContentType, delivery.ContentEncoding, signature); err != nil {
+			log.ERROR.Print(NewErrCouldNotUnmarshaTaskSignature(delivery.Body, err))
+			undecodable = append(undecodable, delivery)
+			continue
+		}
+
+		fn(signature)
+		if err := delivery.Ack(false); err != nil {
+			return drained, fmt.Errorf("Ack error: %s", err)
+		}
+		drained++
+	}
+
+	for _, delivery := range undecodable {
+		if err := delivery.Nack(false, true); err != nil {
+			return drained, fmt.Errorf("Nack error: %s", err)
+		}
+	}
+	return drained, nil
+}
+
+// PeekTasks returns up to limit tasks waiting in the queue, the default queue
+// if empty. AMQP cannot read messages without getting them, so this is not
+// truly non-destructive: the messages are got and requeued once all of them
+// have been read. Meanwhile they are not delivered to consumers, requeued
+// messages are flagged as redelivered and may lose their place in the queue.
+func (b *AMQPBroker) PeekTasks(queue string, limit int) ([]*tasks.Signature, error) {
+	if queue == "" {
+		queue = b.cnf.DefaultQueue
+	}
+
+	conn, channel, err := b.Open(b.cnf.Broker, b.cnf.TLSConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer b.Close(channel, conn)
 
-	// initialize worker pool with maxWorkers workers
+	q, err := channel.QueueDeclarePassive(
+		queue, // name
+		false, // durable
+		false, // delete when unused
+		false, // exclusive
+		false, // no-wait
+		nil,   // arguments
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Queue declare error: %s", err)
+	}
+
+	// Messages stay unacked until all of them have been got so none is got
+	// twice, they are requeued in the order they were got
+	var (
+		signatures []*tasks.Signature
+		lastTag    uint64
+	)
+	for i := 0; i < q.Messages && i < limit; i++ {
+		delivery, ok, err := channel.Get(
+			queue, // queue
+			false, // auto-ack
+		)
+		if err != nil {
+			// Messages got so far are requeued when the channel is closed
+			return nil, fmt.Errorf("Queue get error: %s", err)
+		}
+		if !ok {
+			break
+		}
+		lastTag = delivery.DeliveryTag
+
+		signature := new(tasks.Signature)
+		if err := b.unmarshalEncoded(delivery.Body, delivery.ContentType, delivery.ContentEncoding, signature); err != nil {
+			log.ERROR.Print(NewErrCouldNotUnmarshaTaskSignature(delivery.Body, err))
+			continue
+		}
+		signatures = append(signatures, signature)
+	}
+
+	if lastTag > 0 {
+		if err := channel.Nack(lastTag, true, true); err != nil {
+			return nil, fmt.Errorf("Nack error: %s", err)
+		}
+	}
+	return signatures, nil
+}
+
+// TakeTasks removes up to limit tasks waiting in the queue, the default queue
+// if empty, each of them once fn handled it without an error. Deliveries which
+// are not acked are requeued when the channel is closed.
+func (b *AMQPBroker) TakeTasks(queue string, limit int, fn func(signature *tasks.Signature) error) (int, error) {
+	if queue == "" {
+		queue = b.cnf.DefaultQueue
+	}
+
+	conn, channel, err := b.Open(b.cnf.Broker, b.cnf.TLSConfig)
+	if err != nil {
+		return 0, err
+	}
+	defer b.Close(channel, conn)
+
+	q, err := channel.QueueDeclarePassive(
+		queue, // name
+		false, // durable
+		false, // delete when unused
+		false, // exclusive
+		false, // no-wait
+		nil,   // arguments
+	)
+	if err != nil {
+		return 0, fmt.Errorf("Queue declare error: %s", err)
+	}
+
+	// Only take messages enqueued so far, skipped ones stay unacked until the
+	// channel is closed so they are not got again
+	taken := 0
+	for i := 0; i < q.Messages && taken < limit; i++ {
+		delivery, ok, err := channel.Get(
+			queue, // queue
+			false, // auto-ack
+		)
+		if err != nil {
+			return taken, fmt.Errorf("Queue get error: %s", err)
+		}
+		if !ok {
+			break
+		}
+
+		signature := new(tasks.Signature)
+		if err := b.unmarshalEncoded(delivery.Body, delivery.ContentType, delivery.ContentEncoding, signature); err != nil {
+			log.ERROR.Print(NewErrCouldNotUnmarshaTaskSignature(delivery.Body, err))
+			continue
+		}
+
+		if err := fn(signature); err != nil {
+			return taken, err
+		}
+		if err := delivery.Ack(false); err != nil {
+			return taken, fmt.Errorf("Ack error: %s", err)
+		}
+		taken++
+	}
+	return taken, nil
+}
+
+// PublishBatch places new messages on the default queue, tasks with the same
+// routing key are published over a single channel and their publisher
+// confirms are awaited together
+func (b *AMQPBroker) PublishBatch(signatures []*tasks.Signature) []error {
+	errs := make([]error, len(signatures))
+
+	var (
+		routingKeys []string
+		batches     = make(map[string][]int)
+	)
+	for i, signature := range signatures {
+		// Adjust routing key (this decides which queue the message will be published to)
+		AdjustRoutingKey(b, signature)
+
+		if err := b.checkPriority(signature); err != nil {
+			errs[i] = err
+			continue
+		}
+
+		// Delayed tasks are published to their own queues
+		if signature.ETA != nil && signature.ETA.After(time.Now().UTC()) {
+			errs[i] = b.Publish(signature)
+			continue
+		}
+
+		if _, ok := batches[signature.RoutingKey]; !ok {
+			routingKeys = append(routingKeys, signature.RoutingKey)
+		}
+		batches[signature.RoutingKey] = append(batches[signature.RoutingKey], i)
+	}
+
+	for _, routingKey := range routingKeys {
+		b.publishBatch(signatures, batches[routingKey], errs)
+	}
+
+	return errs
+}
+
+// publishBatch publishes tasks at the given indexes which share the same
+// routing key over a single channel, errors are set at the same indexes
+func (b *AMQPBroker) publishBatch(signatures []*tasks.Signature, indexes []int, errs []error) {
+	setErr := func(indexes []int, err error) {
+		for _, i := range indexes {
+			errs[i] = err
+		}
+	}
+
+	first := signatures[indexes[0]]
+	conn, channel, _, confirmsChan, _, err := b.Connect(
+		b.cnf.Broker,
+		b.cnf.TLSConfig,
+		b.cnf.AMQP.Exchange,                     // exchange name
+		b.cnf.AMQP.ExchangeType,                 // exchange type
+		first.RoutingKey,                        // queue name
+		b.queueDurable(),                        // queue durable
+		false,                                   // queue delete when unused
+		b.bindingKey(first),                     // queue binding key
+		b.exchangeDeclareArgs(),                 // exchange declare args
+		b.queueDeclareArgs(),                    // queue declare args
+		amqp.Table(b.cnf.AMQP.QueueBindingArgs), // queue binding args
+	)
+	if err != nil {
+		setErr(indexes, err)
+		return
+	}
+	defer b.Close(channel, conn)
+
+	// Drain confirms as they arrive so publishing is not blocked on them, the
+	// channel is closed when the AMQP channel is closed
+	confirmed := make(chan amqp.Confirmation, len(indexes))
 	go func() {
-		for i := 0; i < concurrency; i++ {
-			pool <- struct{}{}
+		defer close(confirmed)
+		for confirmation := range confirmsChan {
+			confirmed <- confirmation
 		}
 	}()
 
+	var published []int
+	for n, i := range indexes {
+		msg, contentType, err := b.marshal(signatures[i])
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+
+		if err := channel.Publish(
+			b.cnf.AMQP.Exchange,      // exchange name
+			signatures[i].RoutingKey, // routing key
+			false,                    // mandatory
+			false,                    // immediate
+			amqp.Publishing{
+				Headers:         amqp.Table(signatures[i].Headers),
+				ContentType:     contentType,
+				ContentEncoding: contentEncoding(msg),
+				Body:            msg,
+				DeliveryMode:    b.deliveryMode(),
+				Priority:        signatures[i].Priority,
+			},
+		); err != nil {
+			// The channel is unusable after a failed publish
+			setErr(indexes[n:], err)
+			break
+		}
+		published = append(published, i)
+	}
+
+	// With publisher confirms enabled, the whole batch has to be confirmed
+	// within the confirm timeout
+	var timeout <-chan time.Time
+	if b.cnf.AMQP.PublisherConfirms {
+		timer := time.NewTimer(b.publishConfirmTimeout())
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	// Confirms arrive in the order messages were published
+	for n, i := range published {
+		select {
+		case confirmation, ok := <-confirmed:
+			if !ok {
+				errs[i] = errors.New("Channel closed before delivery was confirmed")
+				continue
+			}
+			if !confirmation.Ack {
+				errs[i] = fmt.Errorf("Failed delivery of delivery tag: %v", confirmation.DeliveryTag)
+			}
+		case <-timeout:
+			setErr(published[n:], fmt.Errorf("Delivery not confirmed within %s", b.publishConfirmTimeout()))
+			return
+		}
+	}
+}
+
+// consume takes delivered messages from the channel and processes them
+// concurrently, taking a slot of the worker pool for each of them unless the
+// pool is nil, until stop receives or the connection closes
+func (b *AMQPBroker) consume(deliveries <-chan amqp.Delivery, pool chan struct{}, taskProcessor TaskProcessor, amqpCloseChan <-chan *amqp.Error, stop <-chan int) error {
 	errorsChan := make(chan error)
 
 	for {
+		// Stop reading deliveries while consuming is paused, prefetched
+		// messages stay unacknowledged until it is resumed
+		paused, pauseChanged := b.pauseState()
+		next := deliveries
+		if paused {
+			next = nil
+		}
+
 		select {
 		case amqpErr := <-amqpCloseChan:
 			return amqpErr
 		case err := <-errorsChan:
 			return err
-		case d := <-deliveries:
-			if concurrency > 0 {
+		case <-pauseChanged:
+			// Check again whether consuming is paused
+		case d := <-next:
+			if pool != nil {
 				// get worker from pool (blocks until one is available)
 				<-pool
 			}
@@ -192,17 +991,158 @@ func (b *AMQPBroker) consume(deliveries <-chan amqp.Delivery, concurrency int, t
 
 				b.processingWG.Done()
 
-				if concurrency > 0 {
+				if pool != nil {
 					// give worker back to pool
 					pool <- struct{}{}
 				}
 			}()
-		case <-b.stopChan:
+		case <-stop:
 			return nil
 		}
 	}
 }
 
+// consumerGroup holds consumers sharing a worker pool
+type consumerGroup struct {
+	consumers []<-chan amqp.Delivery
+	pool      chan struct{}
+}
+
+// groupConsumers groups the consumers of the queues by worker pool, queues
+// with a concurrency of their own are consumed with their own pool and the
+// other queues share the pool of the worker
+func groupConsumers(queueNames []string, consumers []<-chan amqp.Delivery, pools *workerPools) []consumerGroup {
+	var (
+		shared []<-chan amqp.Delivery
+		groups []consumerGroup
+	)
+	for i, queueName := range queueNames {
+		if pool, ok := pools.queues[queueName]; ok {
+			groups = append(groups, consumerGroup{consumers: consumers[i : i+1], pool: pool})
+		} else {
+			shared = append(shared, consumers[i])
+		}
+	}
+	if len(shared) > 0 {
+		groups = append([]consumerGroup{{consumers: shared, pool: pools.shared}}, groups...)
+	}
+	return groups
+}
+
+// consumeGroups consumes the deliveries of each group with its own pool, so
+// a busy pool does not hold back deliveries of the other groups, until stop
+// receives, the connection closes or processing a delivery of any of them
+// fails
+func (b *AMQPBroker) consumeGroups(groups []consumerGroup, taskProcessor TaskProcessor, amqpCloseChan <-chan *amqp.Error, stop <-chan int) error {
+	done := make(chan struct{})
+	defer close(done)
+
+	if len(groups) == 1 {
+		deliveries := mergeDeliveries(done, groups[0].consumers)
+		return b.consume(deliveries, groups[0].pool, taskProcessor, amqpCloseChan, stop)
+	}
+
+	var (
+		stopGroups = make(chan int)
+		errorsChan = make(chan error, len(groups))
+		wg         sync.WaitGroup
+	)
+	wg.Add(len(groups))
+	for _, group := range groups {
+		go func(group consumerGroup) {
+			defer wg.Done()
+			deliveries := mergeDeliveries(done, group.consumers)
+			if err := b.consume(deliveries, group.pool, taskProcessor, nil, stopGroups); err != nil {
+				errorsChan <- err
+			}
+		}(group)
+	}
+
+	var err error
+	select {
+	case amqpErr := <-amqpCloseChan:
+		if amqpErr != nil {
+			err = amqpErr
+		}
+	case err = <-errorsChan:
+	case <-stop:
+	}
+	close(stopGroups)
+	wg.Wait()
+	return err
+}
+
+// workerPools holds the worker pool shared by the queues of a task processor
+// and the pools of queues with a concurrency of their own
+type workerPools struct {
+	shared chan struct{}
+	queues map[string]chan struct{}
+}
+
+// newWorkerPools returns the worker pools of the task processor, the shared
+// one is limited to the concurrency
+func newWorkerPools(concurrency int, taskProcessor TaskProcessor) *workerPools {
+	pools := &workerPools{shared: newWorkerPool(concurrency)}
+	for queue, s := range queueSettings(taskProcessor) {
+		if s.Concurrency <= 0 {
+			continue
+		}
+		if pools.queues == nil {
+			pools.queues = make(map[string]chan struct{})
+		}
+		pools.queues[queue] = newWorkerPool(s.Concurrency)
+	}
+	return pools
+}
+
+// newWorkerPool returns a pool of concurrency slots for processing tasks,
+// or nil if concurrency is not limited
+func newWorkerPool(concurrency int) chan struct{} {
+	if concurrency <= 0 {
+		return nil
+	}
+
+	pool := make(chan struct{}, concurrency)
+	for i := 0; i < concurrency; i++ {
+		pool <- struct{}{}
+	}
+	return pool
+}
+
+// mergeDeliveries forwards the deliveries of all consumers to a single channel
+// until done is closed, the channel is closed once all consumers are. Each
+// delivery is still acknowledged on its own.
+func mergeDeliveries(done <-chan struct{}, consumers []<-chan amqp.Delivery) <-chan amqp.Delivery {
+	if len(consumers) == 1 {
+		return consumers[0]
+	}
+
+	var (
+		merged = make(chan amqp.Delivery)
+		wg     sync.WaitGroup
+	)
+	wg.Add(len(consumers))
+	for _, consumer := range consumers {
+		go func(consumer <-chan amqp.Delivery) {
+			defer wg.Done()
+			for d := range consumer {
+				select {
+				case merged <- d:
+				case <-done:
+					return
+				}
+			}
+		}(consumer)
+	}
+
+	go func() {
+		wg.Wait()
+		close(merged)
+	}()
+
+	return merged
+}
+
 // consumeOne processes a single message using TaskProcessor
 func (b *AMQPBroker) consumeOne(delivery amqp.Delivery, taskProcessor TaskProcessor) error {
 	if len(delivery.Body) == 0 {
@@ -214,16 +1154,25 @@ func (b *AMQPBroker) consumeOne(delivery amqp.Delivery, taskProcessor TaskProces
 
 	// Unmarshal message body into signature struct
 	signature := new(tasks.Signature)
-	decoder := json.NewDecoder(bytes.NewReader(delivery.Body))
-	decoder.UseNumber()
-	if err := decoder.Decode(signature); err != nil {
-		delivery.Nack(multiple, requeue)
-		return NewErrCouldNotUnmarshaTaskSignature(delivery.Body, err)
+	if err := b.unmarshalEncoded(delivery.Body, delivery.ContentType, delivery.ContentEncoding, signature); err != nil {
+		// A message failing verification is never processed nor requeued, it
+		// is dead lettered if the queue has a dead letter exchange
+		if isSignatureError(err) {
+			log.ERROR.Printf("Rejecting message failing verification: %s. Error = %v", delivery.Body, err)
+			delivery.Nack(multiple, false)
+			return nil
+		}
+		// A malformed message would fail the same way on every worker, so unless
+		// requeueing is explicitly enabled it is rejected (and dead lettered if
+		// the queue has a dead letter exchange). Consuming carries on either way.
+		log.ERROR.Print(NewErrCouldNotUnmarshaTaskSignature(delivery.Body, err))
+		delivery.Nack(multiple, b.cnf.AMQP.RequeueOnDecodeError)
+		return nil
 	}
 
 	// If the task is not registered, we nack it and requeue,
 	// there might be different workers for processing specific tasks
-	if !b.IsTaskRegistered(signature.Name) {
+	if !b.acceptsTask(signature.Name) {
 		if !delivery.Redelivered {
 			requeue = true
 			log.INFO.Printf("Task not registered with this worker. Requeing message: %s", delivery.Body)
@@ -232,13 +1181,144 @@ func (b *AMQPBroker) consumeOne(delivery amqp.Delivery, taskProcessor TaskProces
 		return nil
 	}
 
+	// A message redelivered by the broker was not acked, e.g. because the
+	// worker processing it crashed. With MaxRedeliveries it is counted like a
+	// failed attempt, so a message crashing every worker is not redelivered
+	// forever; the counted copy is processed once it is consumed.
+	if delivery.Redelivered && b.cnf.MaxRedeliveries > 0 {
+		log.ERROR.Printf("Received message redelivered by the broker: %s", delivery.Body)
+		b.redeliver(delivery, errors.New("Message was not acknowledged"))
+		return nil
+	}
+
 	log.INFO.Printf("Received new message: %s", delivery.Body)
 
-	err := taskProcessor.Process(signature)
+	if b.cnf.AckLate {
+		return b.processAckLate(delivery, signature, taskProcessor)
+	}
+
+	err := processDelivery(taskProcessor, signature, deliveryInfo(delivery, signature))
 	delivery.Ack(multiple)
 	return err
 }
 
+// deliveryInfo returns the details of the delivery passed to tasks
+func deliveryInfo(delivery amqp.Delivery, signature *tasks.Signature) tasks.DeliveryInfo {
+	var headers map[string]interface{}
+	if len(delivery.Headers) > 0 {
+		headers = make(map[string]interface{}, len(delivery.Headers))
+		for k, v := range delivery.Headers {
+			headers[k] = v
+		}
+	}
+
+	// Messages republished with MaxRedeliveries count their redeliveries in
+	// the signature, quorum queues count requeued ones in x-delivery-count
+	deliveryCount := 1 + redeliveries(signature)
+	switch value := delivery.Headers["x-delivery-count"].(type) {
+	case int64:
+		deliveryCount += int(value)
+	case int32:
+		deliveryCount += int(value)
+	case int:
+		deliveryCount += value
+	}
+
+	return tasks.DeliveryInfo{
+		Headers:       headers,
+		Redelivered:   delivery.Redelivered || deliveryCount > 1,
+		RoutingKey:    delivery.RoutingKey,
+		DeliveryCount: deliveryCount,
+	}
+}
+
+// processAckLate acks the delivery only once the task has been processed, if
+// processing returns an error, times out or panics the delivery is requeued
+// instead so it is picked up again by this or another worker. The error is
+// not returned once the delivery has been handled, so consuming goes on.
+func (b *AMQPBroker) processAckLate(delivery amqp.Delivery, signature *tasks.Signature, taskProcessor TaskProcessor) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("Processing task %s panicked: %v", signature.UUID, e)
+		}
+
+		if err != nil {
+			log.ERROR.Printf("Failed processing task %s, redelivering it: %s", signature.UUID, err)
+			b.redeliver(delivery, err)
+			err = nil
+			return
+		}
+
+		delivery.Ack(false) // multiple
+	}()
+
+	return processDelivery(taskProcessor, signature, deliveryInfo(delivery, signature))
+}
+
+// redeliver requeues a message which could not be processed. With
+// MaxRedeliveries set the message is published again with its redeliveries
+// counted in a header instead, once it has been redelivered MaxRedeliveries
+// times it is sent to the dead letter queue, or rejected if there is none.
+func (b *AMQPBroker) redeliver(delivery amqp.Delivery, processErr error) {
+	maxRedeliveries := b.cnf.MaxRedeliveries
+	if maxRedeliveries <= 0 {
+		log.ERROR.Printf("Requeueing message: %s", delivery.Body)
+		delivery.Nack(false, true) // multiple, requeue
+		return
+	}
+
+	// The worker may have changed the signature, start from the message
+	signature := new(tasks.Signature)
+	if err := b.unmarshalEncoded(delivery.Body, delivery.ContentType, delivery.ContentEncoding, signature); err != nil {
+		delivery.Nack(false, true) // multiple, requeue
+		return
+	}
+
+	redeliveries := redeliveries(signature)
+	if redeliveries >= maxRedeliveries {
+		b.rejectPoisonMessage(delivery, signature, processErr)
+		return
+	}
+
+	if signature.Headers == nil {
+		signature.Headers = make(tasks.Headers)
+	}
+	signature.Headers[RedeliveriesHeader] = redeliveries + 1
+
+	log.ERROR.Printf("Redelivering message (%d of %d): %s", redeliveries+1, maxRedeliveries, delivery.Body)
+	if err := b.Publish(signature); err != nil {
+		log.ERROR.Printf("Failed redelivering message, requeueing it: %s", err)
+		delivery.Nack(false, true) // multiple, requeue
+		return
+	}
+	delivery.Ack(false) // multiple
+}
+
+// rejectPoisonMessage stops redelivering a message which keeps failing
+func (b *AMQPBroker) rejectPoisonMessage(delivery amqp.Delivery, signature *tasks.Signature, processErr error) {
+	reason := fmt.Errorf("Redelivered %d times: %s", b.cnf.MaxRedeliveries, processErr)
+
+	if b.cnf.DeadLetterQueue == "" {
+		log.ERROR.Printf("Rejecting message: %s. Error = %v", delivery.Body, reason)
+		delivery.Nack(false, false) // multiple, requeue
+		return
+	}
+
+	log.ERROR.Printf("Dead lettering message: %s. Error = %v", delivery.Body, reason)
+	if err := b.Publish(NewDeadLetter(signature, b.cnf.DeadLetterQueue, reason)); err != nil {
+		log.ERROR.Printf("Failed dead lettering message, requeueing it: %s", err)
+		delivery.Nack(false, true) // multiple, requeue
+		return
+	}
+	delivery.Ack(false) // multiple
+}
+
+// redeliveries returns how many times the message of the task has been
+// redelivered
+func redeliveries(signature *tasks.Signature) int {
+	return signature.Headers.Count(RedeliveriesHeader)
+}
+
 // delay a task by delayDuration miliseconds, the way it works is a new queue
 // is created without any consumers, the message is then published to this queue
 // with appropriate ttl expiration headers, after the expiration, it is sent to
@@ -248,9 +1328,9 @@ func (b *AMQPBroker) delay(signature *tasks.Signature, delayMs int64) error {
 		return errors.New("Cannot delay task by 0ms")
 	}
 
-	message, err := json.Marshal(signature)
+	message, contentType, err := b.marshal(signature)
 	if err != nil {
-		return fmt.Errorf("JSON marshal error: %s", err)
+		return err
 	}
 
 	// It's necessary to redeclare the queue each time (to zero its TTL timer).
@@ -258,29 +1338,29 @@ func (b *AMQPBroker) delay(signature *tasks.Signature, delayMs int64) error {
 		"delay.%d.%s.%s",
 		delayMs, // delay duration in mileseconds
 		b.cnf.AMQP.Exchange,
-		b.cnf.AMQP.BindingKey, // routing key
+		b.bindingKey(signature), // routing key
 	)
 	declareQueueArgs := amqp.Table{
 		// Exchange where to send messages after TTL expiration.
 		"x-dead-letter-exchange": b.cnf.AMQP.Exchange,
 		// Routing key which use when resending expired messages.
-		"x-dead-letter-routing-key": b.cnf.AMQP.BindingKey,
+		"x-dead-letter-routing-key": b.bindingKey(signature),
 		// Time in milliseconds
 		// after that message will expire and be sent to destination.
 		"x-message-ttl": delayMs,
 		// Time after that the queue will be deleted.
 		"x-expires": delayMs * 2,
 	}
-	conn, channel, _, _, _, err := b.Connect(
+	conn, channel, _, confirmsChan, _, err := b.Connect(
 		b.cnf.Broker,
 		b.cnf.TLSConfig,
 		b.cnf.AMQP.Exchange,                     // exchange name
 		b.cnf.AMQP.ExchangeType,                 // exchange type
 		queueName,                               // queue name
-		true,                                    // queue durable
+		b.queueDurable(),                        // queue durable
 		false,                                   // queue delete when unused
 		queueName,                               // queue binding key
-		nil,                                     // exchange declare args
+		b.exchangeDeclareArgs(),                 // exchange declare args
 		declareQueueArgs,                        // queue declare args
 		amqp.Table(b.cnf.AMQP.QueueBindingArgs), // queue binding args
 	)
@@ -295,14 +1375,244 @@ func (b *AMQPBroker) delay(signature *tasks.Signature, delayMs int64) error {
 		false,               // mandatory
 		false,               // immediate
 		amqp.Publishing{
-			Headers:      amqp.Table(signature.Headers),
-			ContentType:  "application/json",
-			Body:         message,
-			DeliveryMode: amqp.Persistent,
+			Headers:         amqp.Table(signature.Headers),
+			ContentType:     contentType,
+			ContentEncoding: contentEncoding(message),
+			Body:            message,
+			DeliveryMode:    b.deliveryMode(),
+			Priority:        signature.Priority,
+		},
+	); err != nil {
+		return err
+	}
+
+	if b.cnf.AMQP.PublisherConfirms {
+		return b.awaitConfirm(confirmsChan)
+	}
+	return nil
+}
+
+// delayWithPlugin delays a task by delayMs miliseconds using the RabbitMQ
+// delayed message exchange plugin. The message is published with x-delay header
+// to an exchange of x-delayed-message type, which holds the message back until
+// the delay passes and then routes it like an exchange of the configured type
+func (b *AMQPBroker) delayWithPlugin(signature *tasks.Signature, delayMs int64) error {
+	if delayMs <= 0 {
+		return errors.New("Cannot delay task by 0ms")
+	}
+
+	message, contentType, err := b.marshal(signature)
+	if err != nil {
+		return err
+	}
+
+	conn, channel, _, confirmsChan, _, err := b.Connect(
+		b.cnf.Broker,
+		b.cnf.TLSConfig,
+		b.delayedExchange(),                     // exchange name
+		"x-delayed-message",                     // exchange type
+		signature.RoutingKey,                    // queue name
+		b.queueDurable(),                        // queue durable
+		false,                                   // queue delete when unused
+		b.bindingKey(signature),                 // queue binding key
+		b.delayedExchangeArgs(),                 // exchange declare args
+		b.queueDeclareArgs(),                    // queue declare args
+		amqp.Table(b.cnf.AMQP.QueueBindingArgs), // queue binding args
+	)
+	if err != nil {
+		return err
+	}
+	defer b.Close(channel, conn)
+
+	// Copy the headers so the x-delay header does not end up in the signature
+	headers := amqp.Table{"x-delay": delayMs}
+	for k, v := range signature.Headers {
+		headers[k] = v
+	}
+
+	if err := channel.Publish(
+		b.delayedExchange(),  // exchange
+		signature.RoutingKey, // routing key
+		false,                // mandatory
+		false,                // immediate
+		amqp.Publishing{
+			Headers:         headers,
+			ContentType:     contentType,
+			ContentEncoding: contentEncoding(message),
+			Body:            message,
+			DeliveryMode:    b.deliveryMode(),
+			Priority:        signature.Priority,
 		},
 	); err != nil {
 		return err
 	}
 
+	if b.cnf.AMQP.PublisherConfirms {
+		return b.awaitConfirm(confirmsChan)
+	}
+	return nil
+}
+
+// queueDurable returns whether task queues are declared durable so they
+// survive broker restarts
+func (b *AMQPBroker) queueDurable() bool {
+	return !b.cnf.AMQP.Transient
+}
+
+// deliveryMode returns the delivery mode of published tasks, persistent
+// messages in durable queues survive broker restarts
+func (b *AMQPBroker) deliveryMode() uint8 {
+	if b.cnf.AMQP.Transient {
+		return amqp.Transient
+	}
+	return amqp.Persistent
+}
+
+// bindDelayedExchange declares the delayed message exchange and binds a queue
+// to it with the binding key
+func (b *AMQPBroker) bindDelayedExchange(channel *amqp.Channel, queueName, bindingKey string) error {
+	if err := channel.ExchangeDeclare(
+		b.delayedExchange(),     // name of the exchange
+		"x-delayed-message",     // type
+		true,                    // durable
+		false,                   // delete when complete
+		false,                   // internal
+		false,                   // noWait
+		b.delayedExchangeArgs(), // arguments
+	); err != nil {
+		return fmt.Errorf("Delayed exchange declare error: %s", err)
+	}
+
+	if err := channel.QueueBind(
+		queueName,                               // name of the queue
+		bindingKey,                              // binding key
+		b.delayedExchange(),                     // source exchange
+		false,                                   // noWait
+		amqp.Table(b.cnf.AMQP.QueueBindingArgs), // arguments
+	); err != nil {
+		return fmt.Errorf("Delayed exchange queue bind error: %s", err)
+	}
+
 	return nil
 }
+
+// delayedExchange returns name of the exchange used with the RabbitMQ delayed
+// message exchange plugin
+func (b *AMQPBroker) delayedExchange() string {
+	return b.cnf.AMQP.Exchange + ".delayed"
+}
+
+// delayedExchangeArgs returns arguments for declaring the delayed message
+// exchange, it routes messages the same way as the configured exchange type
+func (b *AMQPBroker) delayedExchangeArgs() amqp.Table {
+	return amqp.Table{"x-delayed-type": b.cnf.AMQP.ExchangeType}
+}
+
+// exchangeDeclareArgs returns the configured arguments used to declare the
+// exchange
+func (b *AMQPBroker) exchangeDeclareArgs() amqp.Table {
+	if b.cnf.AMQP == nil {
+		return nil
+	}
+	return declareArgs(b.cnf.AMQP.ExchangeArgs)
+}
+
+// queueDeclareArgs returns arguments used to declare task queues, the
+// configured queue arguments are used and queues are declared as priority
+// queues if max priority is configured
+func (b *AMQPBroker) queueDeclareArgs() amqp.Table {
+	if b.cnf.AMQP == nil {
+		return nil
+	}
+	args := declareArgs(b.cnf.AMQP.QueueArgs)
+	if b.cnf.AMQP.MaxPriority == 0 {
+		return args
+	}
+	if args == nil {
+		args = make(amqp.Table, 1)
+	}
+	// Encoded as a signed integer, a byte would wrap above 127
+	args["x-max-priority"] = int32(b.cnf.AMQP.MaxPriority)
+	return args
+}
+
+// declareArgs copies configured arguments into a table, ints e.g. decoded from
+// YAML are not supported by the AMQP table encoding so they are sent as int64
+func declareArgs(args map[string]interface{}) amqp.Table {
+	if len(args) == 0 {
+		return nil
+	}
+	table := make(amqp.Table, len(args))
+	for k, v := range args {
+		if i, ok := v.(int); ok {
+			v = int64(i)
+		}
+		table[k] = v
+	}
+	return table
+}
+
+// checkPriority returns an error if the task priority exceeds max priority of
+// the queues
+func (b *AMQPBroker) checkPriority(signature *tasks.Signature) error {
+	var maxPriority uint8
+	if b.cnf.AMQP != nil {
+		maxPriority = b.cnf.AMQP.MaxPriority
+	}
+	if signature.Priority > maxPriority {
+		return fmt.Errorf("Task priority %d exceeds max priority %d", signature.Priority, maxPriority)
+	}
+	return nil
+}
+
+// bindingKey returns the key for binding a queue which should receive the task.
+// For direct exchange it has to match the routing key, otherwise the configured
+// binding key is used
+func (b *AMQPBroker) bindingKey(signature *tasks.Signature) string {
+	if b.isDirectExchange() {
+		return signature.RoutingKey
+	}
+	return b.cnf.AMQP.BindingKey
+}
+
+// queueBindingKey returns the binding key of a queue consumed by workers. A
+// direct exchange routes tasks to the queues whose binding key matches the
+// routing key exactly, so a custom queue is bound by its name to receive tasks
+// sent with the queue name as the routing key.
+func (b *AMQPBroker) queueBindingKey(queueName string) string {
+	if queueName != b.cnf.DefaultQueue && b.isDirectExchange() {
+		return queueName
+	}
+	return b.cnf.AMQP.BindingKey
+}
+
+// declareQueue declares the queue and binds it to the exchange
+func (b *AMQPBroker) declareQueue(channel *amqp.Channel, queueName string) error {
+	if _, err := channel.QueueDeclare(
+		queueName,            // name
+		b.queueDurable(),     // durable
+		false,                // delete when unused
+		false,                // exclusive
+		false,                // no-wait
+		b.queueDeclareArgs(), // arguments
+	); err != nil {
+		return fmt.Errorf("Queue declare error: %s", err)
+	}
+
+	if err := channel.QueueBind(
+		queueName,                               // name of the queue
+		b.queueBindingKey(queueName),            // binding key
+		b.cnf.AMQP.Exchange,                     // source exchange
+		false,                                   // noWait
+		amqp.Table(b.cnf.AMQP.QueueBindingArgs), // arguments
+	); err != nil {
+		return fmt.Errorf("Queue bind error: %s", err)
+	}
+
+	return nil
+}
+
+// isDirectExchange returns true if the configured exchange is of direct type
+func (b *AMQPBroker) isDirectExchange() bool {
+	return b.cnf.AMQP != nil && b.cnf.AMQP.ExchangeType == "direct"
+}
@@ -0,0 +1,55 @@
+package brokers
+
+import (
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"github.com/streadway/amqp"
+)
+
+func (b *AMQPBroker) ConsumeOneForTest(delivery amqp.Delivery, taskProcessor TaskProcessor) error {
+	return b.consumeOne(delivery, taskProcessor)
+}
+
+func (b *AMQPBroker) ConsumeForTest(deliveries <-chan amqp.Delivery, concurrency int, taskProcessor TaskProcessor, amqpCloseChan <-chan *amqp.Error) error {
+	return b.consume(deliveries, newWorkerPool(concurrency), taskProcessor, amqpCloseChan, b.stopChan)
+}
+
+func (b *AMQPBroker) ConsumeQueuesForTest(queueNames []string, consumers []<-chan amqp.Delivery, concurrency int, taskProcessor TaskProcessor, amqpCloseChan <-chan *amqp.Error) error {
+	groups := groupConsumers(queueNames, consumers, newWorkerPools(concurrency, taskProcessor))
+	return b.consumeGroups(groups, taskProcessor, amqpCloseChan, b.stopChan)
+}
+
+func (b *AMQPBroker) PrefetchCountForTest(queueName string, concurrency int, settings QueueSettings) int {
+	return b.prefetchCount(queueName, concurrency, settings)
+}
+
+func (b *AMQPBroker) AwaitConfirmForTest(confirmsChan <-chan amqp.Confirmation) error {
+	return b.awaitConfirm(confirmsChan)
+}
+
+func (b *AMQPBroker) QueueDurableForTest() bool {
+	return b.queueDurable()
+}
+
+func (b *AMQPBroker) DeliveryModeForTest() uint8 {
+	return b.deliveryMode()
+}
+
+func (b *AMQPBroker) QueueDeclareArgsForTest() amqp.Table {
+	return b.queueDeclareArgs()
+}
+
+func (b *AMQPBroker) ExchangeDeclareArgsForTest() amqp.Table {
+	return b.exchangeDeclareArgs()
+}
+
+func (b *AMQPBroker) QueueBindingKeyForTest(queueName string) string {
+	return b.queueBindingKey(queueName)
+}
+
+func (b *AMQPBroker) IsDeadLetterForTest(signature *tasks.Signature) bool {
+	return b.isDeadLetter(signature)
+}
+
+func MergeDeliveriesForTest(done <-chan struct{}, consumers []<-chan amqp.Delivery) <-chan amqp.Delivery {
+	return mergeDeliveries(done, consumers)
+}
@@ -1,9 +1,9 @@
 package brokers
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -41,10 +41,14 @@ func NewAWSSQSBroker(cnf *config.Config) Interface {
 	} else {
 		// Initialize a session that the SDK will use to load credentials from the shared credentials file, ~/.aws/credentials.
 		// See details on: https://docs.aws.amazon.com/sdk-for-go/v1/developer-guide/configuring-sdk.html
-		// Also, env AWS_REGION is also required
-		b.sess = session.Must(session.NewSessionWithOptions(session.Options{
+		// Also, env AWS_REGION is also required unless the region is configured
+		options := session.Options{
 			SharedConfigState: session.SharedConfigEnable,
-		}))
+		}
+		if cnf.SQS != nil && cnf.SQS.Region != "" {
+			options.Config.Region = aws.String(cnf.SQS.Region)
+		}
+		b.sess = session.Must(session.NewSessionWithOptions(options))
 		b.service = sqs.New(b.sess)
 	}
 
@@ -58,8 +62,16 @@ func (b *AWSSQSBroker) GetPendingTasks(queue string) ([]*tasks.Signature, error)
 
 // StartConsuming enters a loop and waits for incoming messages
 func (b *AWSSQSBroker) StartConsuming(consumerTag string, concurrency int, taskProcessor TaskProcessor) (bool, error) {
+	if len(b.getQueues(taskProcessor)) > 1 {
+		return false, errors.New("AWS SQS broker does not support consuming from multiple queues")
+	}
+
 	b.startConsuming(consumerTag, taskProcessor)
-	qURL := b.defaultQueueURL()
+	qURL := b.getQueueURL(taskProcessor)
+	// There is no connection to keep, the broker counts as connected while
+	// receiving messages
+	b.connected()
+	defer b.disconnected()
 	deliveries := make(chan *sqs.ReceiveMessageOutput)
 
 	b.stopReceivingChan = make(chan int)
@@ -71,6 +83,11 @@ func (b *AWSSQSBroker) StartConsuming(consumerTag string, concurrency int, taskP
 		log.INFO.Print("[*] Waiting for messages. To exit press CTRL+C")
 
 		for {
+			// Do not receive messages while consuming is paused
+			if !b.waitUntilResumed(b.stopReceivingChan) {
+				return
+			}
+
 			select {
 			// A way to stop this goroutine from b.StopConsuming
 			case <-b.stopReceivingChan:
@@ -112,7 +129,7 @@ func (b *AWSSQSBroker) StopConsuming() {
 	b.stopReceiving()
 
 	// Waiting for any tasks being processed to finish
-	b.processingWG.Wait()
+	b.waitForProcessing(&b.processingWG)
 
 	// Waiting for the receiving goroutine to have stopped
 	b.receivingWG.Wait()
@@ -121,10 +138,12 @@ func (b *AWSSQSBroker) StopConsuming() {
 // Publish places a new message on the default queue
 func (b *AWSSQSBroker) Publish(signature *tasks.Signature) error {
 
-	msg, err := json.Marshal(signature)
+	// Message bodies must be text, so they are never compressed
+	msg, _, err := b.encode(signature)
 	if err != nil {
-		return fmt.Errorf("JSON marshal error: %s", err)
+		return err
 	}
+	msg = b.sign(msg)
 
 	// Check that signature.RoutingKey is set, if not switch to DefaultQueue
 	AdjustRoutingKey(b, signature)
@@ -200,33 +219,61 @@ func (b *AWSSQSBroker) consumeOne(delivery *sqs.ReceiveMessageOutput, taskProces
 	}
 
 	sig := new(tasks.Signature)
-	decoder := json.NewDecoder(strings.NewReader(*delivery.Messages[0].Body))
-	decoder.UseNumber()
-	if err := decoder.Decode(sig); err != nil {
+	if err := b.unmarshal([]byte(*delivery.Messages[0].Body), "", sig); err != nil {
 		log.ERROR.Printf("unmarshal error. the delivery is %v", delivery)
 		return err
 	}
 
 	// If the task is not registered return an error
 	// and leave the message in the queue
-	if !b.IsTaskRegistered(sig.Name) {
+	if !b.acceptsTask(sig.Name) {
 		return fmt.Errorf("task %s is not registered", sig.Name)
 	}
 
-	err := taskProcessor.Process(sig)
+	err := processDelivery(taskProcessor, sig, b.deliveryInfo(delivery.Messages[0], taskProcessor))
 	if err != nil {
 		return err
 	}
 	// Delete message after successfully consuming and processing the message
-	if err = b.deleteOne(delivery); err != nil {
+	if err = b.deleteOne(delivery, b.getQueueURL(taskProcessor)); err != nil {
 		log.ERROR.Printf("error when deleting the delivery. the delivery is %v", delivery)
 	}
 	return err
 }
 
-// deleteOne is a method delete a delivery from AWS SQS
-func (b *AWSSQSBroker) deleteOne(delivery *sqs.ReceiveMessageOutput) error {
-	qURL := b.defaultQueueURL()
+// deliveryInfo returns the details of the message passed to tasks, its
+// string and binary message attributes are passed as headers
+func (b *AWSSQSBroker) deliveryInfo(message *sqs.Message, taskProcessor TaskProcessor) tasks.DeliveryInfo {
+	var headers map[string]interface{}
+	for name, attribute := range message.MessageAttributes {
+		if attribute == nil {
+			continue
+		}
+		if headers == nil {
+			headers = make(map[string]interface{}, len(message.MessageAttributes))
+		}
+		if attribute.StringValue != nil {
+			headers[name] = *attribute.StringValue
+		} else {
+			headers[name] = attribute.BinaryValue
+		}
+	}
+
+	var deliveryCount int
+	if value := message.Attributes[sqs.MessageSystemAttributeNameApproximateReceiveCount]; value != nil {
+		deliveryCount, _ = strconv.Atoi(*value)
+	}
+
+	return tasks.DeliveryInfo{
+		Headers:       headers,
+		Redelivered:   deliveryCount > 1,
+		RoutingKey:    b.getQueue(taskProcessor),
+		DeliveryCount: deliveryCount,
+	}
+}
+
+// deleteOne is a method delete a delivery from AWS SQS queue
+func (b *AWSSQSBroker) deleteOne(delivery *sqs.ReceiveMessageOutput, qURL *string) error {
 	_, err := b.service.DeleteMessage(&sqs.DeleteMessageInput{
 		QueueUrl:      qURL,
 		ReceiptHandle: delivery.Messages[0].ReceiptHandle,
@@ -238,11 +285,38 @@ func (b *AWSSQSBroker) deleteOne(delivery *sqs.ReceiveMessageOutput) error {
 	return nil
 }
 
+// QueueLength returns the approximate number of messages available for
+// retrieval from the queue, as reported by SQS
+func (b *AWSSQSBroker) QueueLength(queue string) (int, error) {
+	if queue == "" {
+		queue = b.cnf.DefaultQueue
+	}
+
+	output, err := b.service.GetQueueAttributes(&sqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String(b.cnf.Broker + "/" + queue),
+		AttributeNames: []*string{aws.String(sqs.QueueAttributeNameApproximateNumberOfMessages)},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	value, ok := output.Attributes[sqs.QueueAttributeNameApproximateNumberOfMessages]
+	if !ok || value == nil {
+		return 0, errors.New("Queue attributes are missing the number of messages")
+	}
+	return strconv.Atoi(*value)
+}
+
 // defaultQueueURL is a method returns the default queue url
 func (b *AWSSQSBroker) defaultQueueURL() *string {
 	return aws.String(b.cnf.Broker + "/" + b.cnf.DefaultQueue)
 }
 
+// getQueueURL is a method returns url of the queue the task processor consumes from
+func (b *AWSSQSBroker) getQueueURL(taskProcessor TaskProcessor) *string {
+	return aws.String(b.cnf.Broker + "/" + b.getQueue(taskProcessor))
+}
+
 // receiveMessage is a method receives a message from specified queue url
 func (b *AWSSQSBroker) receiveMessage(qURL *string) (*sqs.ReceiveMessageOutput, error) {
 	var waitTimeSeconds int
@@ -256,6 +330,7 @@ func (b *AWSSQSBroker) receiveMessage(qURL *string) (*sqs.ReceiveMessageOutput,
 	input := &sqs.ReceiveMessageInput{
 		AttributeNames: []*string{
 			aws.String(sqs.MessageSystemAttributeNameSentTimestamp),
+			aws.String(sqs.MessageSystemAttributeNameApproximateReceiveCount),
 		},
 		MessageAttributeNames: []*string{
 			aws.String(sqs.QueueAttributeNameAll),
@@ -317,6 +392,10 @@ func (b *AWSSQSBroker) consumeDeliveries(deliveries <-chan *sqs.ReceiveMessageOu
 
 // continueReceivingMessages is a method returns a continue signal
 func (b *AWSSQSBroker) continueReceivingMessages(qURL *string, deliveries chan *sqs.ReceiveMessageOutput) (bool, error) {
+	if !b.waitUntilResumed(b.stopReceivingChan) {
+		return false, nil
+	}
+
 	select {
 	// A way to stop this goroutine from b.StopConsuming
 	case <-b.stopReceivingChan:
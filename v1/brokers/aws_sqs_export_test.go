@@ -7,6 +7,7 @@ import (
 	"sync"
 
 	"github.com/RichardKnop/machinery/v1/config"
+	"github.com/RichardKnop/machinery/v1/tasks"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/sqs"
@@ -37,6 +38,14 @@ func (f *FakeSQS) ReceiveMessage(*sqs.ReceiveMessageInput) (*sqs.ReceiveMessageO
 	return ReceiveMessageOutput, nil
 }
 
+func (f *FakeSQS) GetQueueAttributes(input *sqs.GetQueueAttributesInput) (*sqs.GetQueueAttributesOutput, error) {
+	return &sqs.GetQueueAttributesOutput{
+		Attributes: map[string]*string{
+			sqs.QueueAttributeNameApproximateNumberOfMessages: aws.String("42"),
+		},
+	}, nil
+}
+
 func (f *FakeSQS) DeleteMessage(*sqs.DeleteMessageInput) (*sqs.DeleteMessageOutput, error) {
 	return &sqs.DeleteMessageOutput{}, nil
 }
@@ -55,6 +64,11 @@ func (e *ErrorSQS) ReceiveMessage(*sqs.ReceiveMessageInput) (*sqs.ReceiveMessage
 	return nil, err
 }
 
+func (e *ErrorSQS) GetQueueAttributes(*sqs.GetQueueAttributesInput) (*sqs.GetQueueAttributesOutput, error) {
+	err := errors.New("this is an error")
+	return nil, err
+}
+
 func (e *ErrorSQS) DeleteMessage(*sqs.DeleteMessageInput) (*sqs.DeleteMessageOutput, error) {
 	err := errors.New("this is an error")
 	return nil, err
@@ -132,7 +146,7 @@ func (b *AWSSQSBroker) ConsumeOneForTest(delivery *sqs.ReceiveMessageOutput, tas
 }
 
 func (b *AWSSQSBroker) DeleteOneForTest(delivery *sqs.ReceiveMessageOutput) error {
-	return b.deleteOne(delivery)
+	return b.deleteOne(delivery, b.defaultQueueURL())
 }
 
 func (b *AWSSQSBroker) DefaultQueueURLForTest() *string {
@@ -167,6 +181,10 @@ func (b *Broker) StartConsumingForTest(consumerTag string, taskProcessor TaskPro
 	b.startConsuming(consumerTag, taskProcessor)
 }
 
+func (b *Broker) GetQueuesForTest(taskProcessor TaskProcessor) []string {
+	return b.getQueues(taskProcessor)
+}
+
 func (b *Broker) GetRetryFuncForTest() func(chan int) {
 	return b.retryFunc
 }
@@ -178,3 +196,23 @@ func (b *Broker) GetStopChanForTest() chan int {
 func (b *Broker) GetRetryStopChanForTest() chan int {
 	return b.retryStopChan
 }
+
+func (b *Broker) ConnectFailedForTest(err error) bool {
+	return b.connectFailed(err)
+}
+
+func (b *Broker) ConnectedForTest() {
+	b.connected()
+}
+
+func (b *Broker) DisconnectedForTest() {
+	b.disconnected()
+}
+
+func (b *Broker) MarshalForTest(signature *tasks.Signature) ([]byte, string, error) {
+	return b.marshal(signature)
+}
+
+func (b *Broker) UnmarshalForTest(msg []byte, contentType string, signature *tasks.Signature) error {
+	return b.unmarshal(msg, contentType, signature)
+}
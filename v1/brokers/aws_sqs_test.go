@@ -226,3 +226,29 @@ func TestPrivateFunc_deleteOne(t *testing.T) {
 	err = errAWSSQSBroker.DeleteOneForTest(receiveMessageOutput)
 	assert.NotNil(t, err)
 }
+
+func TestQueueLength(t *testing.T) {
+	length, err := testAWSSQSBroker.QueueLength("")
+	assert.Nil(t, err)
+	assert.Equal(t, 42, length)
+
+	_, err = errAWSSQSBroker.QueueLength("test_queue")
+	assert.NotNil(t, err)
+}
+
+func TestMultipleQueues(t *testing.T) {
+	server1, err := machinery.NewServer(cnf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wk := server1.NewCustomQueueWorker("sms_worker", 0, "test_queue")
+	assert.Equal(t, []string{"test_queue"}, testAWSSQSBroker.GetQueuesForTest(wk))
+
+	wk = server1.NewMultiQueueWorker("sms_worker", 0, []string{"test_queue", "other_queue"})
+	assert.Equal(t, []string{"test_queue", "other_queue"}, testAWSSQSBroker.GetQueuesForTest(wk))
+
+	retry, err := testAWSSQSBroker.StartConsuming("sms_worker", 0, wk)
+	assert.False(t, retry)
+	assert.NotNil(t, err)
+}
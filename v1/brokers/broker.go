@@ -2,6 +2,11 @@ package brokers
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/RichardKnop/machinery/v1/config"
 	"github.com/RichardKnop/machinery/v1/log"
@@ -12,16 +17,58 @@ import (
 // Broker represents a base broker structure
 type Broker struct {
 	cnf                 *config.Config
-	registeredTaskNames []string
+	registeredTaskNames *taskNames
 	retry               bool
 	retryFunc           func(chan int)
 	retryStopChan       chan int
 	stopChan            chan int
+	connectAttempts     int
+	pause               *pauseGate
+	connection          *connectionState
+}
+
+// connectionState tracks whether consumers are connected to the broker, it
+// is read by health checks from other goroutines
+type connectionState struct {
+	connected int32
+}
+
+// taskNames holds names of the registered tasks, they can be set while
+// consumers are checking them
+type taskNames struct {
+	mu    sync.RWMutex
+	names []string
+}
+
+// pauseGate tracks whether consuming is paused, consumers wait for the
+// current state to change instead of polling it
+type pauseGate struct {
+	mu         sync.Mutex
+	paused     bool
+	pauseChan  chan struct{} // closed once consuming gets paused
+	resumeChan chan struct{} // closed once consuming gets resumed
+}
+
+// changed returns a channel closed once the current state changes
+func (g *pauseGate) changed() chan struct{} {
+	if g.paused {
+		return g.resumeChan
+	}
+	if g.pauseChan == nil {
+		g.pauseChan = make(chan struct{})
+	}
+	return g.pauseChan
 }
 
 // New creates new Broker instance
 func New(cnf *config.Config) Broker {
-	return Broker{cnf: cnf, retry: true}
+	return Broker{
+		cnf:                 cnf,
+		retry:               true,
+		registeredTaskNames: new(taskNames),
+		pause:               new(pauseGate),
+		connection:          new(connectionState),
+	}
 }
 
 // GetConfig returns config
@@ -46,12 +93,29 @@ func (b *Broker) StopConsuming() {
 
 // SetRegisteredTaskNames sets registered task names
 func (b *Broker) SetRegisteredTaskNames(names []string) {
-	b.registeredTaskNames = names
+	if b.registeredTaskNames == nil {
+		b.registeredTaskNames = new(taskNames)
+	}
+	b.registeredTaskNames.mu.Lock()
+	defer b.registeredTaskNames.mu.Unlock()
+	b.registeredTaskNames.names = names
+}
+
+// acceptsTask returns true if consumed tasks of the name are passed to the
+// task processor, that is if the task is registered or if workers handle
+// unknown tasks themselves as configured by OnUnknownTask
+func (b *Broker) acceptsTask(name string) bool {
+	return (b.cnf != nil && b.cnf.OnUnknownTask != "") || b.IsTaskRegistered(name)
 }
 
 // IsTaskRegistered returns true if the task is registered with this broker
 func (b *Broker) IsTaskRegistered(name string) bool {
-	for _, registeredTaskName := range b.registeredTaskNames {
+	if b.registeredTaskNames == nil {
+		return false
+	}
+	b.registeredTaskNames.mu.RLock()
+	defer b.registeredTaskNames.mu.RUnlock()
+	for _, registeredTaskName := range b.registeredTaskNames.names {
 		if registeredTaskName == name {
 			return true
 		}
@@ -64,6 +128,75 @@ func (b *Broker) GetPendingTasks(queue string) ([]*tasks.Signature, error) {
 	return nil, errors.New("Not implemented")
 }
 
+// PauseConsuming stops consumers from taking new tasks off the queue, tasks
+// being processed are finished and the connection to the broker is kept
+func (b *Broker) PauseConsuming() {
+	if b.pause == nil {
+		return
+	}
+
+	b.pause.mu.Lock()
+	defer b.pause.mu.Unlock()
+
+	if !b.pause.paused {
+		close(b.pause.changed())
+		b.pause.paused = true
+		b.pause.resumeChan = make(chan struct{})
+	}
+}
+
+// ResumeConsuming lets consumers take tasks off the queue again
+func (b *Broker) ResumeConsuming() {
+	if b.pause == nil {
+		return
+	}
+
+	b.pause.mu.Lock()
+	defer b.pause.mu.Unlock()
+
+	if b.pause.paused {
+		close(b.pause.changed())
+		b.pause.paused = false
+		b.pause.pauseChan = make(chan struct{})
+	}
+}
+
+// IsConsumingPaused returns true if consuming is paused
+func (b *Broker) IsConsumingPaused() bool {
+	paused, _ := b.pauseState()
+	return paused
+}
+
+// pauseState returns whether consuming is paused and a channel which is
+// closed once it is paused or resumed
+func (b *Broker) pauseState() (bool, <-chan struct{}) {
+	if b.pause == nil {
+		return false, nil
+	}
+
+	b.pause.mu.Lock()
+	defer b.pause.mu.Unlock()
+
+	return b.pause.paused, b.pause.changed()
+}
+
+// waitUntilResumed blocks while consuming is paused, it returns false if
+// stopChan receives in the meantime
+func (b *Broker) waitUntilResumed(stopChan chan int) bool {
+	for {
+		paused, pauseChanged := b.pauseState()
+		if !paused {
+			return true
+		}
+
+		select {
+		case <-stopChan:
+			return false
+		case <-pauseChanged:
+		}
+	}
+}
+
 // startConsuming is a common part of StartConsuming method
 func (b *Broker) startConsuming(consumerTag string, taskProcessor TaskProcessor) {
 	if b.retryFunc == nil {
@@ -74,6 +207,129 @@ func (b *Broker) startConsuming(consumerTag string, taskProcessor TaskProcessor)
 	b.retryStopChan = make(chan int)
 }
 
+// serializer returns serializer for the configured content type
+func (b *Broker) serializer() (Serializer, error) {
+	if b.cnf == nil || b.cnf.ContentType == "" {
+		return JSONSerializer{}, nil
+	}
+	return GetSerializer(b.cnf.ContentType)
+}
+
+// marshal encodes the signature using the configured serializer, compresses
+// it with CompressPayloads and signs it with SigningKey, it returns content
+// type of the message as well
+func (b *Broker) marshal(signature *tasks.Signature) ([]byte, string, error) {
+	msg, contentType, err := b.encode(signature)
+	if err != nil {
+		return nil, "", err
+	}
+	if msg, err = b.compress(msg); err != nil {
+		return nil, "", fmt.Errorf("Compress signature error: %s", err)
+	}
+	return b.sign(msg), contentType, nil
+}
+
+// encode encodes the signature using the configured serializer without
+// compressing it, it returns content type of the message as well
+func (b *Broker) encode(signature *tasks.Signature) ([]byte, string, error) {
+	serializer, err := b.serializer()
+	if err != nil {
+		return nil, "", err
+	}
+	msg, err := serializer.Marshal(signature)
+	if err != nil {
+		return nil, "", fmt.Errorf("Marshal signature error: %s", err)
+	}
+	return msg, serializer.ContentType(), nil
+}
+
+// unmarshal decodes the signature using serializer for the content type of
+// the message, the configured one is used when the content type is unknown.
+// With SigningKey set the message is verified first, then compressed
+// messages are decompressed regardless of CompressPayloads. It is used by
+// brokers whose messages carry no content encoding, compressed messages are
+// recognised by the gzip magic bytes.
+func (b *Broker) unmarshal(msg []byte, contentType string, signature *tasks.Signature) error {
+	msg, err := b.verify(msg)
+	if err != nil {
+		return err
+	}
+	return b.decode(msg, contentType, contentEncoding(msg), signature)
+}
+
+// unmarshalEncoded decodes the signature like unmarshal, but only decompresses
+// the message if its content encoding, sent along with it, is gzip
+func (b *Broker) unmarshalEncoded(msg []byte, contentType, encoding string, signature *tasks.Signature) error {
+	msg, err := b.verify(msg)
+	if err != nil {
+		return err
+	}
+	return b.decode(msg, contentType, encoding, signature)
+}
+
+// decode decompresses the verified message if its content encoding is gzip
+// and decodes the signature using serializer for the content type
+func (b *Broker) decode(msg []byte, contentType, encoding string, signature *tasks.Signature) error {
+	var (
+		serializer Serializer
+		err        error
+	)
+	if contentType == "" {
+		serializer, err = b.serializer()
+	} else {
+		serializer, err = GetSerializer(contentType)
+	}
+	if err != nil {
+		return err
+	}
+	if encoding == gzipEncoding {
+		if msg, err = decompress(msg); err != nil {
+			return fmt.Errorf("Decompress signature error: %s", err)
+		}
+	}
+	return serializer.Unmarshal(msg, signature)
+}
+
+// connectFailed is called when connecting to the broker fails, it waits
+// before the next attempt and returns false when MaxReconnectAttempts
+// has been exhausted and consuming should not be retried
+func (b *Broker) connectFailed(err error) bool {
+	b.connectAttempts++
+	if b.cnf.MaxReconnectAttempts > 0 && b.connectAttempts > b.cnf.MaxReconnectAttempts {
+		log.ERROR.Printf("Giving up connecting to the broker after %d reconnect attempts: %s", b.cnf.MaxReconnectAttempts, err)
+		return false
+	}
+
+	if b.connectAttempts > 1 {
+		log.WARNING.Printf("Reconnect attempt %d to the broker failed: %s", b.connectAttempts-1, err)
+	}
+	b.retryFunc(b.retryStopChan)
+	return b.retry
+}
+
+// connected is called after connecting to the broker, it resets the reconnect
+// attempts so the backoff starts over when the connection drops later
+func (b *Broker) connected() {
+	b.connectAttempts = 0
+	b.retryFunc = retry.Closure()
+	if b.connection != nil {
+		atomic.StoreInt32(&b.connection.connected, 1)
+	}
+}
+
+// disconnected is called when consumers stop using the connection to the
+// broker, either because consuming stopped or because the connection dropped
+func (b *Broker) disconnected() {
+	if b.connection != nil {
+		atomic.StoreInt32(&b.connection.connected, 0)
+	}
+}
+
+// IsConnected returns true while consumers are connected to the broker
+func (b *Broker) IsConnected() bool {
+	return b.connection != nil && atomic.LoadInt32(&b.connection.connected) == 1
+}
+
 // stopConsuming is a common part of StopConsuming
 func (b *Broker) stopConsuming() {
 	// Do not retry from now on
@@ -88,9 +344,132 @@ func (b *Broker) stopConsuming() {
 	b.stopChan <- 1
 }
 
+// waitForProcessing waits for tasks being processed to finish, at most for
+// the ShutdownGracePeriod if one is set
+func (b *Broker) waitForProcessing(processingWG *sync.WaitGroup) {
+	if b.cnf == nil || b.cnf.ShutdownGracePeriod <= 0 {
+		processingWG.Wait()
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		processingWG.Wait()
+		close(done)
+	}()
+
+	gracePeriod := time.Duration(b.cnf.ShutdownGracePeriod) * time.Second
+	select {
+	case <-done:
+	case <-time.After(gracePeriod):
+		log.WARNING.Printf("Stopped waiting for tasks being processed after the shutdown grace period of %s", gracePeriod)
+	}
+}
+
 // GetRegisteredTaskNames returns registered tasks names
 func (b *Broker) GetRegisteredTaskNames() []string {
-	return b.registeredTaskNames
+	if b.registeredTaskNames == nil {
+		return nil
+	}
+	b.registeredTaskNames.mu.RLock()
+	defer b.registeredTaskNames.mu.RUnlock()
+	return b.registeredTaskNames.names
+}
+
+// processDelivery passes the task to the task processor along with details
+// about its message if the task processor accepts them
+func processDelivery(taskProcessor TaskProcessor, signature *tasks.Signature, delivery tasks.DeliveryInfo) error {
+	if deliveryProcessor, ok := taskProcessor.(DeliveryProcessor); ok {
+		return deliveryProcessor.ProcessDelivery(signature, delivery)
+	}
+	return taskProcessor.Process(signature)
+}
+
+// getQueue returns the queue the task processor consumes from, that is its
+// custom queue if set or the default queue otherwise
+func (b *Broker) getQueue(taskProcessor TaskProcessor) string {
+	if p, ok := taskProcessor.(CustomQueueProcessor); ok {
+		if customQueue := p.CustomQueue(); customQueue != "" {
+			return customQueue
+		}
+	}
+	return b.cnf.DefaultQueue
+}
+
+// getQueues returns the queues the task processor consumes from, these are
+// its custom queues if it consumes from several queues or the single queue
+// returned by getQueue otherwise
+func (b *Broker) getQueues(taskProcessor TaskProcessor) []string {
+	if p, ok := taskProcessor.(MultiQueueProcessor); ok {
+		if customQueues := p.CustomQueues(); len(customQueues) > 0 {
+			// Copy the queues so brokers can reorder them
+			return append([]string(nil), customQueues...)
+		}
+	}
+	return []string{b.getQueue(taskProcessor)}
+}
+
+// queueSettings returns the settings of the queues of the task processor
+// which are consumed with settings of their own
+func queueSettings(taskProcessor TaskProcessor) map[string]QueueSettings {
+	if p, ok := taskProcessor.(QueueSettingsProcessor); ok {
+		return p.CustomQueueSettings()
+	}
+	return nil
+}
+
+// connectionObserver returns the task processor as a ConnectionObserver, or
+// an observer ignoring connections if it is not one
+func connectionObserver(taskProcessor TaskProcessor) ConnectionObserver {
+	if o, ok := taskProcessor.(ConnectionObserver); ok {
+		return o
+	}
+	return noopConnectionObserver{}
+}
+
+// noopConnectionObserver is used for task processors not observing
+// connections
+type noopConnectionObserver struct{}
+
+func (noopConnectionObserver) ConnectionOpened()            {}
+func (noopConnectionObserver) ConnectionClosed(err error)   {}
+func (noopConnectionObserver) ReconnectAttempt(attempt int) {}
+func (noopConnectionObserver) ChannelError(err error)       {}
+
+// NewDeadLetter returns a copy of the task to be published to the dead letter
+// queue, its headers record why it failed and where it was routed originally
+func NewDeadLetter(signature *tasks.Signature, queue string, reason error) *tasks.Signature {
+	deadLetter := *signature
+	deadLetter.RoutingKey = queue
+	deadLetter.ETA = nil
+	deadLetter.Headers = make(tasks.Headers, len(signature.Headers)+3)
+	for k, v := range signature.Headers {
+		deadLetter.Headers[k] = v
+	}
+	deadLetter.Headers["dead_letter_error"] = reason.Error()
+	deadLetter.Headers["dead_letter_routing_key"] = signature.RoutingKey
+	deadLetter.Headers["dead_letter_failed_at"] = time.Now().UTC().Format(time.RFC3339)
+	return &deadLetter
+}
+
+// RedeliveriesHeader holds how many times a message has been redelivered
+// because it could not be processed, see MaxRedeliveries
+const RedeliveriesHeader = "redeliveries"
+
+// RestoreDeadLetter returns a copy of the task published to the dead letter
+// queue by NewDeadLetter as it was routed originally, without the headers
+// recording why it failed and with its redeliveries reset
+func RestoreDeadLetter(deadLetter *tasks.Signature) *tasks.Signature {
+	signature := *deadLetter
+	signature.RoutingKey, _ = deadLetter.Headers["dead_letter_routing_key"].(string)
+	signature.Headers = make(tasks.Headers, len(deadLetter.Headers))
+	for k, v := range deadLetter.Headers {
+		if k == RedeliveriesHeader || strings.HasPrefix(k, "dead_letter_") {
+			continue
+		}
+		signature.Headers[k] = v
+	}
+	return &signature
 }
 
 // AdjustRoutingKey makes sure the routing key is correct.
@@ -0,0 +1,13 @@
+package brokers
+
+import (
+	"sync"
+)
+
+func (b *Broker) WaitForProcessingForTest(processingWG *sync.WaitGroup) {
+	b.waitForProcessing(processingWG)
+}
+
+func ContentEncodingForTest(msg []byte) string {
+	return contentEncoding(msg)
+}
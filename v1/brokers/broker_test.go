@@ -1,7 +1,12 @@
 package brokers_test
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/RichardKnop/machinery/v1/brokers"
 	"github.com/RichardKnop/machinery/v1/config"
@@ -17,6 +22,31 @@ func TestIsTaskRegistered(t *testing.T) {
 	assert.False(t, broker.IsTaskRegistered("bogus"))
 }
 
+func TestMaxReconnectAttempts(t *testing.T) {
+	broker := brokers.New(&config.Config{MaxReconnectAttempts: 1})
+	broker.StartConsumingForTest("fooTag", nil)
+	err := errors.New("connection refused")
+
+	assert.True(t, broker.ConnectFailedForTest(err))
+	assert.False(t, broker.ConnectFailedForTest(err))
+
+	// Connecting successfully resets the attempts
+	broker.ConnectedForTest()
+	assert.True(t, broker.ConnectFailedForTest(err))
+}
+
+func TestIsConnected(t *testing.T) {
+	broker := brokers.New(new(config.Config))
+	broker.StartConsumingForTest("fooTag", nil)
+	assert.False(t, broker.IsConnected())
+
+	broker.ConnectedForTest()
+	assert.True(t, broker.IsConnected())
+
+	broker.DisconnectedForTest()
+	assert.False(t, broker.IsConnected())
+}
+
 func TestAdjustRoutingKey(t *testing.T) {
 	var (
 		s      *tasks.Signature
@@ -74,3 +104,147 @@ func TestGetRegisteredTaskNames(t *testing.T) {
 	broker.SetRegisteredTaskNames(fooTasks)
 	assert.Equal(t, fooTasks, broker.GetRegisteredTaskNames())
 }
+
+func TestCompressPayloads(t *testing.T) {
+	broker := brokers.New(&config.Config{CompressPayloads: true, CompressThreshold: 1000})
+	small := &tasks.Signature{Name: "small"}
+	large := &tasks.Signature{
+		Name: "large",
+		Args: []tasks.Arg{{Type: "string", Value: strings.Repeat("payload ", 1000)}},
+	}
+
+	// Small messages are not worth compressing
+	msg, _, err := broker.MarshalForTest(small)
+	assert.NoError(t, err)
+	assert.Equal(t, byte('{'), msg[0])
+
+	msg, contentType, err := broker.MarshalForTest(large)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x1f, 0x8b}, msg[:2])
+	assert.True(t, len(msg) < 1000, len(msg))
+
+	// Compressed messages are decompressed even if compression is disabled
+	for _, broker := range []brokers.Broker{broker, brokers.New(new(config.Config))} {
+		signature := new(tasks.Signature)
+		assert.NoError(t, broker.UnmarshalForTest(msg, contentType, signature))
+		assert.Equal(t, "large", signature.Name)
+		assert.Equal(t, large.Args[0].Value, signature.Args[0].Value)
+	}
+
+	uncompressed := brokers.New(new(config.Config))
+	msg, _, err = uncompressed.MarshalForTest(large)
+	assert.NoError(t, err)
+	assert.Equal(t, byte('{'), msg[0])
+}
+
+func TestSigningKey(t *testing.T) {
+	broker := brokers.New(&config.Config{SigningKey: []byte("secret")})
+	signature := &tasks.Signature{Name: "add", Args: []tasks.Arg{{Type: "int64", Value: 1}}}
+
+	msg, contentType, err := broker.MarshalForTest(signature)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(msg), "hmac-sha256:"), string(msg))
+
+	// Signed messages are accepted with the same key and, while rolling the
+	// key out, by brokers without one
+	for _, broker := range []brokers.Broker{broker, brokers.New(new(config.Config))} {
+		decoded := new(tasks.Signature)
+		assert.NoError(t, broker.UnmarshalForTest(msg, contentType, decoded))
+		assert.Equal(t, "add", decoded.Name)
+	}
+
+	// Tampered messages, messages signed with another key and unsigned
+	// messages are rejected
+	tampered := []byte(strings.Replace(string(msg), `"add"`, `"rm"`, 1))
+	assert.Equal(t, brokers.ErrInvalidMessageSignature, broker.UnmarshalForTest(tampered, contentType, new(tasks.Signature)))
+
+	other := brokers.New(&config.Config{SigningKey: []byte("other")})
+	assert.Equal(t, brokers.ErrInvalidMessageSignature, other.UnmarshalForTest(msg, contentType, new(tasks.Signature)))
+
+	plain := brokers.New(new(config.Config))
+	unsigned, _, err := plain.MarshalForTest(signature)
+	assert.NoError(t, err)
+	assert.Equal(t, brokers.ErrUnsignedMessage, broker.UnmarshalForTest(unsigned, contentType, new(tasks.Signature)))
+
+	// Compressed messages are signed after compressing them
+	compressing := brokers.New(&config.Config{SigningKey: []byte("secret"), CompressPayloads: true, CompressThreshold: 1})
+	msg, contentType, err = compressing.MarshalForTest(signature)
+	assert.NoError(t, err)
+	decoded := new(tasks.Signature)
+	assert.NoError(t, broker.UnmarshalForTest(msg, contentType, decoded))
+	assert.Equal(t, "add", decoded.Name)
+}
+
+// recordingT records errors of failed assertions
+type recordingT struct {
+	errors []string
+}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestShutdownGracePeriod(t *testing.T) {
+	t.Parallel()
+
+	var processingWG sync.WaitGroup
+	processingWG.Add(1)
+
+	// Waiting stops once the grace period elapsed
+	broker := brokers.New(&config.Config{ShutdownGracePeriod: 1})
+	start := time.Now()
+	broker.WaitForProcessingForTest(&processingWG)
+	assert.WithinDuration(t, start.Add(time.Second), time.Now(), 500*time.Millisecond)
+
+	// Without a grace period tasks are waited for however long they take
+	broker = brokers.New(new(config.Config))
+	done := make(chan struct{})
+	go func() {
+		broker.WaitForProcessingForTest(&processingWG)
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("Stopped waiting before tasks finished")
+	case <-time.After(100 * time.Millisecond):
+	}
+	processingWG.Done()
+	<-done
+}
+
+func TestMockBroker(t *testing.T) {
+	broker := brokers.NewMockBroker(&config.Config{DefaultQueue: "machinery_tasks"})
+	assert.NoError(t, broker.Publish(&tasks.Signature{
+		Name: "email.send",
+		Args: []tasks.Arg{{Type: "string", Value: "user@example.com"}},
+	}))
+	assert.NoError(t, broker.Publish(&tasks.Signature{Name: "report", RoutingKey: "reports"}))
+
+	// Passing assertions do not fail the test
+	mockT := new(recordingT)
+	assert.True(t, broker.AssertSent(mockT, "email.send"))
+	assert.True(t, broker.AssertSent(mockT, "email.send", "user@example.com"))
+	assert.True(t, broker.AssertNotSent(mockT, "sms.send"))
+	assert.Empty(t, mockT.errors)
+
+	// Failing ones do
+	assert.False(t, broker.AssertSent(mockT, "email.send", "other@example.com"))
+	assert.False(t, broker.AssertSent(mockT, "sms.send"))
+	assert.False(t, broker.AssertNotSent(mockT, "report"))
+	assert.Equal(t, []string{
+		"Task email.send was not sent with args [other@example.com], sent tasks: email.send[user@example.com], report[]",
+		"Task sms.send was not sent, sent tasks: email.send[user@example.com], report[]",
+		"Task report was sent 1 times",
+	}, mockT.errors)
+
+	// Sent tasks are routed to the default queue unless set otherwise
+	pending, err := broker.GetPendingTasks("")
+	assert.NoError(t, err)
+	if assert.Len(t, pending, 1) {
+		assert.Equal(t, "email.send", pending[0].Name)
+	}
+	assert.Len(t, broker.SentTasks("report"), 1)
+
+	broker.Reset()
+	assert.Empty(t, broker.Sent())
+}
@@ -0,0 +1,73 @@
+package brokers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+)
+
+const (
+	// gzipEncoding is the content encoding of compressed messages
+	gzipEncoding = "gzip"
+	// defaultCompressThreshold is how many bytes messages must have to be
+	// compressed with CompressPayloads unless CompressThreshold is set
+	defaultCompressThreshold = 1024
+)
+
+// gzipMagic starts every gzip stream, serialized signatures never start
+// with it so compressed messages are recognised without extra headers by
+// brokers which cannot send the content encoding along
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// compress gzips the message with CompressPayloads if it is above the
+// threshold, smaller messages are returned as they are
+func (b *Broker) compress(msg []byte) ([]byte, error) {
+	if b.cnf == nil || !b.cnf.CompressPayloads {
+		return msg, nil
+	}
+
+	threshold := b.cnf.CompressThreshold
+	if threshold <= 0 {
+		threshold = defaultCompressThreshold
+	}
+	if len(msg) < threshold {
+		return msg, nil
+	}
+
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(msg); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// isCompressed returns true if the message has been compressed
+func isCompressed(msg []byte) bool {
+	return bytes.HasPrefix(msg, gzipMagic)
+}
+
+// decompress gunzips the message
+func decompress(msg []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(msg))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
+
+// contentEncoding returns the content encoding of the message, empty unless
+// it has been compressed, the HMAC of signed messages is skipped
+func contentEncoding(msg []byte) string {
+	if isSigned(msg) {
+		msg = msg[len(signedPrefix)+signatureLength:]
+	}
+	if isCompressed(msg) {
+		return gzipEncoding
+	}
+	return ""
+}
@@ -1,8 +1,6 @@
 package brokers
 
 import (
-	"bytes"
-	"encoding/json"
 	"errors"
 	"fmt"
 
@@ -17,7 +15,7 @@ type EagerBroker struct {
 
 // NewEagerBroker creates new EagerBroker instance
 func NewEagerBroker() Interface {
-	return new(EagerBroker)
+	return &EagerBroker{Broker: New(nil)}
 }
 
 // EagerMode interface with methods specific for this broker
@@ -41,24 +39,28 @@ func (eagerBroker *EagerBroker) Publish(task *tasks.Signature) error {
 		return errors.New("worker is not assigned in eager-mode")
 	}
 
-	// faking the behavior to marshal input into json
+	// faking the behavior to marshal input
 	// and unmarshal it back
-	message, err := json.Marshal(task)
+	message, contentType, err := eagerBroker.marshal(task)
 	if err != nil {
-		return fmt.Errorf("JSON marshal error: %s", err)
+		return err
 	}
 
 	signature := new(tasks.Signature)
-	decoder := json.NewDecoder(bytes.NewReader(message))
-	decoder.UseNumber()
-	if err := decoder.Decode(signature); err != nil {
-		return fmt.Errorf("JSON unmarshal error: %s", err)
+	if err := eagerBroker.unmarshal(message, contentType, signature); err != nil {
+		return fmt.Errorf("Unmarshal signature error: %s", err)
 	}
 
 	// blocking call to the task directly
 	return eagerBroker.worker.Process(signature)
 }
 
+// Broadcast processes the task like Publish does, there is just the one
+// worker in eager mode
+func (eagerBroker *EagerBroker) Broadcast(task *tasks.Signature) error {
+	return eagerBroker.Publish(task)
+}
+
 // GetPendingTasks returns a slice of task.Signatures waiting in the queue
 func (eagerBroker *EagerBroker) GetPendingTasks(queue string) ([]*tasks.Signature, error) {
 	return []*tasks.Signature{}, errors.New("Not implemented")
@@ -16,6 +16,123 @@ type Interface interface {
 	GetPendingTasks(queue string) ([]*tasks.Signature, error)
 }
 
+// BatchPublisher is implemented by brokers which can publish multiple tasks
+// with fewer round-trips than publishing them one by one
+type BatchPublisher interface {
+	// PublishBatch publishes the tasks and returns a slice holding an error
+	// for each task which could not be published and nil for the others
+	PublishBatch(tasks []*tasks.Signature) []error
+}
+
+// QueueInspector is implemented by brokers which can report how many tasks
+// are waiting in a queue without consuming them
+type QueueInspector interface {
+	QueueLength(queue string) (int, error)
+}
+
+// QueuePurger is implemented by brokers which can remove the tasks waiting in
+// a queue. Both are destructive administrative operations, removed tasks are
+// gone for good.
+type QueuePurger interface {
+	// PurgeQueue removes all tasks from the queue and returns how many were
+	// removed
+	PurgeQueue(queue string) (int, error)
+	// DrainQueue removes the tasks currently waiting in the queue one by one,
+	// handing each of them to fn, and returns how many were handed to fn
+	DrainQueue(queue string, fn func(signature *tasks.Signature)) (int, error)
+}
+
+// QueueTaker is implemented by brokers which can take tasks from a queue
+// without losing them if handling them fails
+type QueueTaker interface {
+	// TakeTasks removes up to limit tasks currently waiting in the queue one
+	// by one, each of them only once fn handled it without an error. It stops
+	// at the first error, leaving that task in the queue, and returns how
+	// many tasks were taken. Tasks which cannot be decoded are left in the
+	// queue and skipped.
+	TakeTasks(queue string, limit int, fn func(signature *tasks.Signature) error) (int, error)
+}
+
+// QueuePeeker is implemented by brokers which can list the tasks waiting in a
+// queue without taking them off it for good
+type QueuePeeker interface {
+	// PeekTasks returns up to limit tasks currently waiting in the queue,
+	// from its head. Tasks which cannot be decoded are skipped.
+	PeekTasks(queue string, limit int) ([]*tasks.Signature, error)
+}
+
+// Broadcaster is implemented by brokers which can deliver a task to every
+// worker consuming from them instead of a single one
+type Broadcaster interface {
+	Broadcast(signature *tasks.Signature) error
+}
+
+// Pauser is implemented by brokers whose consumers can be paused without
+// disconnecting from the broker
+type Pauser interface {
+	PauseConsuming()
+	ResumeConsuming()
+	IsConsumingPaused() bool
+}
+
+// ConnectionChecker is implemented by brokers which can report whether
+// consumers are connected to the broker
+type ConnectionChecker interface {
+	IsConnected() bool
+}
+
+// CustomQueueProcessor is implemented by task processors which consume from
+// a custom queue instead of the default queue
+type CustomQueueProcessor interface {
+	CustomQueue() string
+}
+
+// MultiQueueProcessor is implemented by task processors which consume from
+// several queues at once instead of a single custom queue
+type MultiQueueProcessor interface {
+	CustomQueues() []string
+}
+
+// QueueSettings overrides how one of the queues of a task processor is
+// consumed, currently only supported by AMQP
+type QueueSettings struct {
+	// Concurrency when greater than zero gives the queue a worker pool of its
+	// own instead of sharing the concurrency of the task processor
+	Concurrency int
+	// PrefetchCount when greater than zero overrides how many messages of the
+	// queue are prefetched, it defaults to Concurrency if that is set
+	PrefetchCount int
+}
+
+// QueueSettingsProcessor is implemented by task processors which consume some
+// of their queues with settings of their own
+type QueueSettingsProcessor interface {
+	CustomQueueSettings() map[string]QueueSettings
+}
+
+// ConnectionObserver is implemented by task processors which are notified
+// about connections their consumers open to the broker, currently only by
+// AMQP
+type ConnectionObserver interface {
+	// ConnectionOpened is called once a connection is opened
+	ConnectionOpened()
+	// ConnectionClosed is called once consuming over a connection stopped,
+	// err is the reason or nil if consuming was stopped
+	ConnectionClosed(err error)
+	// ReconnectAttempt is called before each attempt to open a connection
+	// after the previous one failed or was lost, attempt counts from 1
+	ReconnectAttempt(attempt int)
+	// ChannelError is called when the broker closes the channel of an open
+	// connection because of an error
+	ChannelError(err error)
+}
+
+// DeliveryProcessor is implemented by task processors which accept details
+// about the message a task was delivered in along with the task
+type DeliveryProcessor interface {
+	ProcessDelivery(signature *tasks.Signature, delivery tasks.DeliveryInfo) error
+}
+
 // TaskProcessor - can process a delivered task
 // This will probably always be a worker instance
 type TaskProcessor interface {
@@ -0,0 +1,145 @@
+package brokers
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/RichardKnop/machinery/v1/config"
+	"github.com/RichardKnop/machinery/v1/tasks"
+)
+
+// MockBroker records published tasks instead of sending them anywhere, so
+// tests of code sending tasks can assert what was sent. Set it on the server
+// with SetBroker.
+type MockBroker struct {
+	Broker
+	mu   sync.Mutex
+	sent []*tasks.Signature
+}
+
+// TestingT is the part of *testing.T used by MockBroker assertions
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+}
+
+// NewMockBroker creates new MockBroker instance
+func NewMockBroker(cnf *config.Config) *MockBroker {
+	return &MockBroker{Broker: New(cnf)}
+}
+
+// Publish records a copy of the task
+func (b *MockBroker) Publish(signature *tasks.Signature) error {
+	AdjustRoutingKey(b, signature)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	sent := *signature
+	b.sent = append(b.sent, &sent)
+	return nil
+}
+
+// GetPendingTasks returns tasks published to the queue
+func (b *MockBroker) GetPendingTasks(queue string) ([]*tasks.Signature, error) {
+	if queue == "" {
+		queue = b.GetConfig().DefaultQueue
+	}
+
+	var pending []*tasks.Signature
+	for _, signature := range b.Sent() {
+		if signature.RoutingKey == queue {
+			pending = append(pending, signature)
+		}
+	}
+	return pending, nil
+}
+
+// Sent returns all tasks published so far in the order they were published
+func (b *MockBroker) Sent() []*tasks.Signature {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]*tasks.Signature(nil), b.sent...)
+}
+
+// SentTasks returns tasks of the name published so far
+func (b *MockBroker) SentTasks(name string) []*tasks.Signature {
+	var sent []*tasks.Signature
+	for _, signature := range b.Sent() {
+		if signature.Name == name {
+			sent = append(sent, signature)
+		}
+	}
+	return sent
+}
+
+// Reset forgets the tasks published so far
+func (b *MockBroker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sent = nil
+}
+
+// AssertSent fails the test unless a task of the name has been published,
+// with exactly the arg values if any are given
+func (b *MockBroker) AssertSent(t TestingT, name string, args ...interface{}) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+
+	for _, signature := range b.SentTasks(name) {
+		if len(args) == 0 || argValuesEqual(signature.Args, args) {
+			return true
+		}
+	}
+
+	if len(args) == 0 {
+		t.Errorf("Task %s was not sent, sent tasks: %s", name, b.describeSent())
+	} else {
+		t.Errorf("Task %s was not sent with args %v, sent tasks: %s", name, args, b.describeSent())
+	}
+	return false
+}
+
+// AssertNotSent fails the test if a task of the name has been published
+func (b *MockBroker) AssertNotSent(t TestingT, name string) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+
+	if sent := b.SentTasks(name); len(sent) > 0 {
+		t.Errorf("Task %s was sent %d times", name, len(sent))
+		return false
+	}
+	return true
+}
+
+// describeSent returns names and arg values of the tasks published so far
+func (b *MockBroker) describeSent() string {
+	sent := b.Sent()
+	if len(sent) == 0 {
+		return "none"
+	}
+
+	descriptions := make([]string, len(sent))
+	for i, signature := range sent {
+		values := make([]interface{}, len(signature.Args))
+		for j, arg := range signature.Args {
+			values[j] = arg.Value
+		}
+		descriptions[i] = fmt.Sprintf("%s%v", signature.Name, values)
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+func argValuesEqual(args []tasks.Arg, values []interface{}) bool {
+	if len(args) != len(values) {
+		return false
+	}
+	for i, arg := range args {
+		if !reflect.DeepEqual(arg.Value, values[i]) {
+			return false
+		}
+	}
+	return true
+}
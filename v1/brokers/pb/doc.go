@@ -0,0 +1,5 @@
+// Package pb holds the protobuf messages task signatures are encoded as by
+// brokers.ProtobufSerializer, generated from signature.proto
+package pb
+
+//go:generate protoc --go_out=. signature.proto
@@ -0,0 +1,647 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: signature.proto
+
+package pb
+
+import (
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	timestamp "github.com/golang/protobuf/ptypes/timestamp"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+// Signature is a task signature as encoded by brokers.ProtobufSerializer,
+// its fields mirror the fields of tasks.Signature
+type Signature struct {
+	Uuid                  string               `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	Name                  string               `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	RoutingKey            string               `protobuf:"bytes,3,opt,name=routing_key,json=routingKey,proto3" json:"routing_key,omitempty"`
+	Eta                   *timestamp.Timestamp `protobuf:"bytes,4,opt,name=eta,proto3" json:"eta,omitempty"`
+	ExpiresAt             *timestamp.Timestamp `protobuf:"bytes,5,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	GroupUuid             string               `protobuf:"bytes,6,opt,name=group_uuid,json=groupUuid,proto3" json:"group_uuid,omitempty"`
+	GroupTaskCount        int64                `protobuf:"varint,7,opt,name=group_task_count,json=groupTaskCount,proto3" json:"group_task_count,omitempty"`
+	Args                  []*Arg               `protobuf:"bytes,8,rep,name=args,proto3" json:"args,omitempty"`
+	Headers               map[string]*Value    `protobuf:"bytes,9,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Immutable             bool                 `protobuf:"varint,10,opt,name=immutable,proto3" json:"immutable,omitempty"`
+	RetryCount            int64                `protobuf:"varint,11,opt,name=retry_count,json=retryCount,proto3" json:"retry_count,omitempty"`
+	RetryTimeout          int64                `protobuf:"varint,12,opt,name=retry_timeout,json=retryTimeout,proto3" json:"retry_timeout,omitempty"`
+	TimeoutSeconds        int64                `protobuf:"varint,13,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"`
+	Priority              uint32               `protobuf:"varint,14,opt,name=priority,proto3" json:"priority,omitempty"`
+	OnSuccess             []*Signature         `protobuf:"bytes,15,rep,name=on_success,json=onSuccess,proto3" json:"on_success,omitempty"`
+	OnError               []*Signature         `protobuf:"bytes,16,rep,name=on_error,json=onError,proto3" json:"on_error,omitempty"`
+	ChordCallback         *Signature           `protobuf:"bytes,17,opt,name=chord_callback,json=chordCallback,proto3" json:"chord_callback,omitempty"`
+	ChordOnPartialFailure string               `protobuf:"bytes,18,opt,name=chord_on_partial_failure,json=chordOnPartialFailure,proto3" json:"chord_on_partial_failure,omitempty"`
+	ChordErrorCallback    *Signature           `protobuf:"bytes,19,opt,name=chord_error_callback,json=chordErrorCallback,proto3" json:"chord_error_callback,omitempty"`
+	WebhookUrl            string               `protobuf:"bytes,20,opt,name=webhook_url,json=webhookUrl,proto3" json:"webhook_url,omitempty"`
+	WorkflowUuid          string               `protobuf:"bytes,21,opt,name=workflow_uuid,json=workflowUuid,proto3" json:"workflow_uuid,omitempty"`
+	EstimatedMemoryBytes  int64                `protobuf:"varint,22,opt,name=estimated_memory_bytes,json=estimatedMemoryBytes,proto3" json:"estimated_memory_bytes,omitempty"`
+	RetryBackoff          string               `protobuf:"bytes,23,opt,name=retry_backoff,json=retryBackoff,proto3" json:"retry_backoff,omitempty"`
+	XXX_NoUnkeyedLiteral  struct{}             `json:"-"`
+	XXX_unrecognized      []byte               `json:"-"`
+	XXX_sizecache         int32                `json:"-"`
+}
+
+func (m *Signature) Reset()         { *m = Signature{} }
+func (m *Signature) String() string { return proto.CompactTextString(m) }
+func (*Signature) ProtoMessage()    {}
+func (*Signature) Descriptor() ([]byte, []int) {
+	return fileDescriptor_76962cacebaec211, []int{0}
+}
+
+func (m *Signature) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Signature.Unmarshal(m, b)
+}
+func (m *Signature) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Signature.Marshal(b, m, deterministic)
+}
+func (m *Signature) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Signature.Merge(m, src)
+}
+func (m *Signature) XXX_Size() int {
+	return xxx_messageInfo_Signature.Size(m)
+}
+func (m *Signature) XXX_DiscardUnknown() {
+	xxx_messageInfo_Signature.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Signature proto.InternalMessageInfo
+
+func (m *Signature) GetUuid() string {
+	if m != nil {
+		return m.Uuid
+	}
+	return ""
+}
+
+func (m *Signature) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Signature) GetRoutingKey() string {
+	if m != nil {
+		return m.RoutingKey
+	}
+	return ""
+}
+
+func (m *Signature) GetEta() *timestamp.Timestamp {
+	if m != nil {
+		return m.Eta
+	}
+	return nil
+}
+
+func (m *Signature) GetExpiresAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return nil
+}
+
+func (m *Signature) GetGroupUuid() string {
+	if m != nil {
+		return m.GroupUuid
+	}
+	return ""
+}
+
+func (m *Signature) GetGroupTaskCount() int64 {
+	if m != nil {
+		return m.GroupTaskCount
+	}
+	return 0
+}
+
+func (m *Signature) GetArgs() []*Arg {
+	if m != nil {
+		return m.Args
+	}
+	return nil
+}
+
+func (m *Signature) GetHeaders() map[string]*Value {
+	if m != nil {
+		return m.Headers
+	}
+	return nil
+}
+
+func (m *Signature) GetImmutable() bool {
+	if m != nil {
+		return m.Immutable
+	}
+	return false
+}
+
+func (m *Signature) GetRetryCount() int64 {
+	if m != nil {
+		return m.RetryCount
+	}
+	return 0
+}
+
+func (m *Signature) GetRetryTimeout() int64 {
+	if m != nil {
+		return m.RetryTimeout
+	}
+	return 0
+}
+
+func (m *Signature) GetTimeoutSeconds() int64 {
+	if m != nil {
+		return m.TimeoutSeconds
+	}
+	return 0
+}
+
+func (m *Signature) GetPriority() uint32 {
+	if m != nil {
+		return m.Priority
+	}
+	return 0
+}
+
+func (m *Signature) GetOnSuccess() []*Signature {
+	if m != nil {
+		return m.OnSuccess
+	}
+	return nil
+}
+
+func (m *Signature) GetOnError() []*Signature {
+	if m != nil {
+		return m.OnError
+	}
+	return nil
+}
+
+func (m *Signature) GetChordCallback() *Signature {
+	if m != nil {
+		return m.ChordCallback
+	}
+	return nil
+}
+
+func (m *Signature) GetChordOnPartialFailure() string {
+	if m != nil {
+		return m.ChordOnPartialFailure
+	}
+	return ""
+}
+
+func (m *Signature) GetChordErrorCallback() *Signature {
+	if m != nil {
+		return m.ChordErrorCallback
+	}
+	return nil
+}
+
+func (m *Signature) GetWebhookUrl() string {
+	if m != nil {
+		return m.WebhookUrl
+	}
+	return ""
+}
+
+func (m *Signature) GetWorkflowUuid() string {
+	if m != nil {
+		return m.WorkflowUuid
+	}
+	return ""
+}
+
+func (m *Signature) GetEstimatedMemoryBytes() int64 {
+	if m != nil {
+		return m.EstimatedMemoryBytes
+	}
+	return 0
+}
+
+func (m *Signature) GetRetryBackoff() string {
+	if m != nil {
+		return m.RetryBackoff
+	}
+	return ""
+}
+
+// Arg is an argument of a task
+type Arg struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Type                 string   `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Value                *Value   `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Arg) Reset()         { *m = Arg{} }
+func (m *Arg) String() string { return proto.CompactTextString(m) }
+func (*Arg) ProtoMessage()    {}
+func (*Arg) Descriptor() ([]byte, []int) {
+	return fileDescriptor_76962cacebaec211, []int{1}
+}
+
+func (m *Arg) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Arg.Unmarshal(m, b)
+}
+func (m *Arg) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Arg.Marshal(b, m, deterministic)
+}
+func (m *Arg) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Arg.Merge(m, src)
+}
+func (m *Arg) XXX_Size() int {
+	return xxx_messageInfo_Arg.Size(m)
+}
+func (m *Arg) XXX_DiscardUnknown() {
+	xxx_messageInfo_Arg.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Arg proto.InternalMessageInfo
+
+func (m *Arg) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Arg) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *Arg) GetValue() *Value {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+// Value is an arg or header value, integers keep their full precision and
+// byte slices are not encoded as text
+type Value struct {
+	// Types that are valid to be assigned to Kind:
+	//	*Value_NullValue
+	//	*Value_BoolValue
+	//	*Value_IntValue
+	//	*Value_UintValue
+	//	*Value_DoubleValue
+	//	*Value_StringValue
+	//	*Value_BytesValue
+	//	*Value_NumberValue
+	//	*Value_ListValue
+	//	*Value_MapValue
+	Kind                 isValue_Kind `protobuf_oneof:"kind"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *Value) Reset()         { *m = Value{} }
+func (m *Value) String() string { return proto.CompactTextString(m) }
+func (*Value) ProtoMessage()    {}
+func (*Value) Descriptor() ([]byte, []int) {
+	return fileDescriptor_76962cacebaec211, []int{2}
+}
+
+func (m *Value) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Value.Unmarshal(m, b)
+}
+func (m *Value) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Value.Marshal(b, m, deterministic)
+}
+func (m *Value) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Value.Merge(m, src)
+}
+func (m *Value) XXX_Size() int {
+	return xxx_messageInfo_Value.Size(m)
+}
+func (m *Value) XXX_DiscardUnknown() {
+	xxx_messageInfo_Value.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Value proto.InternalMessageInfo
+
+type isValue_Kind interface {
+	isValue_Kind()
+}
+
+type Value_NullValue struct {
+	NullValue bool `protobuf:"varint,1,opt,name=null_value,json=nullValue,proto3,oneof"`
+}
+
+type Value_BoolValue struct {
+	BoolValue bool `protobuf:"varint,2,opt,name=bool_value,json=boolValue,proto3,oneof"`
+}
+
+type Value_IntValue struct {
+	IntValue int64 `protobuf:"zigzag64,3,opt,name=int_value,json=intValue,proto3,oneof"`
+}
+
+type Value_UintValue struct {
+	UintValue uint64 `protobuf:"varint,4,opt,name=uint_value,json=uintValue,proto3,oneof"`
+}
+
+type Value_DoubleValue struct {
+	DoubleValue float64 `protobuf:"fixed64,5,opt,name=double_value,json=doubleValue,proto3,oneof"`
+}
+
+type Value_StringValue struct {
+	StringValue string `protobuf:"bytes,6,opt,name=string_value,json=stringValue,proto3,oneof"`
+}
+
+type Value_BytesValue struct {
+	BytesValue []byte `protobuf:"bytes,7,opt,name=bytes_value,json=bytesValue,proto3,oneof"`
+}
+
+type Value_NumberValue struct {
+	NumberValue string `protobuf:"bytes,8,opt,name=number_value,json=numberValue,proto3,oneof"`
+}
+
+type Value_ListValue struct {
+	ListValue *ListValue `protobuf:"bytes,9,opt,name=list_value,json=listValue,proto3,oneof"`
+}
+
+type Value_MapValue struct {
+	MapValue *MapValue `protobuf:"bytes,10,opt,name=map_value,json=mapValue,proto3,oneof"`
+}
+
+func (*Value_NullValue) isValue_Kind() {}
+
+func (*Value_BoolValue) isValue_Kind() {}
+
+func (*Value_IntValue) isValue_Kind() {}
+
+func (*Value_UintValue) isValue_Kind() {}
+
+func (*Value_DoubleValue) isValue_Kind() {}
+
+func (*Value_StringValue) isValue_Kind() {}
+
+func (*Value_BytesValue) isValue_Kind() {}
+
+func (*Value_NumberValue) isValue_Kind() {}
+
+func (*Value_ListValue) isValue_Kind() {}
+
+func (*Value_MapValue) isValue_Kind() {}
+
+func (m *Value) GetKind() isValue_Kind {
+	if m != nil {
+		return m.Kind
+	}
+	return nil
+}
+
+func (m *Value) GetNullValue() bool {
+	if x, ok := m.GetKind().(*Value_NullValue); ok {
+		return x.NullValue
+	}
+	return false
+}
+
+func (m *Value) GetBoolValue() bool {
+	if x, ok := m.GetKind().(*Value_BoolValue); ok {
+		return x.BoolValue
+	}
+	return false
+}
+
+func (m *Value) GetIntValue() int64 {
+	if x, ok := m.GetKind().(*Value_IntValue); ok {
+		return x.IntValue
+	}
+	return 0
+}
+
+func (m *Value) GetUintValue() uint64 {
+	if x, ok := m.GetKind().(*Value_UintValue); ok {
+		return x.UintValue
+	}
+	return 0
+}
+
+func (m *Value) GetDoubleValue() float64 {
+	if x, ok := m.GetKind().(*Value_DoubleValue); ok {
+		return x.DoubleValue
+	}
+	return 0
+}
+
+func (m *Value) GetStringValue() string {
+	if x, ok := m.GetKind().(*Value_StringValue); ok {
+		return x.StringValue
+	}
+	return ""
+}
+
+func (m *Value) GetBytesValue() []byte {
+	if x, ok := m.GetKind().(*Value_BytesValue); ok {
+		return x.BytesValue
+	}
+	return nil
+}
+
+func (m *Value) GetNumberValue() string {
+	if x, ok := m.GetKind().(*Value_NumberValue); ok {
+		return x.NumberValue
+	}
+	return ""
+}
+
+func (m *Value) GetListValue() *ListValue {
+	if x, ok := m.GetKind().(*Value_ListValue); ok {
+		return x.ListValue
+	}
+	return nil
+}
+
+func (m *Value) GetMapValue() *MapValue {
+	if x, ok := m.GetKind().(*Value_MapValue); ok {
+		return x.MapValue
+	}
+	return nil
+}
+
+// XXX_OneofWrappers is for the internal use of the proto package.
+func (*Value) XXX_OneofWrappers() []interface{} {
+	return []interface{}{
+		(*Value_NullValue)(nil),
+		(*Value_BoolValue)(nil),
+		(*Value_IntValue)(nil),
+		(*Value_UintValue)(nil),
+		(*Value_DoubleValue)(nil),
+		(*Value_StringValue)(nil),
+		(*Value_BytesValue)(nil),
+		(*Value_NumberValue)(nil),
+		(*Value_ListValue)(nil),
+		(*Value_MapValue)(nil),
+	}
+}
+
+// ListValue is a slice of values
+type ListValue struct {
+	Values               []*Value `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListValue) Reset()         { *m = ListValue{} }
+func (m *ListValue) String() string { return proto.CompactTextString(m) }
+func (*ListValue) ProtoMessage()    {}
+func (*ListValue) Descriptor() ([]byte, []int) {
+	return fileDescriptor_76962cacebaec211, []int{3}
+}
+
+func (m *ListValue) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListValue.Unmarshal(m, b)
+}
+func (m *ListValue) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListValue.Marshal(b, m, deterministic)
+}
+func (m *ListValue) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListValue.Merge(m, src)
+}
+func (m *ListValue) XXX_Size() int {
+	return xxx_messageInfo_ListValue.Size(m)
+}
+func (m *ListValue) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListValue.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListValue proto.InternalMessageInfo
+
+func (m *ListValue) GetValues() []*Value {
+	if m != nil {
+		return m.Values
+	}
+	return nil
+}
+
+// MapValue is a map of values by string keys
+type MapValue struct {
+	Values               map[string]*Value `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *MapValue) Reset()         { *m = MapValue{} }
+func (m *MapValue) String() string { return proto.CompactTextString(m) }
+func (*MapValue) ProtoMessage()    {}
+func (*MapValue) Descriptor() ([]byte, []int) {
+	return fileDescriptor_76962cacebaec211, []int{4}
+}
+
+func (m *MapValue) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MapValue.Unmarshal(m, b)
+}
+func (m *MapValue) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MapValue.Marshal(b, m, deterministic)
+}
+func (m *MapValue) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MapValue.Merge(m, src)
+}
+func (m *MapValue) XXX_Size() int {
+	return xxx_messageInfo_MapValue.Size(m)
+}
+func (m *MapValue) XXX_DiscardUnknown() {
+	xxx_messageInfo_MapValue.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MapValue proto.InternalMessageInfo
+
+func (m *MapValue) GetValues() map[string]*Value {
+	if m != nil {
+		return m.Values
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*Signature)(nil), "machinery.Signature")
+	proto.RegisterMapType((map[string]*Value)(nil), "machinery.Signature.HeadersEntry")
+	proto.RegisterType((*Arg)(nil), "machinery.Arg")
+	proto.RegisterType((*Value)(nil), "machinery.Value")
+	proto.RegisterType((*ListValue)(nil), "machinery.ListValue")
+	proto.RegisterType((*MapValue)(nil), "machinery.MapValue")
+	proto.RegisterMapType((map[string]*Value)(nil), "machinery.MapValue.ValuesEntry")
+}
+
+func init() { proto.RegisterFile("signature.proto", fileDescriptor_76962cacebaec211) }
+
+var fileDescriptor_76962cacebaec211 = []byte{
+	// 849 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x55, 0xdd, 0x6e, 0xdb, 0x36,
+	0x14, 0x8e, 0x22, 0x27, 0x91, 0x8e, 0x9c, 0x9f, 0xb1, 0x69, 0x47, 0x18, 0x2b, 0xec, 0xba, 0xc0,
+	0xe6, 0x8b, 0xc1, 0x01, 0xd2, 0x15, 0xdd, 0xd6, 0xab, 0xa4, 0x68, 0x61, 0xa0, 0x0d, 0x36, 0x28,
+	0xc9, 0x2e, 0x76, 0x23, 0x50, 0x36, 0xed, 0x10, 0x96, 0x48, 0x81, 0x3f, 0xcb, 0xf4, 0x12, 0xc3,
+	0xde, 0x61, 0x2f, 0x3a, 0x90, 0x94, 0x64, 0x63, 0xcb, 0xb2, 0x8b, 0xde, 0xd1, 0xdf, 0xcf, 0xe1,
+	0x47, 0xf1, 0xf0, 0x18, 0x8e, 0x15, 0x5b, 0x71, 0xa2, 0x8d, 0xa4, 0xd3, 0x4a, 0x0a, 0x2d, 0x50,
+	0x5c, 0x92, 0xf9, 0x1d, 0xe3, 0x54, 0xd6, 0x83, 0xe1, 0x4a, 0x88, 0x55, 0x41, 0xcf, 0x1c, 0x91,
+	0x9b, 0xe5, 0x99, 0x66, 0x25, 0x55, 0x9a, 0x94, 0x95, 0xd7, 0x8e, 0xff, 0x8a, 0x20, 0xbe, 0x6e,
+	0xfd, 0x08, 0x41, 0xcf, 0x18, 0xb6, 0xc0, 0xc1, 0x28, 0x98, 0xc4, 0xa9, 0x5b, 0x5b, 0x8c, 0x93,
+	0x92, 0xe2, 0x5d, 0x8f, 0xd9, 0x35, 0x1a, 0x42, 0x22, 0x85, 0xd1, 0x8c, 0xaf, 0xb2, 0x35, 0xad,
+	0x71, 0xe8, 0x28, 0x68, 0xa0, 0x8f, 0xb4, 0x46, 0xdf, 0x42, 0x48, 0x35, 0xc1, 0xbd, 0x51, 0x30,
+	0x49, 0xce, 0x07, 0x53, 0x9f, 0x62, 0xda, 0xa6, 0x98, 0xde, 0xb4, 0x29, 0x52, 0x2b, 0x43, 0x3f,
+	0x00, 0xd0, 0xdf, 0x2b, 0x26, 0xa9, 0xca, 0x88, 0xc6, 0x7b, 0xff, 0x6b, 0x8a, 0x1b, 0xf5, 0x85,
+	0x46, 0xcf, 0x01, 0x56, 0x52, 0x98, 0x2a, 0x73, 0xb9, 0xf7, 0x5d, 0x90, 0xd8, 0x21, 0xb7, 0x36,
+	0xfc, 0x04, 0x4e, 0x3c, 0xad, 0x89, 0x5a, 0x67, 0x73, 0x61, 0xb8, 0xc6, 0x07, 0xa3, 0x60, 0x12,
+	0xa6, 0x47, 0x0e, 0xbf, 0x21, 0x6a, 0xfd, 0xce, 0xa2, 0x68, 0x0c, 0x3d, 0x22, 0x57, 0x0a, 0x47,
+	0xa3, 0x70, 0x92, 0x9c, 0x1f, 0x4d, 0xbb, 0x6f, 0x38, 0xbd, 0x90, 0xab, 0xd4, 0x71, 0xe8, 0x2d,
+	0x1c, 0xdc, 0x51, 0xb2, 0xa0, 0x52, 0xe1, 0xd8, 0xc9, 0x5e, 0x6c, 0xc9, 0xba, 0xaf, 0x38, 0x9d,
+	0x79, 0xcd, 0x7b, 0xae, 0x65, 0x9d, 0xb6, 0x0e, 0xf4, 0x15, 0xc4, 0xac, 0x2c, 0x8d, 0x26, 0x79,
+	0x41, 0x31, 0x8c, 0x82, 0x49, 0x94, 0x6e, 0x00, 0xf7, 0x45, 0xa9, 0x96, 0x75, 0x93, 0x31, 0x71,
+	0x19, 0xc1, 0x41, 0x3e, 0xdf, 0x4b, 0x38, 0xf4, 0x02, 0x7b, 0x83, 0xc2, 0x68, 0xdc, 0x77, 0x92,
+	0xbe, 0x03, 0x6f, 0x3c, 0x86, 0xbe, 0x81, 0xe3, 0x86, 0xce, 0x14, 0x9d, 0x0b, 0xbe, 0x50, 0xf8,
+	0xd0, 0x9f, 0xb6, 0x81, 0xaf, 0x3d, 0x8a, 0x06, 0x10, 0x55, 0x92, 0x09, 0xc9, 0x74, 0x8d, 0x8f,
+	0x46, 0xc1, 0xe4, 0x30, 0xed, 0x7e, 0xa3, 0x57, 0x00, 0x82, 0x67, 0xca, 0xcc, 0xe7, 0x54, 0x29,
+	0x7c, 0xec, 0x0e, 0x7a, 0xfa, 0xd0, 0x41, 0xd3, 0x58, 0xf0, 0x6b, 0x2f, 0x43, 0x67, 0x10, 0x09,
+	0x9e, 0x51, 0x29, 0x85, 0xc4, 0x27, 0x8f, 0x58, 0x0e, 0x04, 0x7f, 0x6f, 0x45, 0xe8, 0x2d, 0x1c,
+	0xcd, 0xef, 0x84, 0x5c, 0x64, 0x73, 0x52, 0x14, 0x39, 0x99, 0xaf, 0xf1, 0x17, 0xa3, 0xe0, 0x3f,
+	0x6d, 0x87, 0x4e, 0xfb, 0xae, 0x91, 0xa2, 0x37, 0x80, 0xbd, 0x59, 0xf0, 0xac, 0x22, 0x52, 0x33,
+	0x52, 0x64, 0x4b, 0xc2, 0x0a, 0x23, 0x29, 0x46, 0xae, 0x07, 0x9e, 0x3a, 0xfe, 0x27, 0xfe, 0xb3,
+	0x67, 0x3f, 0x78, 0x12, 0x7d, 0x80, 0x53, 0x6f, 0x74, 0x49, 0x37, 0x7b, 0x3f, 0x79, 0x64, 0x6f,
+	0xe4, 0x1c, 0x2e, 0x75, 0x17, 0x60, 0x08, 0xc9, 0x3d, 0xcd, 0xef, 0x84, 0x58, 0x67, 0x46, 0x16,
+	0xf8, 0xd4, 0x3f, 0x80, 0x06, 0xba, 0x95, 0x85, 0xbd, 0xae, 0x7b, 0x21, 0xd7, 0xcb, 0x42, 0xdc,
+	0xfb, 0xd6, 0x7c, 0xea, 0x24, 0xfd, 0x16, 0x74, 0xdd, 0xf9, 0x1d, 0x3c, 0xa3, 0x4a, 0xb3, 0x92,
+	0x68, 0xba, 0xc8, 0x4a, 0x5a, 0x0a, 0x59, 0x67, 0x79, 0xad, 0xa9, 0xc2, 0xcf, 0xdc, 0xad, 0x9d,
+	0x76, 0xec, 0x95, 0x23, 0x2f, 0x2d, 0xb7, 0xe9, 0x04, 0x9b, 0x44, 0x2c, 0x97, 0xf8, 0x4b, 0x5f,
+	0xda, 0x81, 0x97, 0x1e, 0x1b, 0x7c, 0x82, 0xfe, 0x76, 0x1b, 0xa2, 0x13, 0x08, 0xed, 0x4b, 0xf5,
+	0x0f, 0xdb, 0x2e, 0xd1, 0xd7, 0xb0, 0xf7, 0x1b, 0x29, 0x8c, 0x7f, 0xd8, 0xc9, 0xf9, 0xc9, 0xd6,
+	0xd9, 0x7f, 0xb1, 0x78, 0xea, 0xe9, 0x1f, 0x77, 0xbf, 0x0f, 0xc6, 0xb7, 0x10, 0x5e, 0xc8, 0x55,
+	0x37, 0x0a, 0x82, 0xad, 0x51, 0x80, 0xa0, 0xa7, 0xeb, 0xaa, 0x1b, 0x0f, 0x76, 0xbd, 0x29, 0x1d,
+	0x3e, 0x5a, 0x7a, 0xfc, 0x47, 0x08, 0x7b, 0x0e, 0x40, 0x43, 0x00, 0x6e, 0x8a, 0x22, 0xf3, 0x36,
+	0x5b, 0x3f, 0x9a, 0xed, 0xa4, 0xb1, 0xc5, 0x3a, 0x41, 0x2e, 0x44, 0x2b, 0xd8, 0x6d, 0x05, 0x16,
+	0xf3, 0x82, 0xe7, 0x10, 0x33, 0xae, 0xb3, 0xcd, 0xbe, 0x68, 0xb6, 0x93, 0x46, 0x8c, 0xeb, 0xce,
+	0x6f, 0x36, 0xbc, 0x9d, 0x4b, 0x3d, 0xeb, 0x37, 0x9d, 0xe0, 0x25, 0xf4, 0x17, 0xc2, 0xe4, 0x05,
+	0x6d, 0x24, 0x76, 0x0a, 0x05, 0xb3, 0x9d, 0x34, 0xf1, 0x68, 0x27, 0x52, 0x5a, 0xda, 0xb1, 0xe7,
+	0x45, 0x6e, 0xde, 0x58, 0x91, 0x47, 0xbd, 0xe8, 0x05, 0x24, 0xee, 0x12, 0x1b, 0x8d, 0x1d, 0x37,
+	0xfd, 0xd9, 0x4e, 0x0a, 0x0e, 0xec, 0xea, 0x70, 0x53, 0xe6, 0x54, 0x36, 0x9a, 0xa8, 0xad, 0xe3,
+	0x51, 0x2f, 0x7a, 0x0d, 0x50, 0x30, 0xd5, 0x46, 0x8e, 0xff, 0xd5, 0xa1, 0x9f, 0x98, 0xf2, 0xd9,
+	0xed, 0x41, 0x8a, 0xf6, 0x07, 0x3a, 0x87, 0xb8, 0x24, 0x55, 0xe3, 0x02, 0xe7, 0x7a, 0xb2, 0xe5,
+	0xba, 0x22, 0x55, 0x6b, 0x8a, 0xca, 0x66, 0x7d, 0xb9, 0x0f, 0xbd, 0x35, 0xe3, 0x8b, 0xf1, 0x6b,
+	0x88, 0xbb, 0xaa, 0x68, 0x02, 0xfb, 0xae, 0x88, 0xc2, 0xc1, 0x28, 0x7c, 0xf0, 0x1a, 0x1b, 0x7e,
+	0xfc, 0x67, 0x00, 0x51, 0x5b, 0x17, 0xbd, 0xf9, 0x87, 0x6d, 0xf8, 0xc0, 0xe6, 0xde, 0xdf, 0x4c,
+	0xc8, 0x46, 0x3e, 0xf8, 0x08, 0xc9, 0x16, 0xfc, 0x79, 0x1d, 0x7b, 0xd9, 0xfb, 0x75, 0xb7, 0xca,
+	0xf3, 0x7d, 0xf7, 0xe7, 0xf1, 0xea, 0xef, 0x01, 0x00, 0x84, 0xf3, 0xd4, 0x8e, 0x23, 0x07, 0x00,
+	0x00,
+}
@@ -0,0 +1,327 @@
+package brokers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	"github.com/RichardKnop/machinery/v1/brokers/pb"
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+)
+
+// ProtobufContentType is content type of the protobuf serializer
+const ProtobufContentType = "application/x-protobuf"
+
+// ProtobufSerializer encodes task signatures as protobuf messages, see
+// pb/signature.proto for the schema. Unlike JSON, integers of any size keep
+// their precision and byte slices are encoded as they are.
+type ProtobufSerializer struct{}
+
+// ContentType returns application/x-protobuf
+func (ProtobufSerializer) ContentType() string {
+	return ProtobufContentType
+}
+
+// Marshal encodes the signature as a protobuf message
+func (ProtobufSerializer) Marshal(signature *tasks.Signature) ([]byte, error) {
+	message, err := protobufSignature(signature)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(message)
+}
+
+// Unmarshal decodes the signature from a protobuf message, integers are
+// decoded as int64 or uint64 so they can be converted to the arg type precisely
+func (ProtobufSerializer) Unmarshal(data []byte, signature *tasks.Signature) error {
+	message := new(pb.Signature)
+	if err := proto.Unmarshal(data, message); err != nil {
+		return err
+	}
+
+	decoded, err := signatureFromProtobuf(message)
+	if err != nil {
+		return err
+	}
+	*signature = *decoded
+	return nil
+}
+
+// protobufSignature converts the signature to its protobuf message
+func protobufSignature(signature *tasks.Signature) (*pb.Signature, error) {
+	if signature == nil {
+		return nil, nil
+	}
+
+	message := &pb.Signature{
+		Uuid:                  signature.UUID,
+		Name:                  signature.Name,
+		RoutingKey:            signature.RoutingKey,
+		Eta:                   protobufTime(signature.ETA),
+		ExpiresAt:             protobufTime(signature.ExpiresAt),
+		GroupUuid:             signature.GroupUUID,
+		GroupTaskCount:        int64(signature.GroupTaskCount),
+		Immutable:             signature.Immutable,
+		RetryCount:            int64(signature.RetryCount),
+		RetryTimeout:          int64(signature.RetryTimeout),
+		RetryBackoff:          signature.RetryBackoff,
+		TimeoutSeconds:        int64(signature.TimeoutSeconds),
+		Priority:              uint32(signature.Priority),
+		ChordOnPartialFailure: signature.ChordOnPartialFailure,
+		WebhookUrl:            signature.WebhookURL,
+		WorkflowUuid:          signature.WorkflowUUID,
+		EstimatedMemoryBytes:  int64(signature.EstimatedMemoryBytes),
+	}
+
+	for _, arg := range signature.Args {
+		value, err := protobufValue(arg.Value)
+		if err != nil {
+			return nil, fmt.Errorf("Encode arg %s error: %s", arg.Type, err)
+		}
+		message.Args = append(message.Args, &pb.Arg{Name: arg.Name, Type: arg.Type, Value: value})
+	}
+
+	if len(signature.Headers) > 0 {
+		message.Headers = make(map[string]*pb.Value, len(signature.Headers))
+		for k, v := range signature.Headers {
+			value, err := protobufValue(v)
+			if err != nil {
+				return nil, fmt.Errorf("Encode header %s error: %s", k, err)
+			}
+			message.Headers[k] = value
+		}
+	}
+
+	var err error
+	if message.OnSuccess, err = protobufSignatures(signature.OnSuccess); err != nil {
+		return nil, err
+	}
+	if message.OnError, err = protobufSignatures(signature.OnError); err != nil {
+		return nil, err
+	}
+	if message.ChordCallback, err = protobufSignature(signature.ChordCallback); err != nil {
+		return nil, err
+	}
+	if message.ChordErrorCallback, err = protobufSignature(signature.ChordErrorCallback); err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+func protobufSignatures(signatures []*tasks.Signature) ([]*pb.Signature, error) {
+	var messages []*pb.Signature
+	for _, signature := range signatures {
+		message, err := protobufSignature(signature)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// signatureFromProtobuf converts the protobuf message back to the signature
+func signatureFromProtobuf(message *pb.Signature) (*tasks.Signature, error) {
+	if message == nil {
+		return nil, nil
+	}
+
+	if message.Priority > math.MaxUint8 {
+		return nil, fmt.Errorf("Priority %d overflows uint8", message.Priority)
+	}
+
+	signature := &tasks.Signature{
+		UUID:                  message.Uuid,
+		Name:                  message.Name,
+		RoutingKey:            message.RoutingKey,
+		ETA:                   timeFromProtobuf(message.Eta),
+		ExpiresAt:             timeFromProtobuf(message.ExpiresAt),
+		GroupUUID:             message.GroupUuid,
+		GroupTaskCount:        int(message.GroupTaskCount),
+		Immutable:             message.Immutable,
+		RetryCount:            int(message.RetryCount),
+		RetryTimeout:          int(message.RetryTimeout),
+		RetryBackoff:          message.RetryBackoff,
+		TimeoutSeconds:        int(message.TimeoutSeconds),
+		Priority:              uint8(message.Priority),
+		ChordOnPartialFailure: message.ChordOnPartialFailure,
+		WebhookURL:            message.WebhookUrl,
+		WorkflowUUID:          message.WorkflowUuid,
+		EstimatedMemoryBytes:  int(message.EstimatedMemoryBytes),
+	}
+
+	for _, arg := range message.Args {
+		signature.Args = append(signature.Args, tasks.Arg{
+			Name:  arg.Name,
+			Type:  arg.Type,
+			Value: valueFromProtobuf(arg.Value),
+		})
+	}
+
+	if len(message.Headers) > 0 {
+		signature.Headers = make(tasks.Headers, len(message.Headers))
+		for k, v := range message.Headers {
+			signature.Headers[k] = valueFromProtobuf(v)
+		}
+	}
+
+	var err error
+	if signature.OnSuccess, err = signaturesFromProtobuf(message.OnSuccess); err != nil {
+		return nil, err
+	}
+	if signature.OnError, err = signaturesFromProtobuf(message.OnError); err != nil {
+		return nil, err
+	}
+	if signature.ChordCallback, err = signatureFromProtobuf(message.ChordCallback); err != nil {
+		return nil, err
+	}
+	if signature.ChordErrorCallback, err = signatureFromProtobuf(message.ChordErrorCallback); err != nil {
+		return nil, err
+	}
+
+	return signature, nil
+}
+
+func signaturesFromProtobuf(messages []*pb.Signature) ([]*tasks.Signature, error) {
+	var signatures []*tasks.Signature
+	for _, message := range messages {
+		signature, err := signatureFromProtobuf(message)
+		if err != nil {
+			return nil, err
+		}
+		signatures = append(signatures, signature)
+	}
+	return signatures, nil
+}
+
+func protobufTime(t *time.Time) *timestamp.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return &timestamp.Timestamp{Seconds: t.Unix(), Nanos: int32(t.Nanosecond())}
+}
+
+func timeFromProtobuf(t *timestamp.Timestamp) *time.Time {
+	if t == nil {
+		return nil
+	}
+	decoded := time.Unix(t.Seconds, int64(t.Nanos)).UTC()
+	return &decoded
+}
+
+// protobufValue converts an arg or header value to its protobuf message,
+// times are encoded as RFC 3339 strings like with JSON
+func protobufValue(value interface{}) (*pb.Value, error) {
+	switch value := value.(type) {
+	case nil:
+		return &pb.Value{Kind: &pb.Value_NullValue{NullValue: true}}, nil
+	case []byte:
+		return &pb.Value{Kind: &pb.Value_BytesValue{BytesValue: value}}, nil
+	case json.Number:
+		return &pb.Value{Kind: &pb.Value_NumberValue{NumberValue: value.String()}}, nil
+	case time.Time:
+		return &pb.Value{Kind: &pb.Value_StringValue{StringValue: value.Format(time.RFC3339Nano)}}, nil
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Bool:
+		return &pb.Value{Kind: &pb.Value_BoolValue{BoolValue: v.Bool()}}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &pb.Value{Kind: &pb.Value_IntValue{IntValue: v.Int()}}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &pb.Value{Kind: &pb.Value_UintValue{UintValue: v.Uint()}}, nil
+	case reflect.Float32, reflect.Float64:
+		return &pb.Value{Kind: &pb.Value_DoubleValue{DoubleValue: v.Float()}}, nil
+	case reflect.String:
+		return &pb.Value{Kind: &pb.Value_StringValue{StringValue: v.String()}}, nil
+	case reflect.Ptr:
+		if v.IsNil() {
+			return protobufValue(nil)
+		}
+		return protobufValue(v.Elem().Interface())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return protobufValue(nil)
+		}
+		list := &pb.ListValue{Values: make([]*pb.Value, v.Len())}
+		for i := 0; i < v.Len(); i++ {
+			elem, err := protobufValue(v.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			list.Values[i] = elem
+		}
+		return &pb.Value{Kind: &pb.Value_ListValue{ListValue: list}}, nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			break
+		}
+		if v.IsNil() {
+			return protobufValue(nil)
+		}
+		values := &pb.MapValue{Values: make(map[string]*pb.Value, v.Len())}
+		for _, key := range v.MapKeys() {
+			elem, err := protobufValue(v.MapIndex(key).Interface())
+			if err != nil {
+				return nil, err
+			}
+			values.Values[key.String()] = elem
+		}
+		return &pb.Value{Kind: &pb.Value_MapValue{MapValue: values}}, nil
+	}
+
+	// Other values, e.g. structs of types registered with tasks.RegisterType,
+	// are encoded the way they are encoded as JSON
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var decoded interface{}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	return protobufValue(decoded)
+}
+
+// valueFromProtobuf converts the protobuf message back to an arg or header
+// value, lists are decoded as []interface{} and maps as map[string]interface{}
+func valueFromProtobuf(value *pb.Value) interface{} {
+	switch kind := value.GetKind().(type) {
+	case *pb.Value_BoolValue:
+		return kind.BoolValue
+	case *pb.Value_IntValue:
+		return kind.IntValue
+	case *pb.Value_UintValue:
+		return kind.UintValue
+	case *pb.Value_DoubleValue:
+		return kind.DoubleValue
+	case *pb.Value_StringValue:
+		return kind.StringValue
+	case *pb.Value_BytesValue:
+		return kind.BytesValue
+	case *pb.Value_NumberValue:
+		return json.Number(kind.NumberValue)
+	case *pb.Value_ListValue:
+		values := make([]interface{}, len(kind.ListValue.GetValues()))
+		for i, elem := range kind.ListValue.GetValues() {
+			values[i] = valueFromProtobuf(elem)
+		}
+		return values
+	case *pb.Value_MapValue:
+		values := make(map[string]interface{}, len(kind.MapValue.GetValues()))
+		for k, elem := range kind.MapValue.GetValues() {
+			values[k] = valueFromProtobuf(elem)
+		}
+		return values
+	}
+	return nil
+}
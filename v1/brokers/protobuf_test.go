@@ -0,0 +1,191 @@
+package brokers_test
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/machinery/v1/brokers"
+	"github.com/RichardKnop/machinery/v1/config"
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProtobufSerializer(t *testing.T) {
+	t.Parallel()
+
+	serializer, err := brokers.GetSerializer(brokers.ProtobufContentType)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	eta := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	expiresAt := eta.Add(time.Hour)
+	signature := &tasks.Signature{
+		UUID:           "task_1",
+		Name:           "add",
+		RoutingKey:     "machinery_tasks",
+		ETA:            &eta,
+		ExpiresAt:      &expiresAt,
+		GroupUUID:      "group_1",
+		GroupTaskCount: 2,
+		Args: []tasks.Arg{
+			{Name: "a", Type: "uint64", Value: uint64(math.MaxUint64)},
+			{Type: "int64", Value: int64(math.MinInt64)},
+		},
+		Headers:               tasks.Headers{"retries": 1, "trace": "abc"},
+		Immutable:             true,
+		RetryCount:            3,
+		RetryTimeout:          5,
+		RetryBackoff:          tasks.RetryBackoffExponential,
+		TimeoutSeconds:        60,
+		Priority:              9,
+		OnSuccess:             []*tasks.Signature{{Name: "on_success"}},
+		OnError:               []*tasks.Signature{{Name: "on_error"}},
+		ChordCallback:         &tasks.Signature{Name: "callback"},
+		ChordOnPartialFailure: tasks.ChordWait,
+		ChordErrorCallback:    &tasks.Signature{Name: "error_callback"},
+		WebhookURL:            "http://example.com/webhook",
+		WorkflowUUID:          "workflow_1",
+		EstimatedMemoryBytes:  1024,
+	}
+
+	// Every field is set, so fields added to signatures must be encoded too
+	fields := reflect.ValueOf(signature).Elem()
+	for i := 0; i < fields.NumField(); i++ {
+		assert.False(t, isZero(fields.Field(i)), "%s is not set", fields.Type().Field(i).Name)
+	}
+
+	msg, err := serializer.Marshal(signature)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	decoded := new(tasks.Signature)
+	assert.NoError(t, serializer.Unmarshal(msg, decoded))
+
+	// Integers are decoded as 64 bit integers
+	expected := *signature
+	expected.Headers = tasks.Headers{"retries": int64(1), "trace": "abc"}
+	assert.Equal(t, &expected, decoded)
+	assert.Equal(t, 1, decoded.Headers.Count("retries"))
+}
+
+func TestProtobufSerializerValues(t *testing.T) {
+	t.Parallel()
+
+	serializer := brokers.ProtobufSerializer{}
+	at := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+
+	testCases := []struct {
+		arg      tasks.Arg
+		expected interface{}
+		reflect  interface{}
+	}{
+		{
+			arg:      tasks.Arg{Type: "[]byte", Value: []byte{0xff, 0x00, 0xfe}},
+			expected: []byte{0xff, 0x00, 0xfe},
+			reflect:  []byte{0xff, 0x00, 0xfe},
+		},
+		{
+			arg:      tasks.Arg{Type: "[]int64", Value: []int64{math.MaxInt64, -1}},
+			expected: []interface{}{int64(math.MaxInt64), int64(-1)},
+			reflect:  []int64{math.MaxInt64, -1},
+		},
+		{
+			arg:      tasks.Arg{Type: "map[string]uint64", Value: map[string]uint64{"a": math.MaxUint64}},
+			expected: map[string]interface{}{"a": uint64(math.MaxUint64)},
+			reflect:  map[string]uint64{"a": math.MaxUint64},
+		},
+		{
+			arg:      tasks.Arg{Type: "float64", Value: 0.1},
+			expected: 0.1,
+			reflect:  0.1,
+		},
+		{
+			arg:      tasks.Arg{Type: "int64", Value: json.Number("9007199254740993")},
+			expected: json.Number("9007199254740993"),
+			reflect:  int64(9007199254740993),
+		},
+		{
+			arg:      tasks.Arg{Type: "time.Time", Value: at},
+			expected: "2020-01-02T03:04:05.000000006Z",
+			reflect:  at,
+		},
+		{
+			arg:      tasks.Arg{Type: "[]string", Value: nil},
+			expected: nil,
+			reflect:  []string{},
+		},
+		{
+			// Other values are encoded like they are encoded as JSON
+			arg:      tasks.Arg{Type: "tasks.TaskError", Value: tasks.TaskError{Message: "failed", Retries: 2}},
+			expected: map[string]interface{}{"Message": "failed", "TaskName": "", "TaskUUID": "", "Retries": json.Number("2")},
+			reflect:  tasks.TaskError{Message: "failed", Retries: 2},
+		},
+	}
+
+	for _, testCase := range testCases {
+		msg, err := serializer.Marshal(&tasks.Signature{Name: "task", Args: []tasks.Arg{testCase.arg}})
+		if !assert.NoError(t, err, testCase.arg.Type) {
+			continue
+		}
+
+		decoded := new(tasks.Signature)
+		if !assert.NoError(t, serializer.Unmarshal(msg, decoded), testCase.arg.Type) {
+			continue
+		}
+		if assert.Len(t, decoded.Args, 1, testCase.arg.Type) {
+			assert.Equal(t, testCase.expected, decoded.Args[0].Value, testCase.arg.Type)
+
+			value, err := tasks.ReflectValue(decoded.Args[0].Type, decoded.Args[0].Value)
+			if assert.NoError(t, err, testCase.arg.Type) {
+				assert.Equal(t, testCase.reflect, value.Interface(), testCase.arg.Type)
+			}
+		}
+	}
+}
+
+func TestProtobufSerializerPriorityOverflow(t *testing.T) {
+	t.Parallel()
+
+	// Producers in other languages are not limited to uint8 priorities
+	err := brokers.ProtobufSerializer{}.Unmarshal([]byte{0x70, 0x80, 0x02}, new(tasks.Signature))
+	assert.EqualError(t, err, "Priority 256 overflows uint8")
+}
+
+func TestAMQPConsumeOneProtobuf(t *testing.T) {
+	t.Parallel()
+
+	msg, err := brokers.ProtobufSerializer{}.Marshal(&tasks.Signature{
+		Name: "add",
+		Args: []tasks.Arg{{Type: "uint64", Value: uint64(math.MaxUint64)}},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	broker := newTestAMQPBroker(&config.AMQPConfig{})
+	broker.SetRegisteredTaskNames([]string{"add"})
+	acknowledger := new(fakeAcknowledger)
+	processor := new(fakeTaskProcessor)
+
+	err = broker.ConsumeOneForTest(amqp.Delivery{
+		Acknowledger: acknowledger,
+		ContentType:  brokers.ProtobufContentType,
+		Body:         msg,
+	}, processor)
+	assert.NoError(t, err)
+	assert.True(t, acknowledger.acked)
+	if assert.Len(t, processor.processed, 1) {
+		assert.Equal(t, uint64(math.MaxUint64), processor.processed[0].Args[0].Value)
+	}
+}
+
+// isZero returns true if the value is the zero value of its type
+func isZero(value reflect.Value) bool {
+	return reflect.DeepEqual(value.Interface(), reflect.Zero(value.Type()).Interface())
+}
@@ -1,9 +1,6 @@
 package brokers
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
 	"sync"
 	"time"
 
@@ -58,9 +55,10 @@ func (b *RedisBroker) StartConsuming(consumerTag string, concurrency int, taskPr
 	// Ping the server to make sure connection is live
 	_, err := conn.Do("PING")
 	if err != nil {
-		b.retryFunc(b.retryStopChan)
-		return b.retry, err
+		return b.connectFailed(err), err
 	}
+	b.connected()
+	defer b.disconnected()
 
 	// Channels and wait groups used to properly close down goroutines
 	b.stopReceivingChan = make(chan int)
@@ -96,6 +94,8 @@ func (b *RedisBroker) StartConsuming(consumerTag string, concurrency int, taskPr
 	go func() {
 		defer b.receivingWG.Done()
 
+		queues := b.getQueues(taskProcessor)
+
 		log.INFO.Print("[*] Waiting for messages. To exit press CTRL+C")
 
 		for {
@@ -104,10 +104,18 @@ func (b *RedisBroker) StartConsuming(consumerTag string, concurrency int, taskPr
 			case <-b.stopReceivingChan:
 				return
 			case <-timer.C:
+				// Do not pull tasks off the queue while consuming is paused
+				if !b.waitUntilResumed(b.stopReceivingChan) {
+					return
+				}
+
 				// If concurrency is limited, limit the tasks being pulled off the queue
 				// until a pool is available
 				if concurrencyAvailable() {
-					task, err := b.nextTask(b.cnf.DefaultQueue)
+					task, err := b.nextTask(queues...)
+					// BLPOP pops from the first non-empty queue, rotate the
+					// queues so a busy queue does not starve the others
+					queues = append(queues[1:], queues[0])
 					if err != nil {
 						// something went wrong, wait a bit before continuing the loop
 						timer.Reset(timerDuration)
@@ -144,10 +152,9 @@ func (b *RedisBroker) StartConsuming(consumerTag string, concurrency int, taskPr
 				}
 
 				signature := new(tasks.Signature)
-				decoder := json.NewDecoder(bytes.NewReader(task))
-				decoder.UseNumber()
-				if err := decoder.Decode(signature); err != nil {
+				if err := b.unmarshal(task, "", signature); err != nil {
 					log.ERROR.Print(NewErrCouldNotUnmarshaTaskSignature(task, err))
+					continue
 				}
 
 				if err := b.Publish(signature); err != nil {
@@ -162,7 +169,7 @@ func (b *RedisBroker) StartConsuming(consumerTag string, concurrency int, taskPr
 	}
 
 	// Waiting for any tasks being processed to finish
-	b.processingWG.Wait()
+	b.waitForProcessing(&b.processingWG)
 
 	return b.retry, nil
 }
@@ -182,7 +189,7 @@ func (b *RedisBroker) StopConsuming() {
 	b.stopConsuming()
 
 	// Waiting for any tasks being processed to finish
-	b.processingWG.Wait()
+	b.waitForProcessing(&b.processingWG)
 }
 
 // Publish places a new message on the default queue
@@ -190,9 +197,10 @@ func (b *RedisBroker) Publish(signature *tasks.Signature) error {
 	// Adjust routing key (this decides which queue the message will be published to)
 	AdjustRoutingKey(b, signature)
 
-	msg, err := json.Marshal(signature)
+	// Redis messages do not carry content type, consumers have to use the same serializer
+	msg, _, err := b.marshal(signature)
 	if err != nil {
-		return fmt.Errorf("JSON marshal error: %s", err)
+		return err
 	}
 
 	conn := b.open()
@@ -214,6 +222,193 @@ func (b *RedisBroker) Publish(signature *tasks.Signature) error {
 	return err
 }
 
+// PublishBatch places new messages on the default queue using a single
+// pipeline
+func (b *RedisBroker) PublishBatch(signatures []*tasks.Signature) []error {
+	errs := make([]error, len(signatures))
+
+	conn := b.open()
+	defer conn.Close()
+
+	var sent []int
+	for i, signature := range signatures {
+		// Adjust routing key (this decides which queue the message will be published to)
+		AdjustRoutingKey(b, signature)
+
+		msg, _, err := b.marshal(signature)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+
+		// Delay tasks with ETA in the future
+		if signature.ETA != nil && signature.ETA.After(time.Now().UTC()) {
+			err = conn.Send("ZADD", redisDelayedTasksKey, signature.ETA.UnixNano(), msg)
+		} else {
+			err = conn.Send("RPUSH", signature.RoutingKey, msg)
+		}
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		sent = append(sent, i)
+	}
+
+	if err := conn.Flush(); err != nil {
+		for _, i := range sent {
+			errs[i] = err
+		}
+		return errs
+	}
+
+	for _, i := range sent {
+		if _, err := conn.Receive(); err != nil {
+			errs[i] = err
+		}
+	}
+
+	return errs
+}
+
+// QueueLength returns the number of tasks waiting in the queue, delayed
+// tasks which are not due yet are not counted
+func (b *RedisBroker) QueueLength(queue string) (int, error) {
+	conn := b.open()
+	defer conn.Close()
+
+	if queue == "" {
+		queue = b.cnf.DefaultQueue
+	}
+	return redis.Int(conn.Do("LLEN", queue))
+}
+
+// PurgeQueue deletes the queue, delayed tasks which are not due yet are kept
+func (b *RedisBroker) PurgeQueue(queue string) (int, error) {
+	conn := b.open()
+	defer conn.Close()
+
+	if queue == "" {
+		queue = b.cnf.DefaultQueue
+	}
+
+	conn.Send("MULTI")
+	conn.Send("LLEN", queue)
+	conn.Send("DEL", queue)
+	replies, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return 0, err
+	}
+	return redis.Int(replies[0], nil)
+}
+
+// DrainQueue pops the tasks waiting in the queue when it is called and hands
+// each of them to fn, tasks which cannot be decoded are dropped
+func (b *RedisBroker) DrainQueue(queue string, fn func(signature *tasks.Signature)) (int, error) {
+	conn := b.open()
+	defer conn.Close()
+
+	if queue == "" {
+		queue = b.cnf.DefaultQueue
+	}
+
+	// Only drain tasks enqueued so far, producers may keep publishing
+	length, err := redis.Int(conn.Do("LLEN", queue))
+	if err != nil {
+		return 0, err
+	}
+
+	drained := 0
+	for i := 0; i < length; i++ {
+		task, err := redis.Bytes(conn.Do("LPOP", queue))
+		if err == redis.ErrNil {
+			break
+		}
+		if err != nil {
+			return drained, err
+		}
+
+		signature := new(tasks.Signature)
+		if err := b.unmarshal(task, "", signature); err != nil {
+			log.ERROR.Print(NewErrCouldNotUnmarshaTaskSignature(task, err))
+			continue
+		}
+
+		fn(signature)
+		drained++
+	}
+	return drained, nil
+}
+
+// TakeTasks removes up to limit tasks waiting in the queue, the default queue
+// if empty, each of them once fn handled it without an error. A task fn fails
+// for is pushed back to the head of the queue, tasks which cannot be decoded
+// to its tail.
+func (b *RedisBroker) TakeTasks(queue string, limit int, fn func(signature *tasks.Signature) error) (int, error) {
+	conn := b.open()
+	defer conn.Close()
+
+	if queue == "" {
+		queue = b.cnf.DefaultQueue
+	}
+
+	// Only take tasks enqueued so far, skipped ones are pushed to the tail
+	length, err := redis.Int(conn.Do("LLEN", queue))
+	if err != nil {
+		return 0, err
+	}
+
+	taken := 0
+	for i := 0; i < length && taken < limit; i++ {
+		task, err := redis.Bytes(conn.Do("LPOP", queue))
+		if err == redis.ErrNil {
+			break
+		}
+		if err != nil {
+			return taken, err
+		}
+
+		signature := new(tasks.Signature)
+		if err := b.unmarshal(task, "", signature); err != nil {
+			log.ERROR.Print(NewErrCouldNotUnmarshaTaskSignature(task, err))
+			conn.Do("RPUSH", queue, task)
+			continue
+		}
+
+		if err := fn(signature); err != nil {
+			conn.Do("LPUSH", queue, task)
+			return taken, err
+		}
+		taken++
+	}
+	return taken, nil
+}
+
+// PeekTasks returns up to limit tasks waiting in the queue, the default queue
+// if empty, without modifying it in any way
+func (b *RedisBroker) PeekTasks(queue string, limit int) ([]*tasks.Signature, error) {
+	conn := b.open()
+	defer conn.Close()
+
+	if queue == "" {
+		queue = b.cnf.DefaultQueue
+	}
+	results, err := redis.ByteSlices(conn.Do("LRANGE", queue, 0, limit-1))
+	if err != nil {
+		return nil, err
+	}
+
+	var signatures []*tasks.Signature
+	for _, result := range results {
+		signature := new(tasks.Signature)
+		if err := b.unmarshal(result, "", signature); err != nil {
+			log.ERROR.Print(NewErrCouldNotUnmarshaTaskSignature(result, err))
+			continue
+		}
+		signatures = append(signatures, signature)
+	}
+	return signatures, nil
+}
+
 // GetPendingTasks returns a slice of task signatures waiting in the queue
 func (b *RedisBroker) GetPendingTasks(queue string) ([]*tasks.Signature, error) {
 	conn := b.open()
@@ -234,9 +429,7 @@ func (b *RedisBroker) GetPendingTasks(queue string) ([]*tasks.Signature, error)
 	taskSignatures := make([]*tasks.Signature, len(results))
 	for i, result := range results {
 		signature := new(tasks.Signature)
-		decoder := json.NewDecoder(bytes.NewReader(result))
-		decoder.UseNumber()
-		if err := decoder.Decode(signature); err != nil {
+		if err := b.unmarshal(result, "", signature); err != nil {
 			return nil, err
 		}
 		taskSignatures[i] = signature
@@ -284,33 +477,58 @@ func (b *RedisBroker) consume(deliveries <-chan []byte, pool chan struct{}, conc
 // consumeOne processes a single message using TaskProcessor
 func (b *RedisBroker) consumeOne(delivery []byte, taskProcessor TaskProcessor) error {
 	signature := new(tasks.Signature)
-	decoder := json.NewDecoder(bytes.NewReader(delivery))
-	decoder.UseNumber()
-	if err := decoder.Decode(signature); err != nil {
+	if err := b.unmarshal(delivery, "", signature); err != nil {
+		if isSignatureError(err) {
+			b.rejectUnverified(delivery, err)
+			return nil
+		}
 		return NewErrCouldNotUnmarshaTaskSignature(delivery, err)
 	}
 
+	// The routing key is the queue the task was popped from
+	queue := signature.RoutingKey
+	if queue == "" {
+		queue = b.getQueue(taskProcessor)
+	}
+
 	// If the task is not registered, we requeue it,
 	// there might be different workers for processing specific tasks
-	if !b.IsTaskRegistered(signature.Name) {
+	if !b.acceptsTask(signature.Name) {
 		conn := b.open()
 		defer conn.Close()
 
-		conn.Do("RPUSH", b.cnf.DefaultQueue, delivery)
+		conn.Do("RPUSH", queue, delivery)
 		return nil
 	}
 
 	log.INFO.Printf("Received new message: %s", delivery)
 
-	return taskProcessor.Process(signature)
+	return processDelivery(taskProcessor, signature, tasks.DeliveryInfo{RoutingKey: queue})
+}
+
+// rejectUnverified drops a message failing verification without processing
+// it, it is pushed to the DeadLetterQueue as it was received if there is one
+func (b *RedisBroker) rejectUnverified(delivery []byte, err error) {
+	if b.cnf.DeadLetterQueue == "" {
+		log.ERROR.Printf("Rejecting message failing verification: %s. Error = %v", delivery, err)
+		return
+	}
+
+	log.ERROR.Printf("Dead lettering message failing verification: %s. Error = %v", delivery, err)
+	conn := b.open()
+	defer conn.Close()
+	if _, err := conn.Do("RPUSH", b.cnf.DeadLetterQueue, delivery); err != nil {
+		log.ERROR.Printf("Failed dead lettering message: %s", err)
+	}
 }
 
-// nextTask pops next available task from the default queue
-func (b *RedisBroker) nextTask(queue string) (result []byte, err error) {
+// nextTask pops next available task from the first of the queues which is
+// not empty
+func (b *RedisBroker) nextTask(queues ...string) (result []byte, err error) {
 	conn := b.open()
 	defer conn.Close()
 
-	items, err := redis.ByteSlices(conn.Do("BLPOP", queue, 1))
+	items, err := redis.ByteSlices(conn.Do("BLPOP", redis.Args{}.AddFlat(queues).Add(1)...))
 	if err != nil {
 		return []byte{}, err
 	}
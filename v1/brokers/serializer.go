@@ -0,0 +1,84 @@
+package brokers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/RichardKnop/machinery/v1/tasks"
+)
+
+// JSONContentType is content type of the default serializer
+const JSONContentType = "application/json"
+
+// Serializer encodes task signatures into message bodies and back
+type Serializer interface {
+	ContentType() string
+	Marshal(signature *tasks.Signature) ([]byte, error)
+	Unmarshal(data []byte, signature *tasks.Signature) error
+}
+
+// JSONSerializer is the default serializer
+type JSONSerializer struct{}
+
+// ContentType returns application/json
+func (JSONSerializer) ContentType() string {
+	return JSONContentType
+}
+
+// Marshal encodes the signature as JSON
+func (JSONSerializer) Marshal(signature *tasks.Signature) ([]byte, error) {
+	return json.Marshal(signature)
+}
+
+// Unmarshal decodes the signature from JSON, numbers are decoded
+// as json.Number so they can be converted to the arg type precisely
+func (JSONSerializer) Unmarshal(data []byte, signature *tasks.Signature) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(signature)
+}
+
+var (
+	serializers = map[string]Serializer{
+		JSONContentType:     JSONSerializer{},
+		ProtobufContentType: ProtobufSerializer{},
+	}
+	serializersMu sync.RWMutex
+)
+
+// RegisterSerializer makes the serializer available by its content type, both
+// for publishing tasks when set as Config.ContentType and for consuming them
+func RegisterSerializer(serializer Serializer) {
+	RegisterSerializerFor(serializer.ContentType(), serializer)
+}
+
+// RegisterSerializerFor makes the serializer available by the content type,
+// e.g. to consume messages of an alias of its own content type
+func RegisterSerializerFor(contentType string, serializer Serializer) {
+	serializersMu.Lock()
+	defer serializersMu.Unlock()
+	serializers[mediaType(contentType)] = serializer
+}
+
+// GetSerializer returns serializer registered for the content type, content
+// types are matched case insensitively and without parameters such as charset
+func GetSerializer(contentType string) (Serializer, error) {
+	serializersMu.RLock()
+	defer serializersMu.RUnlock()
+	serializer, ok := serializers[mediaType(contentType)]
+	if !ok {
+		return nil, fmt.Errorf("No serializer registered for content type %s", contentType)
+	}
+	return serializer, nil
+}
+
+// mediaType returns the content type without parameters, in lower case
+func mediaType(contentType string) string {
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
@@ -0,0 +1,55 @@
+package brokers_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/RichardKnop/machinery/v1/brokers"
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONSerializer(t *testing.T) {
+	serializer, err := brokers.GetSerializer(brokers.JSONContentType)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	msg, err := serializer.Marshal(&tasks.Signature{
+		Name: "add",
+		Args: []tasks.Arg{{Type: "uint64", Value: uint64(18446744073709551615)}},
+	})
+	assert.NoError(t, err)
+
+	signature := new(tasks.Signature)
+	assert.NoError(t, serializer.Unmarshal(msg, signature))
+	assert.Equal(t, "add", signature.Name)
+	// Numbers are kept as json.Number so large integers are not rounded
+	assert.Equal(t, json.Number("18446744073709551615"), signature.Args[0].Value)
+}
+
+func TestGetSerializerUnknownContentType(t *testing.T) {
+	_, err := brokers.GetSerializer("application/x-unknown")
+	assert.EqualError(t, err, "No serializer registered for content type application/x-unknown")
+}
+
+func TestRegisterSerializerFor(t *testing.T) {
+	brokers.RegisterSerializerFor("application/x-task-name-alias", nameSerializer{})
+
+	// Content types are matched without parameters, case insensitively
+	for _, contentType := range []string{
+		"application/x-task-name-alias",
+		"Application/X-Task-Name-Alias",
+		"application/x-task-name-alias; charset=utf-8",
+	} {
+		serializer, err := brokers.GetSerializer(contentType)
+		if assert.NoError(t, err, contentType) {
+			assert.Equal(t, nameSerializer{}, serializer)
+		}
+	}
+
+	serializer, err := brokers.GetSerializer("application/json; charset=utf-8")
+	if assert.NoError(t, err) {
+		assert.Equal(t, brokers.JSONSerializer{}, serializer)
+	}
+}
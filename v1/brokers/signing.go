@@ -0,0 +1,85 @@
+package brokers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+)
+
+var (
+	// ErrUnsignedMessage is returned when consuming a message which has not
+	// been signed while SigningKey is set
+	ErrUnsignedMessage = errors.New("Message is not signed")
+	// ErrInvalidMessageSignature is returned when consuming a message whose
+	// HMAC does not match the SigningKey, i.e. it has been tampered with or
+	// signed with another key
+	ErrInvalidMessageSignature = errors.New("Message signature is invalid")
+)
+
+// signedPrefix starts every signed message, followed by the hex encoded
+// HMAC-SHA256 of the rest of the message. Like gzipMagic serialized
+// signatures never start with it, so signed messages are recognised without
+// extra headers, and the message stays text for SQS.
+var signedPrefix = []byte("hmac-sha256:")
+
+// signatureLength is the length of the hex encoded HMAC following signedPrefix
+const signatureLength = sha256.Size * 2
+
+// sign prepends the HMAC of the message with SigningKey, messages are
+// returned as they are when it is not set
+func (b *Broker) sign(msg []byte) []byte {
+	if b.cnf == nil || len(b.cnf.SigningKey) == 0 {
+		return msg
+	}
+
+	signed := make([]byte, 0, len(signedPrefix)+signatureLength+len(msg))
+	signed = append(signed, signedPrefix...)
+	signed = append(signed, hex.EncodeToString(messageMAC(b.cnf.SigningKey, msg))...)
+	return append(signed, msg...)
+}
+
+// verify checks the HMAC of the message with SigningKey and returns the
+// message without it. When SigningKey is not set signed messages are accepted
+// without checking them, so workers can be given the key before producers.
+func (b *Broker) verify(msg []byte) ([]byte, error) {
+	signed := isSigned(msg)
+	if b.cnf == nil || len(b.cnf.SigningKey) == 0 {
+		if signed {
+			return msg[len(signedPrefix)+signatureLength:], nil
+		}
+		return msg, nil
+	}
+
+	if !signed {
+		return nil, ErrUnsignedMessage
+	}
+	mac, err := hex.DecodeString(string(msg[len(signedPrefix) : len(signedPrefix)+signatureLength]))
+	if err != nil {
+		return nil, ErrInvalidMessageSignature
+	}
+	msg = msg[len(signedPrefix)+signatureLength:]
+	if !hmac.Equal(mac, messageMAC(b.cnf.SigningKey, msg)) {
+		return nil, ErrInvalidMessageSignature
+	}
+	return msg, nil
+}
+
+// isSigned returns true if the message has been signed
+func isSigned(msg []byte) bool {
+	return len(msg) >= len(signedPrefix)+signatureLength && bytes.HasPrefix(msg, signedPrefix)
+}
+
+// isSignatureError returns true if the error means the message was rejected
+// by verify, such messages must not be processed nor requeued
+func isSignatureError(err error) bool {
+	return err == ErrUnsignedMessage || err == ErrInvalidMessageSignature
+}
+
+// messageMAC returns the HMAC-SHA256 of the message with the key
+func messageMAC(key, msg []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(msg)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,112 @@
+package machinery
+
+import (
+	"sync"
+	"time"
+
+	"github.com/RichardKnop/machinery/v1/log"
+)
+
+// SetCircuitBreaker stops workers of this server from starting the task for
+// the cooldown once it failed threshold times in a row, each failure within
+// the cooldown of the previous one. Tasks received while the circuit is open
+// are sent back to the queue with a delay. After the cooldown a single task
+// is started as a probe, the circuit closes again if it succeeds and stays
+// open for another cooldown otherwise. A threshold of 0 removes the breaker.
+// The breaker must be set before launching workers.
+func (server *Server) SetCircuitBreaker(taskName string, threshold int, cooldown time.Duration) {
+	if threshold <= 0 {
+		delete(server.circuitBreakers, taskName)
+		return
+	}
+	if server.circuitBreakers == nil {
+		server.circuitBreakers = make(map[string]*circuitBreaker)
+	}
+	server.circuitBreakers[taskName] = &circuitBreaker{
+		taskName:  taskName,
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// circuitBreaker counts consecutive failures of a task and opens once they
+// reach the threshold
+type circuitBreaker struct {
+	taskName  string
+	threshold int
+	cooldown  time.Duration
+
+	mu          sync.Mutex
+	failures    int
+	lastFailure time.Time
+	open        bool
+	openUntil   time.Time
+	probing     bool
+}
+
+// allow returns true if the task can be started, probe is true if it is
+// the probe of an open circuit. Otherwise it returns how long to wait before
+// trying again.
+func (b *circuitBreaker) allow(now time.Time) (probe bool, wait time.Duration, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.open {
+		return false, 0, true
+	}
+	if now.Before(b.openUntil) {
+		return false, b.openUntil.Sub(now), false
+	}
+	if b.probing {
+		return false, b.cooldown, false
+	}
+	b.probing = true
+	return true, 0, true
+}
+
+// record counts the outcome of a task started after allow returned true
+func (b *circuitBreaker) record(probe, failed bool, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if probe {
+		b.probing = false
+		if failed {
+			b.openUntil = now.Add(b.cooldown)
+			log.WARNING.Printf("Circuit breaker of task %s probe failed, keeping it open for %s", b.taskName, b.cooldown)
+			return
+		}
+		b.open = false
+		b.failures = 0
+		log.INFO.Printf("Circuit breaker of task %s probe succeeded, closing it", b.taskName)
+		return
+	}
+
+	// Tasks started before the circuit opened do not change it
+	if b.open {
+		return
+	}
+	if !failed {
+		b.failures = 0
+		return
+	}
+
+	if now.Sub(b.lastFailure) > b.cooldown {
+		b.failures = 0
+	}
+	b.failures++
+	b.lastFailure = now
+	if b.failures >= b.threshold {
+		b.open = true
+		b.openUntil = now.Add(b.cooldown)
+		log.WARNING.Printf("Task %s failed %d times in a row, opening its circuit breaker for %s", b.taskName, b.failures, b.cooldown)
+	}
+}
+
+// cancelProbe lets another task probe the circuit when the probe was not
+// started after all, e.g. because it was a duplicate
+func (b *circuitBreaker) cancelProbe() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
@@ -0,0 +1,78 @@
+package machinery
+
+import (
+	"time"
+)
+
+// SetTaskConcurrency limits how many instances of the task workers of this
+// server run at the same time, a max value of 0 removes the limit. Tasks
+// received while the limit is reached are sent back to the queue, or waited
+// for if TaskConcurrencyWait is set. The limit must be set before launching
+// workers.
+func (server *Server) SetTaskConcurrency(taskName string, max int) {
+	if max <= 0 {
+		delete(server.concurrencyLimits, taskName)
+		return
+	}
+	if server.concurrencyLimits == nil {
+		server.concurrencyLimits = make(map[string]chan struct{})
+	}
+	server.concurrencyLimits[taskName] = make(chan struct{}, max)
+}
+
+// acquireTaskSlot takes a slot of the task's concurrency limit, if any, and
+// returns a function releasing it. When no slot is free it returns false
+// unless TaskConcurrencyWait is set, in which case it blocks until one is.
+func (server *Server) acquireTaskSlot(taskName string) (func(), bool) {
+	slots, ok := server.concurrencyLimits[taskName]
+	if !ok {
+		return func() {}, true
+	}
+
+	release := func() { <-slots }
+
+	if server.config.TaskConcurrencyWait {
+		slots <- struct{}{}
+		return release, true
+	}
+
+	select {
+	case slots <- struct{}{}:
+		return release, true
+	default:
+		return nil, false
+	}
+}
+
+// acquireMemory reserves the estimated memory of a task within the
+// MemoryBudgetBytes and returns a function releasing it. It returns false if
+// the estimate does not fit next to the tasks already running, a task
+// estimated above the whole budget still runs once no other task does.
+func (server *Server) acquireMemory(estimate int) (func(), bool) {
+	budget := server.config.MemoryBudgetBytes
+	if budget <= 0 || estimate <= 0 {
+		return func() {}, true
+	}
+
+	server.memoryMu.Lock()
+	defer server.memoryMu.Unlock()
+	if server.memoryInFlight > 0 && server.memoryInFlight+estimate > budget {
+		return nil, false
+	}
+	server.memoryInFlight += estimate
+
+	return func() {
+		server.memoryMu.Lock()
+		defer server.memoryMu.Unlock()
+		server.memoryInFlight -= estimate
+	}, true
+}
+
+// taskConcurrencyRequeueDelay returns how long to delay tasks sent back to
+// the queue because their concurrency limit was reached
+func (server *Server) taskConcurrencyRequeueDelay() time.Duration {
+	if delay := server.config.TaskConcurrencyRequeueDelay; delay > 0 {
+		return time.Duration(delay) * time.Millisecond
+	}
+	return time.Second
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"crypto/tls"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -40,14 +42,245 @@ type Config struct {
 	AMQP            *AMQPConfig `yaml:"amqp"`
 	SQS             *SQSConfig  `yaml:"sqs"`
 	TLSConfig       *tls.Config
+	// TLS is used to build TLSConfig when it is not set
+	TLS *TLSFilesConfig `yaml:"tls"`
 	//NoUnixSignals when set disables signal handling in machinery
 	NoUnixSignals bool            `yaml:"no_unix_signals" envconfig:"NO_UNIX_SIGNALS"`
 	DynamoDB      *DynamoDBConfig `yaml:"dynamodb"`
+	// MaxReconnectAttempts limits how many times workers try to reconnect to
+	// the broker before giving up, 0 means no limit
+	MaxReconnectAttempts int `yaml:"max_reconnect_attempts" envconfig:"MAX_RECONNECT_ATTEMPTS"`
+	// DeadLetterQueue when set receives tasks which failed and will not be retried
+	DeadLetterQueue string `yaml:"dead_letter_queue" envconfig:"DEAD_LETTER_QUEUE"`
+	// ContentType selects serializer used to encode published tasks,
+	// defaults to application/json
+	ContentType string `yaml:"content_type" envconfig:"CONTENT_TYPE"`
+	// EnableDeduplication when set makes workers skip tasks which have already
+	// been received within DedupWindow seconds (defaults to 3600)
+	EnableDeduplication bool `yaml:"enable_deduplication" envconfig:"ENABLE_DEDUPLICATION"`
+	DedupWindow         int  `yaml:"dedup_window" envconfig:"DEDUP_WINDOW"`
+	// AckLate when set makes workers requeue messages which could not be
+	// processed instead of acking them, currently only supported by AMQP
+	AckLate bool `yaml:"ack_late" envconfig:"ACK_LATE"`
+	// MaxRedeliveries when greater than zero limits how many times messages
+	// which could not be processed, or were not acked because a worker
+	// crashed, are redelivered, they are sent to the DeadLetterQueue
+	// afterwards, currently only supported by AMQP
+	MaxRedeliveries int `yaml:"max_redeliveries" envconfig:"MAX_REDELIVERIES"`
+	// StructuredErrorCallbacks when set passes tasks.TaskError instead of the
+	// error message as the first argument to error callbacks
+	StructuredErrorCallbacks bool `yaml:"structured_error_callbacks" envconfig:"STRUCTURED_ERROR_CALLBACKS"`
+	// TaskConcurrencyRequeueDelay is how many milliseconds tasks are delayed
+	// when sent back to the queue because their concurrency limit or the
+	// MemoryBudgetBytes was reached (defaults to 1000), TaskConcurrencyWait
+	// when set makes workers wait for a free slot of the concurrency limit
+	// instead
+	TaskConcurrencyRequeueDelay int  `yaml:"task_concurrency_requeue_delay" envconfig:"TASK_CONCURRENCY_REQUEUE_DELAY"`
+	TaskConcurrencyWait         bool `yaml:"task_concurrency_wait" envconfig:"TASK_CONCURRENCY_WAIT"`
+	// MemoryBudgetBytes when greater than zero limits the total
+	// EstimatedMemoryBytes of tasks running at the same time on workers of
+	// the server, tasks which do not fit are sent back to the queue
+	MemoryBudgetBytes int `yaml:"memory_budget_bytes" envconfig:"MEMORY_BUDGET_BYTES"`
+	// MaxTasksPerWorker when greater than zero stops workers once they have
+	// processed that many tasks, so they can be restarted fresh
+	MaxTasksPerWorker int `yaml:"max_tasks_per_worker" envconfig:"MAX_TASKS_PER_WORKER"`
+	// ShutdownGracePeriod when greater than zero bounds how many seconds
+	// stopping workers waits for tasks being processed to finish, tasks still
+	// running afterwards are abandoned
+	ShutdownGracePeriod int `yaml:"shutdown_grace_period" envconfig:"SHUTDOWN_GRACE_PERIOD"`
+	// HeartbeatTimeout when greater than zero makes the worker health check
+	// report a worker which is processing tasks but has not started or
+	// finished any of them within that many seconds as not alive
+	HeartbeatTimeout int `yaml:"heartbeat_timeout" envconfig:"HEARTBEAT_TIMEOUT"`
+	// WorkerHeartbeatInterval when greater than zero makes workers record a
+	// heartbeat in the result backend every that many seconds, so they can
+	// be listed with Server.ActiveWorkers, heartbeats expire after three
+	// intervals
+	WorkerHeartbeatInterval int `yaml:"worker_heartbeat_interval" envconfig:"WORKER_HEARTBEAT_INTERVAL"`
+	// IdleTimeout when greater than zero stops workers once they have not
+	// received any task for that many seconds, see Worker.IdleHandler
+	IdleTimeout int `yaml:"idle_timeout" envconfig:"IDLE_TIMEOUT"`
+	// RetryJitter is the fraction by which retry delays are randomly spread
+	// out in either direction, e.g. 0.2 retries tasks within 20% of their
+	// RetryTimeout, 0 disables jitter
+	RetryJitter float64 `yaml:"retry_jitter" envconfig:"RETRY_JITTER"`
+	// OnUnknownTask selects what workers do with tasks they have not
+	// registered, one of OnUnknownTaskDrop, OnUnknownTaskRequeue or
+	// OnUnknownTaskDeadLetter, by default each broker sends them back to
+	// the queue in its own way
+	OnUnknownTask string `yaml:"on_unknown_task" envconfig:"ON_UNKNOWN_TASK"`
+	// CompressPayloads when set gzips published messages of at least
+	// CompressThreshold bytes (defaults to 1024), workers decompress them
+	// either way, not supported by SQS
+	CompressPayloads  bool `yaml:"compress_payloads" envconfig:"COMPRESS_PAYLOADS"`
+	CompressThreshold int  `yaml:"compress_threshold" envconfig:"COMPRESS_THRESHOLD"`
+	// EagerMode when set runs tasks inline when they are sent, using the
+	// eager broker and result backend whatever Broker and ResultBackend are
+	// set to, e.g. for debugging or tests without a broker
+	EagerMode bool `yaml:"eager_mode" envconfig:"EAGER_MODE"`
+	// SigningKey when set makes brokers sign published messages with
+	// HMAC-SHA256 and reject consumed messages which are not signed with it
+	SigningKey []byte `yaml:"-" ignored:"true"`
+	// Webhook configures the requests notifying the WebhookURL of tasks
+	Webhook *WebhookConfig `yaml:"webhook"`
+}
+
+// Actions workers take on tasks they have not registered
+const (
+	// OnUnknownTaskDrop acknowledges the message without processing the task
+	OnUnknownTaskDrop = "drop"
+	// OnUnknownTaskRequeue sends the task back to the queue after a delay
+	OnUnknownTaskRequeue = "requeue"
+	// OnUnknownTaskDeadLetter sends the task to the DeadLetterQueue
+	OnUnknownTaskDeadLetter = "deadletter"
+)
+
+// exchangeTypes are the AMQP exchange types which can be declared
+var exchangeTypes = []string{"direct", "topic", "fanout", "headers"}
+
+// Validate checks the config for misconfigurations which would otherwise only
+// surface once connecting to the broker, the returned error lists all of them
+func (cnf *Config) Validate() error {
+	var problems []string
+
+	isEager := cnf.EagerMode || strings.HasPrefix(cnf.Broker, "eager")
+	if cnf.Broker == "" && !isEager {
+		problems = append(problems, "Broker is required")
+	}
+	if cnf.DefaultQueue == "" && !isEager {
+		problems = append(problems, "DefaultQueue is required")
+	}
+
+	isAMQP := func(url string) bool {
+		return strings.HasPrefix(url, "amqp://") || strings.HasPrefix(url, "amqps://")
+	}
+	if cnf.AMQP == nil && (isAMQP(cnf.Broker) || isAMQP(cnf.ResultBackend)) {
+		problems = append(problems, "AMQP config is required with AMQP broker or result backend")
+	}
+	if cnf.AMQP != nil {
+		problems = append(problems, cnf.AMQP.validate()...)
+	}
+	if cnf.Webhook != nil {
+		problems = append(problems, cnf.Webhook.validate()...)
+	}
+
+	for name, value := range map[string]int{
+		"ResultsExpireIn":             cnf.ResultsExpireIn,
+		"MaxReconnectAttempts":        cnf.MaxReconnectAttempts,
+		"MaxRedeliveries":             cnf.MaxRedeliveries,
+		"DedupWindow":                 cnf.DedupWindow,
+		"TaskConcurrencyRequeueDelay": cnf.TaskConcurrencyRequeueDelay,
+		"MemoryBudgetBytes":           cnf.MemoryBudgetBytes,
+		"MaxTasksPerWorker":           cnf.MaxTasksPerWorker,
+		"ShutdownGracePeriod":         cnf.ShutdownGracePeriod,
+		"HeartbeatTimeout":            cnf.HeartbeatTimeout,
+		"WorkerHeartbeatInterval":     cnf.WorkerHeartbeatInterval,
+		"IdleTimeout":                 cnf.IdleTimeout,
+		"CompressThreshold":           cnf.CompressThreshold,
+	} {
+		if value < 0 {
+			problems = append(problems, fmt.Sprintf("%s must not be negative, got %d", name, value))
+		}
+	}
+
+	switch cnf.OnUnknownTask {
+	case "", OnUnknownTaskDrop, OnUnknownTaskRequeue:
+	case OnUnknownTaskDeadLetter:
+		if cnf.DeadLetterQueue == "" {
+			problems = append(problems, "DeadLetterQueue is required with OnUnknownTask deadletter")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf(
+			"OnUnknownTask must be one of %s, %s, %s, got %q",
+			OnUnknownTaskDrop, OnUnknownTaskRequeue, OnUnknownTaskDeadLetter, cnf.OnUnknownTask,
+		))
+	}
+	if cnf.RetryJitter < 0 || cnf.RetryJitter > 1 {
+		problems = append(problems, fmt.Sprintf("RetryJitter must be between 0 and 1, got %v", cnf.RetryJitter))
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	// Map iteration order is random, keep the message stable
+	sort.Strings(problems)
+	return fmt.Errorf("Invalid config: %s", strings.Join(problems, "; "))
+}
+
+// validate returns problems with the AMQP config
+func (cnf *AMQPConfig) validate() []string {
+	var problems []string
+
+	if cnf.Exchange != "" || cnf.ExchangeType != "" {
+		valid := false
+		for _, exchangeType := range exchangeTypes {
+			valid = valid || cnf.ExchangeType == exchangeType
+		}
+		if !valid {
+			problems = append(problems, fmt.Sprintf(
+				"AMQP ExchangeType must be one of %s, got %q",
+				strings.Join(exchangeTypes, ", "),
+				cnf.ExchangeType,
+			))
+		}
+	}
+	if cnf.PrefetchCount < 0 {
+		problems = append(problems, fmt.Sprintf("AMQP PrefetchCount must not be negative, got %d", cnf.PrefetchCount))
+	}
+	if cnf.PublishConfirmTimeout < 0 {
+		problems = append(problems, fmt.Sprintf("AMQP PublishConfirmTimeout must not be negative, got %d", cnf.PublishConfirmTimeout))
+	}
+	if cnf.ConnectionPoolSize < 0 {
+		problems = append(problems, fmt.Sprintf("AMQP ConnectionPoolSize must not be negative, got %d", cnf.ConnectionPoolSize))
+	}
+
+	return problems
+}
+
+// validate returns problems with the webhook config
+func (cnf *WebhookConfig) validate() []string {
+	var problems []string
+
+	for name, value := range map[string]int{
+		"Timeout":     cnf.Timeout,
+		"MaxRetries":  cnf.MaxRetries,
+		"Concurrency": cnf.Concurrency,
+	} {
+		if value < 0 {
+			problems = append(problems, fmt.Sprintf("Webhook %s must not be negative, got %d", name, value))
+		}
+	}
+
+	return problems
 }
 
 // QueueBindingArgs arguments which are used when binding to the exchange
 type QueueBindingArgs map[string]interface{}
 
+// QueueDeclareArgs arguments which are used when declaring task queues
+type QueueDeclareArgs map[string]interface{}
+
+// ExchangeDeclareArgs arguments which are used when declaring the exchange
+type ExchangeDeclareArgs map[string]interface{}
+
+// WebhookConfig configures the HTTP requests posted to the WebhookURL of
+// tasks once they complete
+type WebhookConfig struct {
+	// Timeout is how many seconds each request may take (defaults to 10)
+	Timeout int `yaml:"timeout" envconfig:"WEBHOOK_TIMEOUT"`
+	// MaxRetries is how many times failed requests are retried with Fibonacci
+	// backoff in seconds (defaults to 3)
+	MaxRetries int `yaml:"max_retries" envconfig:"WEBHOOK_MAX_RETRIES"`
+	// Concurrency is how many requests are sent at the same time (defaults
+	// to 10)
+	Concurrency int `yaml:"concurrency" envconfig:"WEBHOOK_CONCURRENCY"`
+	// TLSConfig is used for https URLs, it is built from TLS when not set.
+	// They are not read from the environment, where the TLS variables
+	// configure the broker.
+	TLSConfig *tls.Config     `ignored:"true"`
+	TLS       *TLSFilesConfig `yaml:"tls" ignored:"true"`
+}
+
 // AMQPConfig wraps RabbitMQ related configuration
 type AMQPConfig struct {
 	Exchange         string           `yaml:"exchange" envconfig:"AMQP_EXCHANGE"`
@@ -55,6 +288,34 @@ type AMQPConfig struct {
 	QueueBindingArgs QueueBindingArgs `yaml:"queue_binding_args" envconfig:"AMQP_QUEUE_BINDING_ARGS"`
 	BindingKey       string           `yaml:"binding_key" envconfig:"AMQP_BINDING_KEY"`
 	PrefetchCount    int              `yaml:"prefetch_count" envconfig:"AMQP_PREFETCH_COUNT"`
+	// QueueArgs and ExchangeArgs are passed as arguments when declaring task
+	// queues and the exchange, e.g. x-queue-type: quorum or x-max-length
+	QueueArgs    QueueDeclareArgs    `yaml:"queue_args" envconfig:"AMQP_QUEUE_ARGS"`
+	ExchangeArgs ExchangeDeclareArgs `yaml:"exchange_args" envconfig:"AMQP_EXCHANGE_ARGS"`
+	// RequeueOnDecodeError when set requeues messages which cannot be decoded
+	// into a task signature instead of rejecting them
+	RequeueOnDecodeError bool `yaml:"requeue_on_decode_error" envconfig:"AMQP_REQUEUE_ON_DECODE_ERROR"`
+	// UseDelayedMessageExchange when set delays tasks using the RabbitMQ delayed
+	// message exchange plugin instead of per delay dead letter queues
+	UseDelayedMessageExchange bool `yaml:"use_delayed_message_exchange" envconfig:"AMQP_USE_DELAYED_MESSAGE_EXCHANGE"`
+	// MaxPriority when greater than zero declares task queues as priority
+	// queues supporting task priorities up to the value
+	MaxPriority uint8 `yaml:"max_priority" envconfig:"AMQP_MAX_PRIORITY"`
+	// PublisherConfirms when set makes publishing of delayed tasks wait for
+	// publisher confirms too and fails publishing when they do not arrive
+	// within PublishConfirmTimeout seconds (defaults to 10)
+	PublisherConfirms     bool `yaml:"publisher_confirms" envconfig:"AMQP_PUBLISHER_CONFIRMS"`
+	PublishConfirmTimeout int  `yaml:"publish_confirm_timeout" envconfig:"AMQP_PUBLISH_CONFIRM_TIMEOUT"`
+	// Transient when set declares task queues as non durable and publishes
+	// tasks as transient messages, so they are lost when the broker restarts
+	Transient bool `yaml:"transient" envconfig:"AMQP_TRANSIENT"`
+	// ConnectionPoolSize when greater than one makes workers consume over
+	// that many connections, each with its own channel and reconnecting on
+	// its own, to spread high message rates over several connections
+	ConnectionPoolSize int `yaml:"connection_pool_size" envconfig:"AMQP_CONNECTION_POOL_SIZE"`
+	// BroadcastExchange when set is the fanout exchange broadcast tasks are
+	// published to, each worker binds an exclusive queue of its own to it
+	BroadcastExchange string `yaml:"broadcast_exchange" envconfig:"AMQP_BROADCAST_EXCHANGE"`
 }
 
 // DynamoDBConfig wraps DynamoDB related configuration
@@ -65,8 +326,11 @@ type DynamoDBConfig struct {
 
 // SQSConfig wraps SQS related configuration
 type SQSConfig struct {
-	Client          *sqs.SQS
-	WaitTimeSeconds int `yaml:"receive_wait_time_seconds" envconfig:"SQS_WAIT_TIME_SECONDS"`
+	Client *sqs.SQS
+	// Region is used when Client is not provided, if empty the region is
+	// loaded from AWS_REGION env or the shared config file
+	Region          string `yaml:"region" envconfig:"SQS_REGION"`
+	WaitTimeSeconds int    `yaml:"receive_wait_time_seconds" envconfig:"SQS_WAIT_TIME_SECONDS"`
 	// https://docs.aws.amazon.com/AWSSimpleQueueService/latest/SQSDeveloperGuide/sqs-visibility-timeout.html
 	// visiblity timeout should default to nil to use the overall visibility timeout for the queue
 	VisibilityTimeout *int `yaml:"receive_visibility_timeout" envconfig:"SQS_VISIBILITY_TIMEOUT"`
@@ -87,3 +351,41 @@ func (args *QueueBindingArgs) Decode(value string) error {
 	*args = QueueBindingArgs(mp)
 	return nil
 }
+
+// Decode from env to map, integer values are decoded as int64 since RabbitMQ
+// expects numeric arguments such as x-max-length to be numbers
+func (args *QueueDeclareArgs) Decode(value string) error {
+	mp, err := decodeDeclareArgs(value)
+	if err != nil {
+		return err
+	}
+	*args = QueueDeclareArgs(mp)
+	return nil
+}
+
+// Decode from env to map, see QueueDeclareArgs.Decode
+func (args *ExchangeDeclareArgs) Decode(value string) error {
+	mp, err := decodeDeclareArgs(value)
+	if err != nil {
+		return err
+	}
+	*args = ExchangeDeclareArgs(mp)
+	return nil
+}
+
+func decodeDeclareArgs(value string) (map[string]interface{}, error) {
+	pairs := strings.Split(value, ",")
+	mp := make(map[string]interface{}, len(pairs))
+	for _, pair := range pairs {
+		kvpair := strings.Split(pair, ":")
+		if len(kvpair) != 2 {
+			return nil, fmt.Errorf("invalid map item: %q", pair)
+		}
+		if n, err := strconv.ParseInt(kvpair[1], 10, 64); err == nil {
+			mp[kvpair[0]] = n
+			continue
+		}
+		mp[kvpair[0]] = kvpair[1]
+	}
+	return mp, nil
+}
@@ -40,5 +40,8 @@ func TestNewFromEnvironment(t *testing.T) {
 	assert.Equal(t, "binding_key", cnf.AMQP.BindingKey)
 	assert.Equal(t, "any", cnf.AMQP.QueueBindingArgs["x-match"])
 	assert.Equal(t, "png", cnf.AMQP.QueueBindingArgs["image-type"])
+	assert.Equal(t, "quorum", cnf.AMQP.QueueArgs["x-queue-type"])
+	assert.Equal(t, int64(1000), cnf.AMQP.QueueArgs["x-max-length"])
+	assert.Equal(t, "unrouted", cnf.AMQP.ExchangeArgs["alternate-exchange"])
 	assert.Equal(t, 123, cnf.AMQP.PrefetchCount)
 }
@@ -22,6 +22,11 @@ amqp:
   queue_binding_args:
     image-type: png
     x-match: any
+  queue_args:
+    x-max-length: 1000
+    x-queue-type: quorum
+  exchange_args:
+    alternate-exchange: unrouted
 `
 
 func TestReadFromFile(t *testing.T) {
@@ -57,5 +62,8 @@ func TestNewFromYaml(t *testing.T) {
 	assert.Equal(t, "binding_key", cnf.AMQP.BindingKey)
 	assert.Equal(t, "any", cnf.AMQP.QueueBindingArgs["x-match"])
 	assert.Equal(t, "png", cnf.AMQP.QueueBindingArgs["image-type"])
+	assert.Equal(t, "quorum", cnf.AMQP.QueueArgs["x-queue-type"])
+	assert.Equal(t, 1000, cnf.AMQP.QueueArgs["x-max-length"])
+	assert.Equal(t, "unrouted", cnf.AMQP.ExchangeArgs["alternate-exchange"])
 	assert.Equal(t, 123, cnf.AMQP.PrefetchCount)
 }
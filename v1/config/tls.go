@@ -0,0 +1,52 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+// TLSFilesConfig wraps paths to PEM encoded files which TLSConfig can be
+// built from, for those who would rather not build tls.Config themselves
+type TLSFilesConfig struct {
+	// CAFile is used to verify the server certificate instead of system CAs
+	CAFile string `yaml:"ca_file" envconfig:"TLS_CA_FILE"`
+	// CertFile and KeyFile are a client certificate and its private key
+	CertFile string `yaml:"cert_file" envconfig:"TLS_CERT_FILE"`
+	KeyFile  string `yaml:"key_file" envconfig:"TLS_KEY_FILE"`
+}
+
+// TLSConfig builds tls.Config from the files, nil is returned when no files are set
+func (c *TLSFilesConfig) TLSConfig() (*tls.Config, error) {
+	if *c == (TLSFilesConfig{}) {
+		return nil, nil
+	}
+
+	tlsConfig := new(tls.Config)
+
+	if c.CAFile != "" {
+		caCert, err := ioutil.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("Read CA file error: %s", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("No certificates found in CA file %s", c.CAFile)
+		}
+	}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		if c.CertFile == "" || c.KeyFile == "" {
+			return nil, errors.New("Both cert file and key file are required for client certificate")
+		}
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Load client certificate error: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
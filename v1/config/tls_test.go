@@ -0,0 +1,136 @@
+package config_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/machinery/v1/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTLSFilesConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "machinery-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile, keyFile := writeSelfSignedCert(t, dir)
+
+	t.Run("no files", func(t *testing.T) {
+		tlsConfig, err := new(config.TLSFilesConfig).TLSConfig()
+		assert.NoError(t, err)
+		assert.Nil(t, tlsConfig)
+	})
+
+	t.Run("CA and client certificate", func(t *testing.T) {
+		tlsConfig, err := (&config.TLSFilesConfig{
+			CAFile:   certFile,
+			CertFile: certFile,
+			KeyFile:  keyFile,
+		}).TLSConfig()
+		if assert.NoError(t, err) {
+			assert.NotNil(t, tlsConfig.RootCAs)
+			assert.Len(t, tlsConfig.Certificates, 1)
+		}
+	})
+
+	t.Run("handshake with client certificate", func(t *testing.T) {
+		tlsConfig, err := (&config.TLSFilesConfig{
+			CAFile:   certFile,
+			CertFile: certFile,
+			KeyFile:  keyFile,
+		}).TLSConfig()
+		if !assert.NoError(t, err) {
+			return
+		}
+		tlsConfig.ServerName = "localhost"
+
+		// The server trusts the same self-signed certificate as CA
+		listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+			Certificates: tlsConfig.Certificates,
+			ClientCAs:    tlsConfig.RootCAs,
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			conn.(*tls.Conn).Handshake()
+		}()
+
+		conn, err := tls.Dial("tcp", listener.Addr().String(), tlsConfig)
+		if assert.NoError(t, err) {
+			assert.NoError(t, conn.Handshake())
+			conn.Close()
+		}
+	})
+
+	t.Run("missing key file", func(t *testing.T) {
+		_, err := (&config.TLSFilesConfig{CertFile: certFile}).TLSConfig()
+		assert.EqualError(t, err, "Both cert file and key file are required for client certificate")
+	})
+
+	t.Run("invalid CA file", func(t *testing.T) {
+		_, err := (&config.TLSFilesConfig{CAFile: keyFile}).TLSConfig()
+		assert.EqualError(t, err, "No certificates found in CA file "+keyFile)
+	})
+}
+
+func writeSelfSignedCert(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:              []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	certPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	if err := ioutil.WriteFile(certFile, certPem, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, keyPem, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return certFile, keyFile
+}
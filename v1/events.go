@@ -0,0 +1,129 @@
+package machinery
+
+import (
+	"sync"
+	"time"
+
+	"github.com/RichardKnop/machinery/v1/tasks"
+)
+
+// eventsBufferSize is how many events are buffered for each subscriber,
+// further events are dropped until the subscriber catches up
+const eventsBufferSize = 100
+
+// TaskEvent is emitted by workers of the server whenever a task they process
+// changes state
+type TaskEvent struct {
+	TaskUUID string
+	TaskName string
+	// State is the new state of the task, e.g. tasks.StateStarted, or
+	// EventUnknownTask
+	State string
+	// Action is what the worker did with an unknown task as configured by
+	// OnUnknownTask, e.g. config.OnUnknownTaskRequeue, empty for other events
+	Action string
+	// Error is why a connection or channel was closed for connection events,
+	// empty for other events
+	Error string
+	// Attempt counts attempts to connect again since the connection failed or
+	// was lost for EventReconnectAttempt, zero for other events
+	Attempt int
+	Time    time.Time
+}
+
+// EventUnknownTask is the state of events emitted when a worker receives a
+// task it has not registered and OnUnknownTask is set, the task state in the
+// result backend is left alone
+const EventUnknownTask = "UNKNOWN"
+
+// States of connection events, emitted when workers open or close a
+// connection to the broker, try to connect again or when the broker closes
+// a channel because of an error. Their TaskUUID and TaskName are empty.
+const (
+	EventConnectionOpened = "CONNECTION_OPENED"
+	EventConnectionClosed = "CONNECTION_CLOSED"
+	EventReconnectAttempt = "RECONNECT_ATTEMPT"
+	EventChannelError     = "CHANNEL_ERROR"
+)
+
+// eventBus fans out task events to all subscribers
+type eventBus struct {
+	mu          sync.Mutex
+	subscribers []chan TaskEvent
+}
+
+// Events returns a new channel receiving task events emitted by workers of
+// the server. Workers never wait for subscribers, events are dropped while
+// the channel buffer is full. Call StopEvents once no longer interested.
+func (server *Server) Events() <-chan TaskEvent {
+	events := make(chan TaskEvent, eventsBufferSize)
+
+	server.events.mu.Lock()
+	defer server.events.mu.Unlock()
+	server.events.subscribers = append(server.events.subscribers, events)
+
+	return events
+}
+
+// StopEvents stops sending task events to the channel returned by Events and
+// closes it
+func (server *Server) StopEvents(events <-chan TaskEvent) {
+	server.events.mu.Lock()
+	defer server.events.mu.Unlock()
+
+	for i, subscriber := range server.events.subscribers {
+		if subscriber == events {
+			server.events.subscribers = append(server.events.subscribers[:i], server.events.subscribers[i+1:]...)
+			close(subscriber)
+			return
+		}
+	}
+}
+
+// emitEvent sends the task event to all subscribers which are not lagging
+// behind
+func (server *Server) emitEvent(signature *tasks.Signature, state string) {
+	server.publishEvent(TaskEvent{
+		TaskUUID: signature.UUID,
+		TaskName: signature.Name,
+		State:    state,
+	})
+}
+
+// emitUnknownTaskEvent reports the action taken on a task which is not
+// registered
+func (server *Server) emitUnknownTaskEvent(signature *tasks.Signature, action string) {
+	server.publishEvent(TaskEvent{
+		TaskUUID: signature.UUID,
+		TaskName: signature.Name,
+		State:    EventUnknownTask,
+		Action:   action,
+	})
+}
+
+// emitConnectionEvent reports a change of a connection to the broker
+func (server *Server) emitConnectionEvent(state string, err error, attempt int) {
+	event := TaskEvent{State: state, Attempt: attempt}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	server.publishEvent(event)
+}
+
+// publishEvent timestamps the event and sends it to all subscribers
+func (server *Server) publishEvent(event TaskEvent) {
+	server.events.mu.Lock()
+	defer server.events.mu.Unlock()
+
+	if len(server.events.subscribers) == 0 {
+		return
+	}
+
+	event.Time = time.Now().UTC()
+	for _, subscriber := range server.events.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package machinery
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/RichardKnop/machinery/v1/brokers"
+)
+
+// ServeHealth starts an HTTP server on the address reporting health of the
+// worker, e.g. for Kubernetes probes. It blocks like http.ListenAndServe, so
+// run it in a goroutine next to the worker. See HealthHandler for the
+// endpoints served.
+func (worker *Worker) ServeHealth(addr string) error {
+	return http.ListenAndServe(addr, worker.HealthHandler())
+}
+
+// HealthHandler returns a handler serving health of the worker:
+//
+// /healthz reports liveness, the worker is alive while it keeps consuming
+// (or reconnecting to) the broker and, with HeartbeatTimeout configured,
+// while tasks it processes keep starting or finishing
+//
+// /readyz reports readiness, the worker is ready while it is alive and
+// connected to the broker
+//
+// Both respond with 200 OK when healthy or 503 Service Unavailable otherwise.
+func (worker *Worker) HealthHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, worker.checkLiveness())
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, worker.checkReadiness())
+	})
+	return mux
+}
+
+// checkLiveness returns an error if the worker stopped consuming or seems to
+// be stuck processing tasks
+func (worker *Worker) checkLiveness() error {
+	if atomic.LoadInt32(&worker.running) == 0 {
+		return errors.New("Worker is not running")
+	}
+
+	timeout := time.Duration(worker.server.GetConfig().HeartbeatTimeout) * time.Second
+	if timeout <= 0 || atomic.LoadInt64(&worker.activeTasks) == 0 {
+		return nil
+	}
+	lastHeartbeat := time.Unix(0, atomic.LoadInt64(&worker.lastHeartbeat))
+	if time.Since(lastHeartbeat) > timeout {
+		return errors.New("Worker has not started or finished a task since " + lastHeartbeat.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// checkReadiness returns an error if the worker is not alive or not
+// connected to the broker
+func (worker *Worker) checkReadiness() error {
+	if err := worker.checkLiveness(); err != nil {
+		return err
+	}
+	if checker, ok := worker.server.GetBroker().(brokers.ConnectionChecker); ok && !checker.IsConnected() {
+		return errors.New("Broker is not connected")
+	}
+	return nil
+}
+
+// heartbeat records that the worker made progress processing tasks
+func (worker *Worker) heartbeat() {
+	atomic.StoreInt64(&worker.lastHeartbeat, time.Now().UnixNano())
+}
+
+func writeHealth(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(err.Error() + "\n"))
+		return
+	}
+	w.Write([]byte("OK\n"))
+}
@@ -0,0 +1,56 @@
+package machinery
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/RichardKnop/machinery/v1/backends"
+	"github.com/RichardKnop/machinery/v1/log"
+)
+
+// ActiveWorkers returns info of workers recording heartbeats in the result
+// backend, see WorkerHeartbeatInterval. Workers which stopped recording them
+// disappear from the list once their last heartbeat expires.
+func (server *Server) ActiveWorkers() ([]backends.WorkerInfo, error) {
+	registry, ok := server.GetBackend().(backends.WorkerRegistry)
+	if !ok {
+		return nil, errors.New("Result backend does not support tracking workers")
+	}
+	return registry.ActiveWorkers()
+}
+
+// recordHeartbeats records a heartbeat of the worker in the result backend
+// every WorkerHeartbeatInterval until stop is closed
+func (worker *Worker) recordHeartbeats(registry backends.WorkerRegistry, stop <-chan struct{}) {
+	interval := time.Duration(worker.server.GetConfig().WorkerHeartbeatInterval) * time.Second
+	ttl := 3 * interval
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := registry.RecordWorkerHeartbeat(worker.info(), ttl); err != nil {
+			log.WARNING.Printf("Record worker heartbeat error: %s", err)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// info returns info of the worker as of now
+func (worker *Worker) info() backends.WorkerInfo {
+	hostname, _ := os.Hostname()
+	return backends.WorkerInfo{
+		ID:          fmt.Sprintf("%s:%d:%s", hostname, os.Getpid(), worker.ConsumerTag),
+		ConsumerTag: worker.ConsumerTag,
+		Hostname:    hostname,
+		ActiveTasks: int(atomic.LoadInt64(&worker.activeTasks)),
+		LastSeen:    time.Now().UTC(),
+	}
+}
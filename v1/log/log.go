@@ -1,6 +1,10 @@
 package log
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
 	"github.com/RichardKnop/logging"
 )
 
@@ -15,12 +19,73 @@ var (
 	ERROR = logger[logging.ERROR]
 	// FATAL ...
 	FATAL = logger[logging.FATAL]
+
+	structured StructuredLogger
+)
+
+const (
+	// LevelInfo ...
+	LevelInfo = "INFO"
+	// LevelWarning ...
+	LevelWarning = "WARNING"
+	// LevelError ...
+	LevelError = "ERROR"
 )
 
-// Set sets a custom logger
+// Fields are the key value pairs carried by a structured log event
+type Fields map[string]interface{}
+
+// StructuredLogger is implemented by loggers which log events as a message
+// with fields, e.g. adapters for JSON logging pipelines which index the fields
+type StructuredLogger interface {
+	Event(level, message string, fields Fields)
+}
+
+// Set sets a custom logger, it is used for structured events as well if it
+// implements StructuredLogger
 func Set(l logging.LoggerInterface) {
 	INFO = l
 	WARNING = l
 	ERROR = l
 	FATAL = l
+	structured, _ = l.(StructuredLogger)
+}
+
+// SetStructured sets a custom logger for structured events, nil restores the
+// human readable default
+func SetStructured(l StructuredLogger) {
+	structured = l
+}
+
+// Event logs a single event with fields, through the structured logger if one
+// is set. Otherwise it is logged as the message followed by the fields in
+// alphabetical order, one per line.
+func Event(level, message string, fields Fields) {
+	if structured != nil {
+		structured.Event(level, message, fields)
+		return
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	lines := make([]string, 0, len(keys)+1)
+	lines = append(lines, message)
+	for _, key := range keys {
+		lines = append(lines, fmt.Sprintf("- %s: %v", key, fields[key]))
+	}
+
+	var l logging.LoggerInterface
+	switch level {
+	case LevelWarning:
+		l = WARNING
+	case LevelError:
+		l = ERROR
+	default:
+		l = INFO
+	}
+	l.Print(strings.Join(lines, "\n"))
 }
@@ -1,9 +1,12 @@
 package log_test
 
 import (
+	"fmt"
 	"testing"
 
+	"github.com/RichardKnop/logging"
 	"github.com/RichardKnop/machinery/v1/log"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestDefaultLogger(t *testing.T) {
@@ -12,3 +15,47 @@ func TestDefaultLogger(t *testing.T) {
 	log.ERROR.Print("should not panic")
 	log.FATAL.Print("should not panic")
 }
+
+type recordingLogger struct {
+	logging.LoggerInterface
+	printed []string
+	events  []string
+	fields  []log.Fields
+}
+
+func (l *recordingLogger) Print(args ...interface{}) {
+	l.printed = append(l.printed, fmt.Sprint(args...))
+}
+
+func (l *recordingLogger) Event(level, message string, fields log.Fields) {
+	l.events = append(l.events, level+" "+message)
+	l.fields = append(l.fields, fields)
+}
+
+func TestEvent(t *testing.T) {
+	info, warning, errorLogger, fatal := log.INFO, log.WARNING, log.ERROR, log.FATAL
+	defer func() {
+		log.Set(info)
+		log.WARNING, log.ERROR, log.FATAL = warning, errorLogger, fatal
+	}()
+
+	// Fields are logged one per line by default
+	l := new(recordingLogger)
+	plain := struct{ logging.LoggerInterface }{l}
+	log.Set(plain)
+	log.Event(log.LevelInfo, "Launching a worker:", log.Fields{"concurrency": 10, "broker": "redis://"})
+	assert.Equal(t, []string{"Launching a worker:\n- broker: redis://\n- concurrency: 10"}, l.printed)
+	assert.Empty(t, l.events)
+
+	// Loggers implementing StructuredLogger get the fields as they are
+	log.Set(l)
+	log.Event(log.LevelWarning, "Launching a worker:", log.Fields{"concurrency": 10})
+	assert.Equal(t, []string{"WARNING Launching a worker:"}, l.events)
+	assert.Equal(t, []log.Fields{{"concurrency": 10}}, l.fields)
+	assert.Len(t, l.printed, 1)
+
+	log.SetStructured(nil)
+	log.Event(log.LevelInfo, "Launching a worker:", nil)
+	assert.Len(t, l.events, 1)
+	assert.Equal(t, "Launching a worker:", l.printed[1])
+}
@@ -0,0 +1,43 @@
+package machinery
+
+import (
+	"time"
+)
+
+// Metrics receives notifications about tasks processed by workers, it can be
+// used to export metrics (e.g. to Prometheus or StatsD)
+type Metrics interface {
+	// TaskStarted is called right before the task function is called
+	TaskStarted(name string)
+	// TaskSucceeded is called after the task function returned without error
+	TaskSucceeded(name string, duration time.Duration)
+	// TaskFailed is called after the task function returned an error, including
+	// errors which cause the task to be retried
+	TaskFailed(name string, duration time.Duration)
+	// TaskExpired is called when the task is dropped without being processed
+	// because it was received after its expiration time
+	TaskExpired(name string)
+}
+
+// ConnectionMetrics is optionally implemented by Metrics set on the server to
+// be notified about connections workers of the server consume over, driven
+// by the brokers reporting them (currently AMQP)
+type ConnectionMetrics interface {
+	// BrokerConnections is called with how many connections to the broker
+	// are open each time one is opened or closed
+	BrokerConnections(open int)
+	// BrokerReconnectAttempt is called before each attempt to connect again
+	// after a connection failed or was lost
+	BrokerReconnectAttempt()
+	// BrokerChannelError is called when the broker closes a channel of an
+	// open connection because of an error
+	BrokerChannelError()
+}
+
+// noopMetrics is used when no metrics are set on the server
+type noopMetrics struct{}
+
+func (noopMetrics) TaskStarted(name string)                           {}
+func (noopMetrics) TaskExpired(name string)                           {}
+func (noopMetrics) TaskSucceeded(name string, duration time.Duration) {}
+func (noopMetrics) TaskFailed(name string, duration time.Duration)    {}
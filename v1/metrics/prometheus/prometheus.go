@@ -0,0 +1,129 @@
+package prometheus
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics exports tasks processed by workers and their broker connections to
+// Prometheus, it implements machinery.Metrics and machinery.ConnectionMetrics
+type Metrics struct {
+	tasksStarted      *prom.CounterVec
+	tasksSucceeded    *prom.CounterVec
+	tasksFailed       *prom.CounterVec
+	tasksExpired      *prom.CounterVec
+	taskDuration      *prom.HistogramVec
+	brokerConnections prom.Gauge
+	reconnectAttempts prom.Counter
+	channelErrors     prom.Counter
+}
+
+// New returns metrics registered with the registerer, e.g.
+// prometheus.DefaultRegisterer, their names are prefixed with the namespace
+// (e.g. "machinery"). Task metrics are labeled by task name.
+func New(namespace string, registerer prom.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		tasksStarted: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: namespace,
+			Name:      "tasks_started_total",
+			Help:      "Number of task function calls started.",
+		}, []string{"task"}),
+		tasksSucceeded: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: namespace,
+			Name:      "tasks_succeeded_total",
+			Help:      "Number of task function calls which returned without error.",
+		}, []string{"task"}),
+		tasksFailed: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: namespace,
+			Name:      "tasks_failed_total",
+			Help:      "Number of task function calls which returned an error, including ones retried.",
+		}, []string{"task"}),
+		tasksExpired: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: namespace,
+			Name:      "tasks_expired_total",
+			Help:      "Number of tasks dropped because they were received after their expiration time.",
+		}, []string{"task"}),
+		taskDuration: prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace: namespace,
+			Name:      "task_duration_seconds",
+			Help:      "Duration of task function calls in seconds.",
+			Buckets:   prom.DefBuckets,
+		}, []string{"task", "success"}),
+		brokerConnections: prom.NewGauge(prom.GaugeOpts{
+			Namespace: namespace,
+			Name:      "broker_connections",
+			Help:      "Number of open connections workers consume over.",
+		}),
+		reconnectAttempts: prom.NewCounter(prom.CounterOpts{
+			Namespace: namespace,
+			Name:      "broker_reconnect_attempts_total",
+			Help:      "Number of attempts to connect to the broker again after a connection failed or was lost.",
+		}),
+		channelErrors: prom.NewCounter(prom.CounterOpts{
+			Namespace: namespace,
+			Name:      "broker_channel_errors_total",
+			Help:      "Number of channels of open connections closed by the broker because of an error.",
+		}),
+	}
+
+	for _, collector := range []prom.Collector{
+		m.tasksStarted,
+		m.tasksSucceeded,
+		m.tasksFailed,
+		m.tasksExpired,
+		m.taskDuration,
+		m.brokerConnections,
+		m.reconnectAttempts,
+		m.channelErrors,
+	} {
+		if err := registerer.Register(collector); err != nil {
+			return nil, fmt.Errorf("Register metrics error: %s", err)
+		}
+	}
+
+	return m, nil
+}
+
+// TaskStarted counts the task as started
+func (m *Metrics) TaskStarted(name string) {
+	m.tasksStarted.WithLabelValues(name).Inc()
+}
+
+// TaskSucceeded counts the task as succeeded and observes its duration
+func (m *Metrics) TaskSucceeded(name string, duration time.Duration) {
+	m.tasksSucceeded.WithLabelValues(name).Inc()
+	m.observeDuration(name, true, duration)
+}
+
+// TaskFailed counts the task as failed and observes its duration
+func (m *Metrics) TaskFailed(name string, duration time.Duration) {
+	m.tasksFailed.WithLabelValues(name).Inc()
+	m.observeDuration(name, false, duration)
+}
+
+// TaskExpired counts the task as expired
+func (m *Metrics) TaskExpired(name string) {
+	m.tasksExpired.WithLabelValues(name).Inc()
+}
+
+// BrokerConnections sets the number of open connections
+func (m *Metrics) BrokerConnections(open int) {
+	m.brokerConnections.Set(float64(open))
+}
+
+// BrokerReconnectAttempt counts the attempt to connect again
+func (m *Metrics) BrokerReconnectAttempt() {
+	m.reconnectAttempts.Inc()
+}
+
+// BrokerChannelError counts the channel closed because of an error
+func (m *Metrics) BrokerChannelError() {
+	m.channelErrors.Inc()
+}
+
+func (m *Metrics) observeDuration(name string, success bool, duration time.Duration) {
+	m.taskDuration.WithLabelValues(name, strconv.FormatBool(success)).Observe(duration.Seconds())
+}
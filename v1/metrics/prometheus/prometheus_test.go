@@ -0,0 +1,139 @@
+package prometheus_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/machinery/v1"
+	"github.com/RichardKnop/machinery/v1/config"
+	"github.com/RichardKnop/machinery/v1/metrics/prometheus"
+	"github.com/RichardKnop/machinery/v1/tasks"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	_ machinery.Metrics           = (*prometheus.Metrics)(nil)
+	_ machinery.ConnectionMetrics = (*prometheus.Metrics)(nil)
+)
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	registry := prom.NewRegistry()
+	metrics, err := prometheus.New("machinery", registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metrics.TaskStarted("add")
+	metrics.TaskSucceeded("add", 100*time.Millisecond)
+	metrics.TaskStarted("add")
+	metrics.TaskFailed("add", 2*time.Second)
+	metrics.TaskExpired("multiply")
+	metrics.BrokerConnections(2)
+	metrics.BrokerReconnectAttempt()
+	metrics.BrokerChannelError()
+
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP machinery_tasks_started_total Number of task function calls started.
+# TYPE machinery_tasks_started_total counter
+machinery_tasks_started_total{task="add"} 2
+# HELP machinery_tasks_succeeded_total Number of task function calls which returned without error.
+# TYPE machinery_tasks_succeeded_total counter
+machinery_tasks_succeeded_total{task="add"} 1
+# HELP machinery_tasks_failed_total Number of task function calls which returned an error, including ones retried.
+# TYPE machinery_tasks_failed_total counter
+machinery_tasks_failed_total{task="add"} 1
+# HELP machinery_tasks_expired_total Number of tasks dropped because they were received after their expiration time.
+# TYPE machinery_tasks_expired_total counter
+machinery_tasks_expired_total{task="multiply"} 1
+# HELP machinery_broker_connections Number of open connections workers consume over.
+# TYPE machinery_broker_connections gauge
+machinery_broker_connections 2
+# HELP machinery_broker_reconnect_attempts_total Number of attempts to connect to the broker again after a connection failed or was lost.
+# TYPE machinery_broker_reconnect_attempts_total counter
+machinery_broker_reconnect_attempts_total 1
+# HELP machinery_broker_channel_errors_total Number of channels of open connections closed by the broker because of an error.
+# TYPE machinery_broker_channel_errors_total counter
+machinery_broker_channel_errors_total 1
+`),
+		"machinery_tasks_started_total",
+		"machinery_tasks_succeeded_total",
+		"machinery_tasks_failed_total",
+		"machinery_tasks_expired_total",
+		"machinery_broker_connections",
+		"machinery_broker_reconnect_attempts_total",
+		"machinery_broker_channel_errors_total",
+	))
+
+	// Durations are observed in seconds, labeled by whether the call succeeded
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	durations := make(map[string]float64)
+	for _, family := range families {
+		if family.GetName() != "machinery_task_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := make([]string, 0, len(metric.GetLabel()))
+			for _, label := range metric.GetLabel() {
+				labels = append(labels, label.GetName()+"="+label.GetValue())
+			}
+			durations[strings.Join(labels, ",")] = metric.GetHistogram().GetSampleSum()
+		}
+	}
+	assert.Equal(t, map[string]float64{
+		"success=true,task=add":  0.1,
+		"success=false,task=add": 2,
+	}, durations)
+}
+
+func TestMetricsRegisteredTwice(t *testing.T) {
+	t.Parallel()
+
+	registry := prom.NewRegistry()
+	_, err := prometheus.New("machinery", registry)
+	assert.NoError(t, err)
+
+	_, err = prometheus.New("machinery", registry)
+	if assert.Error(t, err) {
+		assert.True(t, strings.HasPrefix(err.Error(), "Register metrics error: "))
+	}
+}
+
+func TestMetricsProcessedTask(t *testing.T) {
+	t.Parallel()
+
+	server, err := machinery.NewServer(&config.Config{
+		Broker:        "eager",
+		ResultBackend: "eager",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, server.RegisterTask("add", func(a, b int64) (int64, error) { return a + b, nil }))
+
+	registry := prom.NewRegistry()
+	metrics, err := prometheus.New("machinery", registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.SetMetrics(metrics)
+
+	_, err = server.SendTask(&tasks.Signature{
+		Name: "add",
+		Args: []tasks.Arg{{Type: "int64", Value: 1}, {Type: "int64", Value: 2}},
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP machinery_tasks_succeeded_total Number of task function calls which returned without error.
+# TYPE machinery_tasks_succeeded_total counter
+machinery_tasks_succeeded_total{task="add"} 1
+`), "machinery_tasks_succeeded_total"))
+}
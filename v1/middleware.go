@@ -0,0 +1,30 @@
+package machinery
+
+import (
+	"context"
+
+	"github.com/RichardKnop/machinery/v1/tasks"
+)
+
+// TaskHandlerFunc executes the task described by the signature, ctx is passed
+// to the task function if it accepts context.Context as the first argument
+type TaskHandlerFunc func(ctx context.Context, signature *tasks.Signature) ([]*tasks.TaskResult, error)
+
+// TaskMiddleware wraps execution of tasks processed by workers. It can change
+// the context or signature args before calling next, or skip the task by
+// returning without calling next
+type TaskMiddleware func(next TaskHandlerFunc) TaskHandlerFunc
+
+// Use adds middleware wrapping execution of every task, middleware added
+// first is the outermost one
+func (server *Server) Use(middleware TaskMiddleware) {
+	server.middleware = append(server.middleware, middleware)
+}
+
+// wrapTaskHandler wraps the handler in all middleware
+func (server *Server) wrapTaskHandler(handler TaskHandlerFunc) TaskHandlerFunc {
+	for i := len(server.middleware) - 1; i >= 0; i-- {
+		handler = server.middleware[i](handler)
+	}
+	return handler
+}
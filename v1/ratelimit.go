@@ -0,0 +1,123 @@
+package machinery
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/RichardKnop/machinery/v1/backends"
+)
+
+// rateLimit limits how many times per second a task is started, either by
+// the workers of this server (using a token bucket) or by all workers
+// sharing the result backend
+type rateLimit struct {
+	perSecond int
+	global    bool
+	bucket    *tokenBucket
+}
+
+// SetRateLimit limits how many times per second the task is started by
+// workers of this server, a perSecond value of 0 removes the limit. Workers
+// wait until the task can be started. The limit must be set before launching
+// workers.
+func (server *Server) SetRateLimit(taskName string, perSecond int) {
+	server.setRateLimit(taskName, &rateLimit{
+		perSecond: perSecond,
+		bucket:    newTokenBucket(perSecond),
+	})
+}
+
+// SetGlobalRateLimit limits how many times per second the task is started by
+// all workers sharing the result backend, a perSecond value of 0 removes the
+// limit. The backend must implement backends.RateLimiter.
+func (server *Server) SetGlobalRateLimit(taskName string, perSecond int) error {
+	if _, ok := server.backend.(backends.RateLimiter); !ok && perSecond > 0 {
+		return errors.New("Result backend does not support rate limiting")
+	}
+	server.setRateLimit(taskName, &rateLimit{perSecond: perSecond, global: true})
+	return nil
+}
+
+func (server *Server) setRateLimit(taskName string, limit *rateLimit) {
+	if limit.perSecond <= 0 {
+		delete(server.rateLimits, taskName)
+		return
+	}
+	if server.rateLimits == nil {
+		server.rateLimits = make(map[string]*rateLimit)
+	}
+	server.rateLimits[taskName] = limit
+}
+
+// waitRateLimit blocks until the task can be started without exceeding its
+// rate limit, if any
+func (server *Server) waitRateLimit(taskName string) error {
+	limit, ok := server.rateLimits[taskName]
+	if !ok {
+		return nil
+	}
+
+	if !limit.global {
+		for wait := limit.bucket.take(time.Now()); wait > 0; wait = limit.bucket.take(time.Now()) {
+			time.Sleep(wait)
+		}
+		return nil
+	}
+
+	limiter, ok := server.backend.(backends.RateLimiter)
+	if !ok {
+		return errors.New("Result backend does not support rate limiting")
+	}
+	for {
+		taken, err := limiter.TakeRateLimitToken(taskName, limit.perSecond)
+		if err != nil {
+			return fmt.Errorf("Take rate limit token error: %s", err)
+		}
+		if taken {
+			return nil
+		}
+		// Wait for tokens of the next second
+		now := time.Now()
+		time.Sleep(now.Truncate(time.Second).Add(time.Second).Sub(now))
+	}
+}
+
+// tokenBucket holds up to perSecond tokens and is refilled continuously at
+// the rate of perSecond tokens per second
+type tokenBucket struct {
+	mu        sync.Mutex
+	perSecond float64
+	tokens    float64
+	updatedAt time.Time
+}
+
+func newTokenBucket(perSecond int) *tokenBucket {
+	return &tokenBucket{
+		perSecond: float64(perSecond),
+		tokens:    float64(perSecond),
+		updatedAt: time.Now(),
+	}
+}
+
+// take takes a token and returns 0 if one is available, otherwise it returns
+// how long to wait until the next token is available
+func (b *tokenBucket) take(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if elapsed := now.Sub(b.updatedAt); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * b.perSecond
+		if b.tokens > b.perSecond {
+			b.tokens = b.perSecond
+		}
+		b.updatedAt = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.perSecond * float64(time.Second))
+}
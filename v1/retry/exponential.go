@@ -0,0 +1,10 @@
+package retry
+
+// ExponentialNext returns the number following start when doubling it,
+// starting from 1
+func ExponentialNext(start int) int {
+	if start < 1 {
+		return 1
+	}
+	return start * 2
+}
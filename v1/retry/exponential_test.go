@@ -0,0 +1,16 @@
+package retry_test
+
+import (
+	"testing"
+
+	"github.com/RichardKnop/machinery/v1/retry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExponentialNext(t *testing.T) {
+	assert.Equal(t, 1, retry.ExponentialNext(-1))
+	assert.Equal(t, 1, retry.ExponentialNext(0))
+	assert.Equal(t, 2, retry.ExponentialNext(1))
+	assert.Equal(t, 6, retry.ExponentialNext(3))
+	assert.Equal(t, 20, retry.ExponentialNext(10))
+}
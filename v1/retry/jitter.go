@@ -0,0 +1,16 @@
+package retry
+
+import "time"
+
+// Jitter randomly spreads delay by up to fraction of it in either direction,
+// e.g. a fraction of 0.2 returns a delay between 80% and 120% of it. Random
+// must return numbers in [0, 1) like rand.Float64.
+func Jitter(delay time.Duration, fraction float64, random func() float64) time.Duration {
+	if fraction <= 0 || random == nil {
+		return delay
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+	return delay + time.Duration(float64(delay)*fraction*(2*random()-1))
+}
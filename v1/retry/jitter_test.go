@@ -0,0 +1,23 @@
+package retry_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/machinery/v1/retry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJitter(t *testing.T) {
+	fixed := func(value float64) func() float64 {
+		return func() float64 { return value }
+	}
+
+	assert.Equal(t, 8*time.Second, retry.Jitter(10*time.Second, 0.2, fixed(0)))
+	assert.Equal(t, 10*time.Second, retry.Jitter(10*time.Second, 0.2, fixed(0.5)))
+	assert.Equal(t, 11*time.Second, retry.Jitter(10*time.Second, 0.2, fixed(0.75)))
+
+	// Jitter is disabled without a fraction or a random source
+	assert.Equal(t, 10*time.Second, retry.Jitter(10*time.Second, 0, fixed(0)))
+	assert.Equal(t, 10*time.Second, retry.Jitter(10*time.Second, 0.2, nil))
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/rand"
+	"reflect"
 	"sync"
+	"time"
 
 	opentracing "github.com/opentracing/opentracing-go"
 
@@ -13,21 +16,67 @@ import (
 	"github.com/RichardKnop/machinery/v1/backends"
 	"github.com/RichardKnop/machinery/v1/brokers"
 	"github.com/RichardKnop/machinery/v1/config"
+	"github.com/RichardKnop/machinery/v1/retry"
 	"github.com/RichardKnop/machinery/v1/tasks"
 	"github.com/RichardKnop/machinery/v1/tracing"
 )
 
+// resultPollInterval is how often SendTaskAndWait checks the result backend
+const resultPollInterval = 50 * time.Millisecond
+
+// ErrRevokeTooLate is returned by RevokeTask when the task is not pending any
+// more, so it may already be running or finished
+var ErrRevokeTooLate = errors.New("Task is not pending any more")
+
 // Server is the main Machinery object and stores all configuration
 // All the tasks workers process are registered against the server
 type Server struct {
-	config          *config.Config
-	registeredTasks map[string]interface{}
-	broker          brokers.Interface
-	backend         backends.Interface
+	config            *config.Config
+	registeredTasks   map[string]interface{}
+	registeredTasksMu sync.RWMutex
+	taskDefaults      map[string]*taskDefaults
+	broker            brokers.Interface
+	backend           backends.Interface
+	metrics           Metrics
+	auditSink         AuditSink
+	middleware        []TaskMiddleware
+	rateLimits        map[string]*rateLimit
+	concurrencyLimits map[string]chan struct{}
+	memoryInFlight    int
+	memoryMu          sync.Mutex
+	brokerConnections int32
+	circuitBreakers   map[string]*circuitBreaker
+	events            eventBus
+	tracer            opentracing.Tracer
+	retryRandom       func() float64
+	webhooksMu        sync.Mutex
+	webhooksStopped   bool
+	webhooksWG        sync.WaitGroup
+	webhooks          chan webhookRequest
 }
 
 // NewServer creates Server instance
 func NewServer(cnf *config.Config) (*Server, error) {
+	if err := cnf.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Build TLS config from files unless it has been set explicitly
+	if cnf.TLSConfig == nil && cnf.TLS != nil {
+		tlsConfig, err := cnf.TLS.TLSConfig()
+		if err != nil {
+			return nil, fmt.Errorf("TLS config error: %s", err)
+		}
+		cnf.TLSConfig = tlsConfig
+	}
+	if cnf.Webhook != nil && cnf.Webhook.TLSConfig == nil && cnf.Webhook.TLS != nil {
+		tlsConfig, err := cnf.Webhook.TLS.TLSConfig()
+		if err != nil {
+			return nil, fmt.Errorf("Webhook TLS config error: %s", err)
+		}
+		cnf.Webhook.TLSConfig = tlsConfig
+	}
+
 	broker, err := BrokerFactory(cnf)
 	if err != nil {
 		return nil, err
@@ -41,6 +90,7 @@ func NewServer(cnf *config.Config) (*Server, error) {
 		registeredTasks: make(map[string]interface{}),
 		broker:          broker,
 		backend:         backend,
+		metrics:         noopMetrics{},
 	}
 
 	// init for eager-mode
@@ -54,6 +104,17 @@ func NewServer(cnf *config.Config) (*Server, error) {
 	return srv, nil
 }
 
+// NewTestServer creates Server instance using the eager broker and result
+// backend, so unit tests can register and send tasks without a message broker.
+// Sending a task runs it before returning, its results are available straight
+// away.
+func NewTestServer() (*Server, error) {
+	return NewServer(&config.Config{
+		Broker:        "eager",
+		ResultBackend: "eager",
+	})
+}
+
 // NewWorker creates Worker instance
 func (server *Server) NewWorker(consumerTag string, concurrency int) *Worker {
 	return &Worker{
@@ -63,6 +124,29 @@ func (server *Server) NewWorker(consumerTag string, concurrency int) *Worker {
 	}
 }
 
+// NewCustomQueueWorker creates Worker instance which consumes from the given
+// queue instead of the default queue
+func (server *Server) NewCustomQueueWorker(consumerTag string, concurrency int, queue string) *Worker {
+	return &Worker{
+		server:      server,
+		ConsumerTag: consumerTag,
+		Concurrency: concurrency,
+		Queue:       queue,
+	}
+}
+
+// NewMultiQueueWorker creates Worker instance which consumes from all of the
+// given queues over a single broker connection, tasks from all the queues
+// share the worker's concurrency
+func (server *Server) NewMultiQueueWorker(consumerTag string, concurrency int, queues []string) *Worker {
+	return &Worker{
+		server:      server,
+		ConsumerTag: consumerTag,
+		Concurrency: concurrency,
+		Queues:      queues,
+	}
+}
+
 // GetBroker returns broker
 func (server *Server) GetBroker() brokers.Interface {
 	return server.broker
@@ -83,6 +167,51 @@ func (server *Server) SetBackend(backend backends.Interface) {
 	server.backend = backend
 }
 
+// GetMetrics returns metrics object
+func (server *Server) GetMetrics() Metrics {
+	return server.metrics
+}
+
+// SetMetrics sets metrics object notified about processed tasks,
+// nil disables metrics
+func (server *Server) SetMetrics(metrics Metrics) {
+	if metrics == nil {
+		metrics = noopMetrics{}
+	}
+	server.metrics = metrics
+}
+
+// GetTracer returns the tracer used to trace sent and processed tasks, the
+// global opentracing tracer unless another one has been set
+func (server *Server) GetTracer() opentracing.Tracer {
+	if server.tracer == nil {
+		return opentracing.GlobalTracer()
+	}
+	return server.tracer
+}
+
+// SetTracer sets the tracer used to trace sent and processed tasks instead of
+// the global opentracing tracer, e.g. an OpenTelemetry bridge, nil resets it
+func (server *Server) SetTracer(tracer opentracing.Tracer) {
+	server.tracer = tracer
+}
+
+// SetRetryRandom sets the source of random numbers in [0, 1) used to jitter
+// retry delays with RetryJitter, e.g. a fixed one in tests, nil resets it to
+// rand.Float64
+func (server *Server) SetRetryRandom(random func() float64) {
+	server.retryRandom = random
+}
+
+// retryDelay returns the delay before retrying a task, jittered by RetryJitter
+func (server *Server) retryDelay(delay time.Duration) time.Duration {
+	random := server.retryRandom
+	if random == nil {
+		random = rand.Float64
+	}
+	return retry.Jitter(delay, server.config.RetryJitter, random)
+}
+
 // GetConfig returns connection object
 func (server *Server) GetConfig() *config.Config {
 	return server.config
@@ -93,36 +222,111 @@ func (server *Server) SetConfig(cnf *config.Config) {
 	server.config = cnf
 }
 
-// RegisterTasks registers all tasks at once
+// RegisterTasks registers all tasks at once, replacing any tasks registered
+// before. Nothing is registered if any of the tasks is invalid. Like the other
+// Register methods it is safe to call from multiple goroutines.
 func (server *Server) RegisterTasks(namedTaskFuncs map[string]interface{}) error {
 	for _, task := range namedTaskFuncs {
 		if err := tasks.ValidateTask(task); err != nil {
 			return err
 		}
 	}
-	server.registeredTasks = namedTaskFuncs
-	server.broker.SetRegisteredTaskNames(server.GetRegisteredTaskNames())
+
+	// Copy the tasks so the map of the caller can be changed afterwards
+	registeredTasks := make(map[string]interface{}, len(namedTaskFuncs))
+	for name, task := range namedTaskFuncs {
+		registeredTasks[name] = task
+	}
+
+	server.registeredTasksMu.Lock()
+	defer server.registeredTasksMu.Unlock()
+	server.registeredTasks = registeredTasks
+	server.taskDefaults = nil
+	server.broker.SetRegisteredTaskNames(server.registeredTaskNames())
+	return nil
+}
+
+// RegisterTask registers a single task, the options set defaults for its
+// signatures, e.g. WithDefaultRetries(5)
+func (server *Server) RegisterTask(name string, taskFunc interface{}, options ...TaskOption) error {
+	if err := tasks.ValidateTask(taskFunc); err != nil {
+		return err
+	}
+
+	server.registeredTasksMu.Lock()
+	defer server.registeredTasksMu.Unlock()
+	server.registeredTasks[name] = taskFunc
+	if defaults := newTaskDefaults(options); defaults != nil {
+		if server.taskDefaults == nil {
+			server.taskDefaults = make(map[string]*taskDefaults)
+		}
+		server.taskDefaults[name] = defaults
+	} else {
+		delete(server.taskDefaults, name)
+	}
+	server.broker.SetRegisteredTaskNames(server.registeredTaskNames())
 	return nil
 }
 
-// RegisterTask registers a single task
-func (server *Server) RegisterTask(name string, taskFunc interface{}) error {
+// RegisterTaskFunc registers a single task under its default name (see
+// tasks.TaskName) and any aliases, e.g. "add.v2", and returns the default name
+func (server *Server) RegisterTaskFunc(taskFunc interface{}, aliases ...string) (string, error) {
+	if err := tasks.ValidateTask(taskFunc); err != nil {
+		return "", err
+	}
+	name, err := tasks.TaskName(taskFunc)
+	if err != nil {
+		return "", err
+	}
+
+	server.registeredTasksMu.Lock()
+	defer server.registeredTasksMu.Unlock()
+	for _, taskName := range append([]string{name}, aliases...) {
+		server.registeredTasks[taskName] = taskFunc
+		delete(server.taskDefaults, taskName)
+	}
+	server.broker.SetRegisteredTaskNames(server.registeredTaskNames())
+	return name, nil
+}
+
+// ReplaceTask swaps the function of a registered task, e.g. to roll out a fix
+// to running workers, keeping the defaults the task was registered with. The
+// new function is validated first, so an invalid one leaves the task as it
+// was. The swap is atomic: tasks already being processed finish with the old
+// function and tasks received afterwards are processed with the new one.
+func (server *Server) ReplaceTask(name string, taskFunc interface{}) error {
 	if err := tasks.ValidateTask(taskFunc); err != nil {
 		return err
 	}
+
+	server.registeredTasksMu.Lock()
+	defer server.registeredTasksMu.Unlock()
+	if _, ok := server.registeredTasks[name]; !ok {
+		return fmt.Errorf("Task not registered error: %s", name)
+	}
 	server.registeredTasks[name] = taskFunc
-	server.broker.SetRegisteredTaskNames(server.GetRegisteredTaskNames())
 	return nil
 }
 
+// RegisterType registers the type of the sample value, e.g. a struct, so
+// tasks can take args of that type or of pointers to it, see
+// tasks.RegisterType. It returns the type name to use as the arg type.
+func (server *Server) RegisterType(sample interface{}) (string, error) {
+	return tasks.RegisterType(sample)
+}
+
 // IsTaskRegistered returns true if the task name is registered with this broker
 func (server *Server) IsTaskRegistered(name string) bool {
+	server.registeredTasksMu.RLock()
+	defer server.registeredTasksMu.RUnlock()
 	_, ok := server.registeredTasks[name]
 	return ok
 }
 
 // GetRegisteredTask returns registered task by name
 func (server *Server) GetRegisteredTask(name string) (interface{}, error) {
+	server.registeredTasksMu.RLock()
+	defer server.registeredTasksMu.RUnlock()
 	taskFunc, ok := server.registeredTasks[name]
 	if !ok {
 		return nil, fmt.Errorf("Task not registered error: %s", name)
@@ -132,7 +336,7 @@ func (server *Server) GetRegisteredTask(name string) (interface{}, error) {
 
 // SendTaskWithContext will inject the trace context in the signature headers before publishing it
 func (server *Server) SendTaskWithContext(ctx context.Context, signature *tasks.Signature) (*backends.AsyncResult, error) {
-	span, _ := opentracing.StartSpanFromContext(ctx, "SendTask", tracing.ProducerOption(), tracing.MachineryTag)
+	span := tracing.StartSpanFromContext(ctx, server.GetTracer(), "SendTask", tracing.ProducerOption(), tracing.MachineryTag)
 	defer span.Finish()
 
 	// tag the span with some info about the signature
@@ -149,33 +353,466 @@ func (server *Server) SendTask(signature *tasks.Signature) (*backends.AsyncResul
 		return nil, errors.New("Result backend required")
 	}
 
+	if err := server.prepareTask(signature); err != nil {
+		return nil, err
+	}
+
+	if err := server.broker.Publish(signature); err != nil {
+		return nil, fmt.Errorf("Publish message error: %s", err)
+	}
+
+	return backends.NewAsyncResult(signature, server.backend), nil
+}
+
+// Broadcast sends a task to be processed by every worker instead of a single
+// one, e.g. to invalidate caches or reload configuration, workers which are
+// not running when it is sent never get it. All of the workers update the
+// state of the same task. The broker must implement brokers.Broadcaster.
+func (server *Server) Broadcast(signature *tasks.Signature) error {
+	// Make sure result backend is defined
+	if server.backend == nil {
+		return errors.New("Result backend required")
+	}
+
+	broadcaster, ok := server.broker.(brokers.Broadcaster)
+	if !ok {
+		return errors.New("Broker does not support broadcasting tasks")
+	}
+
+	if err := server.prepareTask(signature); err != nil {
+		return err
+	}
+
+	// Every worker gets a copy of the same task, deduplication must not drop
+	// them as already received
+	if signature.Headers == nil {
+		signature.Headers = make(tasks.Headers)
+	}
+	signature.Headers[broadcastHeader] = true
+
+	if err := broadcaster.Broadcast(signature); err != nil {
+		return fmt.Errorf("Broadcast message error: %s", err)
+	}
+	return nil
+}
+
+// RegisterSerializer makes the serializer available for the content type,
+// workers pick the serializer of each message by its content type, so tasks
+// encoded by different serializers can be consumed side by side, e.g. while
+// migrating producers to another format. Serializers are registered for the
+// whole process, not just this server.
+func (server *Server) RegisterSerializer(contentType string, serializer brokers.Serializer) {
+	brokers.RegisterSerializerFor(contentType, serializer)
+}
+
+// QueueLength returns how many tasks are waiting in the queue, the default
+// queue if empty. The broker must implement brokers.QueueInspector.
+func (server *Server) QueueLength(queue string) (int, error) {
+	inspector, ok := server.broker.(brokers.QueueInspector)
+	if !ok {
+		return 0, errors.New("Broker does not support inspecting queues")
+	}
+	return inspector.QueueLength(queue)
+}
+
+// PurgeQueue removes all tasks waiting in the queue, the default queue if
+// empty, and returns how many were removed. This is a destructive operation
+// meant for administration, e.g. cleaning up after an incident. The broker
+// must implement brokers.QueuePurger.
+func (server *Server) PurgeQueue(queue string) (int, error) {
+	purger, ok := server.broker.(brokers.QueuePurger)
+	if !ok {
+		return 0, errors.New("Broker does not support purging queues")
+	}
+	return purger.PurgeQueue(queue)
+}
+
+// DrainQueue removes the tasks waiting in the queue, the default queue if
+// empty, handing each of them to fn, e.g. to audit a backlog or migrate it to
+// another queue. Like PurgeQueue it is a destructive operation, the tasks are
+// not processed by workers. The broker must implement brokers.QueuePurger.
+func (server *Server) DrainQueue(queue string, fn func(signature *tasks.Signature)) (int, error) {
+	purger, ok := server.broker.(brokers.QueuePurger)
+	if !ok {
+		return 0, errors.New("Broker does not support purging queues")
+	}
+	return purger.DrainQueue(queue, fn)
+}
+
+// ReplayDeadLetter takes up to limit tasks from the DeadLetterQueue, all of
+// the tasks waiting in it if limit is not positive, and sends them again to
+// the queue they were routed to originally, e.g. after deploying a fix for
+// the failures. Their retries are reset: the retries they used are given back
+// and the backoff starts over. A task is only removed from the dead letter
+// queue once it has been sent, so replaying can be repeated after an error.
+// It returns how many tasks were replayed, the broker must implement
+// brokers.QueueTaker.
+func (server *Server) ReplayDeadLetter(limit int) (int, error) {
+	if server.config.DeadLetterQueue == "" {
+		return 0, errors.New("DeadLetterQueue is not configured")
+	}
+	taker, ok := server.broker.(brokers.QueueTaker)
+	if !ok {
+		return 0, errors.New("Broker does not support taking tasks from queues")
+	}
+	if limit <= 0 {
+		limit = int(^uint(0) >> 1)
+	}
+
+	return taker.TakeTasks(server.config.DeadLetterQueue, limit, func(deadLetter *tasks.Signature) error {
+		signature := brokers.RestoreDeadLetter(deadLetter)
+
+		// Give back the retries the task used, the retries header counts them
+		signature.RetryCount += retries(signature)
+		signature.RetryTimeout = 0
+		delete(signature.Headers, retriesHeader)
+		delete(signature.Headers, firstFailedAtHeader)
+
+		// The task keeps its UUID, let it pass deduplication
+		if deduplicator, ok := server.backend.(backends.Deduplicator); ok && server.config.EnableDeduplication {
+			if err := deduplicator.UnmarkTaskSeen(signature.UUID); err != nil {
+				return fmt.Errorf("Unmark task seen error: %s", err)
+			}
+		}
+
+		if _, err := server.SendTask(signature); err != nil {
+			return fmt.Errorf("Replay task %s error: %s", signature.UUID, err)
+		}
+		return nil
+	})
+}
+
+// DeadLetterEntry is a task waiting in the DeadLetterQueue, listed by
+// ListDeadLetter
+type DeadLetterEntry struct {
+	// Signature of the task as it was routed originally, without the headers
+	// recording why it failed
+	Signature *tasks.Signature
+	// Error the task failed with the last time
+	Error string
+	// Retries is how many times the task was retried before it was dead
+	// lettered
+	Retries int
+	// FirstFailedAt is when the task failed for the first time, before it
+	// was retried
+	FirstFailedAt time.Time
+	// DeadLetteredAt is when the task failed for the last time and was sent
+	// to the dead letter queue
+	DeadLetteredAt time.Time
+}
+
+// ListDeadLetter returns up to limit tasks from the head of the
+// DeadLetterQueue, all of the tasks waiting in it if limit is not positive,
+// without replaying them, e.g. to show operators what failed. The broker must
+// implement brokers.QueuePeeker. The Redis broker only reads the queue, but
+// the AMQP broker has to get the messages and requeue them, see
+// brokers.AMQPBroker.PeekTasks.
+func (server *Server) ListDeadLetter(limit int) ([]*DeadLetterEntry, error) {
+	if server.config.DeadLetterQueue == "" {
+		return nil, errors.New("DeadLetterQueue is not configured")
+	}
+	peeker, ok := server.broker.(brokers.QueuePeeker)
+	if !ok {
+		return nil, errors.New("Broker does not support peeking at queues")
+	}
+	if limit <= 0 {
+		limit = int(^uint(0) >> 1)
+	}
+
+	deadLetters, err := peeker.PeekTasks(server.config.DeadLetterQueue, limit)
+	if err != nil {
+		return nil, fmt.Errorf("Peek tasks error: %s", err)
+	}
+
+	entries := make([]*DeadLetterEntry, len(deadLetters))
+	for i, deadLetter := range deadLetters {
+		entry := &DeadLetterEntry{
+			Signature:      brokers.RestoreDeadLetter(deadLetter),
+			Retries:        retries(deadLetter),
+			DeadLetteredAt: headerTime(deadLetter, "dead_letter_failed_at"),
+			FirstFailedAt:  headerTime(deadLetter, firstFailedAtHeader),
+		}
+		entry.Error, _ = deadLetter.Headers["dead_letter_error"].(string)
+		delete(entry.Signature.Headers, retriesHeader)
+		delete(entry.Signature.Headers, firstFailedAtHeader)
+
+		// Tasks which were not retried failed for the first time when they
+		// were dead lettered
+		if entry.FirstFailedAt.IsZero() {
+			entry.FirstFailedAt = entry.DeadLetteredAt
+		}
+		entries[i] = entry
+	}
+	return entries, nil
+}
+
+// headerTime returns the time held by the header of the task in RFC 3339
+// format, or the zero time if it is missing or malformed
+func headerTime(signature *tasks.Signature, header string) time.Time {
+	value, _ := signature.Headers[header].(string)
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// RevokeTask revokes a task which has been sent but has not been started yet,
+// workers receiving it drop it and update its state to REVOKED instead of
+// processing it. Running tasks cannot be stopped this way, ErrRevokeTooLate is
+// returned if the task was not pending any more and the task is left
+// unrevoked, retries of it included. The result backend must implement
+// backends.Revoker.
+func (server *Server) RevokeTask(taskUUID string) error {
+	revoker, ok := server.backend.(backends.Revoker)
+	if !ok {
+		return errors.New("Result backend does not support revoking tasks")
+	}
+
+	// Revoke first and check the state afterwards, workers check for
+	// revocation once they updated the state to RECEIVED, so a task seen
+	// PENDING here is never started
+	if err := revoker.RevokeTask(taskUUID); err != nil {
+		return fmt.Errorf("Revoke task error: %s", err)
+	}
+
+	taskState, err := server.backend.GetState(taskUUID)
+	if err != nil {
+		return err
+	}
+	switch taskState.State {
+	case tasks.StatePending, tasks.StateRetry, tasks.StateRevoked:
+		return nil
+	}
+
+	// The task is running or finished, it is not revoked after all, so a
+	// retry of it is still processed
+	if err := revoker.UnrevokeTask(taskUUID); err != nil {
+		return fmt.Errorf("Unrevoke task error: %s", err)
+	}
+	return ErrRevokeTooLate
+}
+
+// RevokeWorkflow revokes the remaining tasks of a chain, group, chord or
+// nested workflow identified by the WorkflowUUID its tasks were tagged with
+// when it was sent. Tasks already running finish, but workers drop pending
+// tasks of the workflow and do not send its next steps, updating their state
+// to REVOKED instead. The result backend must implement backends.Revoker.
+func (server *Server) RevokeWorkflow(workflowUUID string) error {
+	revoker, ok := server.backend.(backends.Revoker)
+	if !ok {
+		return errors.New("Result backend does not support revoking tasks")
+	}
+
+	if err := revoker.RevokeTask(workflowRevocationID(workflowUUID)); err != nil {
+		return fmt.Errorf("Revoke workflow error: %s", err)
+	}
+	return nil
+}
+
+// workflowRevocationID returns the ID workflow revocations are recorded under
+// in the backend, the UUID of a chain is the UUID of its first task, so
+// revoking that task must not revoke the whole chain
+func workflowRevocationID(workflowUUID string) string {
+	return "workflow_" + workflowUUID
+}
+
+// GetResults returns results of the tasks which have succeeded by task UUID,
+// tasks which have not finished yet, failed or were not found are left out.
+// A task returning a single value maps to it, a task returning several values
+// to a slice of them. Backends implementing backends.StatesGetter get all of
+// the states in a single round trip instead of one lookup per task, e.g. when
+// waiting for a large group.
+func (server *Server) GetResults(taskUUIDs []string) (map[string]interface{}, error) {
+	// Make sure result backend is defined
+	if server.backend == nil {
+		return nil, errors.New("Result backend required")
+	}
+
+	taskStates, err := server.getStates(taskUUIDs)
+	if err != nil {
+		return nil, fmt.Errorf("Get states error: %s", err)
+	}
+
+	results := make(map[string]interface{}, len(taskStates))
+	for taskUUID, taskState := range taskStates {
+		if !taskState.IsSuccess() {
+			continue
+		}
+		values, err := tasks.ReflectTaskResults(taskState.Results)
+		if err != nil {
+			return nil, fmt.Errorf("Reflect results of task %s error: %s", taskUUID, err)
+		}
+		switch len(values) {
+		case 0:
+			results[taskUUID] = nil
+		case 1:
+			results[taskUUID] = values[0].Interface()
+		default:
+			multiple := make([]interface{}, len(values))
+			for i, value := range values {
+				multiple[i] = value.Interface()
+			}
+			results[taskUUID] = multiple
+		}
+	}
+	return results, nil
+}
+
+// GetGroupResults returns results of the tasks of the group which have
+// succeeded, see GetResults
+func (server *Server) GetGroupResults(group *tasks.Group) (map[string]interface{}, error) {
+	return server.GetResults(group.GetUUIDs())
+}
+
+// getStates returns states of the tasks which were found, with a single
+// lookup if the backend supports it
+func (server *Server) getStates(taskUUIDs []string) (map[string]*tasks.TaskState, error) {
+	if getter, ok := server.backend.(backends.StatesGetter); ok {
+		return getter.GetStates(taskUUIDs...)
+	}
+
+	taskStates := make(map[string]*tasks.TaskState, len(taskUUIDs))
+	for _, taskUUID := range taskUUIDs {
+		taskState, err := server.backend.GetState(taskUUID)
+		if _, ok := err.(backends.ErrTasknotFound); ok {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		taskStates[taskUUID] = taskState
+	}
+	return taskStates, nil
+}
+
+// SendTaskAndWait sends a task and waits up to timeout for its results, it
+// returns the error of the task if it failed and backends.ErrTimeoutReached
+// if it did not finish in time
+func (server *Server) SendTaskAndWait(signature *tasks.Signature, timeout time.Duration) ([]reflect.Value, error) {
+	asyncResult, err := server.SendTask(signature)
+	if err != nil {
+		return nil, err
+	}
+
+	return asyncResult.GetWithTimeout(timeout, resultPollInterval)
+}
+
+// SendTasksError holds errors of tasks which could not be sent by SendTasks,
+// indexed the same way as the tasks, nil for tasks which have been sent
+type SendTasksError struct {
+	Errors []error
+}
+
+// Error returns how many tasks failed and the first error
+func (e *SendTasksError) Error() string {
+	var (
+		failed int
+		first  error
+	)
+	for _, err := range e.Errors {
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			failed++
+		}
+	}
+	return fmt.Sprintf("Sending %d of %d tasks failed, first error: %s", failed, len(e.Errors), first)
+}
+
+// SendTasks publishes multiple tasks at once, using fewer broker round-trips
+// than SendTask if the broker supports it. If some of the tasks could not be
+// sent, their async results are nil and *SendTasksError is returned
+func (server *Server) SendTasks(signatures []*tasks.Signature) ([]*backends.AsyncResult, error) {
+	// Make sure result backend is defined
+	if server.backend == nil {
+		return nil, errors.New("Result backend required")
+	}
+
+	var (
+		errs     = make([]error, len(signatures))
+		prepared []*tasks.Signature
+		indexes  []int
+	)
+	for i, signature := range signatures {
+		if err := server.prepareTask(signature); err != nil {
+			errs[i] = err
+			continue
+		}
+		prepared = append(prepared, signature)
+		indexes = append(indexes, i)
+	}
+
+	var publishErrs []error
+	if batchPublisher, ok := server.broker.(brokers.BatchPublisher); ok {
+		publishErrs = batchPublisher.PublishBatch(prepared)
+	} else {
+		publishErrs = make([]error, len(prepared))
+		for i, signature := range prepared {
+			publishErrs[i] = server.broker.Publish(signature)
+		}
+	}
+
+	asyncResults := make([]*backends.AsyncResult, len(signatures))
+	for n, i := range indexes {
+		if publishErrs[n] != nil {
+			errs[i] = fmt.Errorf("Publish message error: %s", publishErrs[n])
+			continue
+		}
+		asyncResults[i] = backends.NewAsyncResult(signatures[i], server.backend)
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			return asyncResults, &SendTasksError{Errors: errs}
+		}
+	}
+	return asyncResults, nil
+}
+
+// prepareTask auto generates the task UUID and arg types if needed and sets
+// the initial task state before the task is published
+func (server *Server) prepareTask(signature *tasks.Signature) error {
 	// Auto generate a UUID if not set already
 	if signature.UUID == "" {
 
 		taskID, err := uuid.NewV4()
 
 		if err != nil {
-			return nil, fmt.Errorf("Error generating task id: %s", err.Error())
+			return fmt.Errorf("Error generating task id: %s", err.Error())
 		}
 
 		signature.UUID = fmt.Sprintf("task_%v", taskID)
 	}
 
+	server.applySendDefaults(signature)
+
+	// Infer types of args which have been left empty
+	if err := tasks.InferArgTypes(signature); err != nil {
+		return fmt.Errorf("Infer arg types error: %s", err)
+	}
+
 	// Set initial task state to PENDING
 	if err := server.backend.SetStatePending(signature); err != nil {
-		return nil, fmt.Errorf("Set state pending error: %s", err)
+		return fmt.Errorf("Set state pending error: %s", err)
 	}
 
-	if err := server.broker.Publish(signature); err != nil {
-		return nil, fmt.Errorf("Publish message error: %s", err)
-	}
+	return nil
+}
 
-	return backends.NewAsyncResult(signature, server.backend), nil
+// SendTaskWithDelay publishes a task to the default queue to be processed after
+// the delay passes, it is a shortcut for setting the signature ETA
+func (server *Server) SendTaskWithDelay(signature *tasks.Signature, delay time.Duration) (*backends.AsyncResult, error) {
+	eta := time.Now().UTC().Add(delay)
+	signature.ETA = &eta
+
+	return server.SendTask(signature)
 }
 
 // SendChainWithContext will inject the trace context in all the signature headers before publishing it
 func (server *Server) SendChainWithContext(ctx context.Context, chain *tasks.Chain) (*backends.ChainAsyncResult, error) {
-	span, _ := opentracing.StartSpanFromContext(ctx, "SendChain", tracing.ProducerOption(), tracing.MachineryTag, tracing.WorkflowChainTag)
+	span := tracing.StartSpanFromContext(ctx, server.GetTracer(), "SendChain", tracing.ProducerOption(), tracing.MachineryTag, tracing.WorkflowChainTag)
 	defer span.Finish()
 
 	tracing.AnnotateSpanWithChainInfo(span, chain)
@@ -185,6 +822,22 @@ func (server *Server) SendChainWithContext(ctx context.Context, chain *tasks.Cha
 
 // SendChain triggers a chain of tasks
 func (server *Server) SendChain(chain *tasks.Chain) (*backends.ChainAsyncResult, error) {
+	// Make sure result backend is defined
+	if server.backend == nil {
+		return nil, errors.New("Result backend required")
+	}
+
+	tasks.TagWorkflow(chain)
+
+	// Later tasks are only sent once the previous one succeeded, store their
+	// PENDING state up front so waiting for them does not take the missing
+	// state for an expired result
+	for _, signature := range chain.Tasks[1:] {
+		if err := server.backend.SetStatePending(signature); err != nil {
+			return nil, fmt.Errorf("Set state pending error: %s", err)
+		}
+	}
+
 	_, err := server.SendTask(chain.Tasks[0])
 	if err != nil {
 		return nil, err
@@ -195,7 +848,7 @@ func (server *Server) SendChain(chain *tasks.Chain) (*backends.ChainAsyncResult,
 
 // SendGroupWithContext will inject the trace context in all the signature headers before publishing it
 func (server *Server) SendGroupWithContext(ctx context.Context, group *tasks.Group, sendConcurrency int) ([]*backends.AsyncResult, error) {
-	span, _ := opentracing.StartSpanFromContext(ctx, "SendGroup", tracing.ProducerOption(), tracing.MachineryTag, tracing.WorkflowGroupTag)
+	span := tracing.StartSpanFromContext(ctx, server.GetTracer(), "SendGroup", tracing.ProducerOption(), tracing.MachineryTag, tracing.WorkflowGroupTag)
 	defer span.Finish()
 
 	tracing.AnnotateSpanWithGroupInfo(span, group, sendConcurrency)
@@ -210,6 +863,8 @@ func (server *Server) SendGroup(group *tasks.Group, sendConcurrency int) ([]*bac
 		return nil, errors.New("Result backend required")
 	}
 
+	tasks.TagWorkflow(group)
+
 	asyncResults := make([]*backends.AsyncResult, len(group.Tasks))
 
 	var wg sync.WaitGroup
@@ -219,11 +874,11 @@ func (server *Server) SendGroup(group *tasks.Group, sendConcurrency int) ([]*bac
 	// Init group
 	server.backend.InitGroup(group.GroupUUID, group.GetUUIDs())
 
-	// Init the tasks Pending state first
+	// Init the tasks Pending state first, none of them is published if one
+	// cannot be prepared
 	for _, signature := range group.Tasks {
-		if err := server.backend.SetStatePending(signature); err != nil {
-			errorsChan <- err
-			continue
+		if err := server.prepareTask(signature); err != nil {
+			return nil, err
 		}
 	}
 
@@ -276,7 +931,7 @@ func (server *Server) SendGroup(group *tasks.Group, sendConcurrency int) ([]*bac
 
 // SendChordWithContext will inject the trace context in all the signature headers before publishing it
 func (server *Server) SendChordWithContext(ctx context.Context, chord *tasks.Chord, sendConcurrency int) (*backends.ChordAsyncResult, error) {
-	span, _ := opentracing.StartSpanFromContext(ctx, "SendChord", tracing.ProducerOption(), tracing.MachineryTag, tracing.WorkflowChordTag)
+	span := tracing.StartSpanFromContext(ctx, server.GetTracer(), "SendChord", tracing.ProducerOption(), tracing.MachineryTag, tracing.WorkflowChordTag)
 	defer span.Finish()
 
 	tracing.AnnotateSpanWithChordInfo(span, chord, sendConcurrency)
@@ -286,6 +941,26 @@ func (server *Server) SendChordWithContext(ctx context.Context, chord *tasks.Cho
 
 // SendChord triggers a group of parallel tasks with a callback
 func (server *Server) SendChord(chord *tasks.Chord, sendConcurrency int) (*backends.ChordAsyncResult, error) {
+	if err := tasks.ValidateChordPolicy(chord.OnPartialFailure); err != nil {
+		return nil, err
+	}
+	for _, signature := range chord.Group.Tasks {
+		signature.ChordOnPartialFailure = chord.OnPartialFailure
+		signature.ChordErrorCallback = chord.ErrorCallback
+	}
+	tasks.TagWorkflow(chord)
+
+	// Make sure result backend is defined
+	if server.backend == nil {
+		return nil, errors.New("Result backend required")
+	}
+
+	// The callback is only sent once the group finished, store its PENDING
+	// state up front like the states of chain tasks
+	if err := server.backend.SetStatePending(chord.Callback); err != nil {
+		return nil, fmt.Errorf("Set state pending error: %s", err)
+	}
+
 	_, err := server.SendGroup(chord.Group, sendConcurrency)
 	if err != nil {
 		return nil, err
@@ -298,8 +973,70 @@ func (server *Server) SendChord(chord *tasks.Chord, sendConcurrency int) (*backe
 	), nil
 }
 
+// SendWorkflow triggers a workflow of nested chains, groups and chords and
+// returns result of the task completing the workflow
+func (server *Server) SendWorkflow(workflow tasks.Workflow) (*backends.AsyncResult, error) {
+	// Make sure result backend is defined
+	if server.backend == nil {
+		return nil, errors.New("Result backend required")
+	}
+
+	terminals := workflow.Terminals()
+	if len(terminals) != 1 {
+		return nil, errors.New("Workflow must complete with a single task, use a chord to join a group")
+	}
+
+	signatures, err := tasks.WorkflowSignatures(workflow)
+	if err != nil {
+		return nil, err
+	}
+	tasks.TagWorkflow(workflow)
+
+	// Groups nested in the workflow are started by different tasks, so init
+	// all of them up front, as well as states of their tasks
+	var (
+		groupUUIDs []string
+		groupTasks = make(map[string][]string)
+	)
+	for _, signature := range signatures {
+		if signature.GroupUUID == "" {
+			continue
+		}
+		if _, ok := groupTasks[signature.GroupUUID]; !ok {
+			groupUUIDs = append(groupUUIDs, signature.GroupUUID)
+		}
+		groupTasks[signature.GroupUUID] = append(groupTasks[signature.GroupUUID], signature.UUID)
+	}
+	for _, groupUUID := range groupUUIDs {
+		if err := server.backend.InitGroup(groupUUID, groupTasks[groupUUID]); err != nil {
+			return nil, fmt.Errorf("Init group error: %s", err)
+		}
+	}
+	for _, signature := range signatures {
+		if err := server.backend.SetStatePending(signature); err != nil {
+			return nil, fmt.Errorf("Set state pending error: %s", err)
+		}
+	}
+
+	for _, signature := range workflow.Heads() {
+		if _, err := server.SendTask(signature); err != nil {
+			return nil, err
+		}
+	}
+
+	return backends.NewAsyncResult(terminals[0], server.backend), nil
+}
+
 // GetRegisteredTaskNames returns slice of registered task names
 func (server *Server) GetRegisteredTaskNames() []string {
+	server.registeredTasksMu.RLock()
+	defer server.registeredTasksMu.RUnlock()
+	return server.registeredTaskNames()
+}
+
+// registeredTaskNames returns names of the registered tasks, the caller must
+// hold registeredTasksMu
+func (server *Server) registeredTaskNames() []string {
 	taskNames := make([]string, len(server.registeredTasks))
 	var i = 0
 	for name := range server.registeredTasks {
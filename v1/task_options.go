@@ -0,0 +1,88 @@
+package machinery
+
+import (
+	"time"
+
+	"github.com/RichardKnop/machinery/v1/tasks"
+)
+
+// TaskOption sets an option of a task registered with RegisterTask, e.g. a
+// default which is used for signatures of the task leaving the option at its
+// zero value
+type TaskOption func(defaults *taskDefaults)
+
+// taskDefaults holds the default options of a registered task
+type taskDefaults struct {
+	retryCount     int
+	timeoutSeconds int
+	queue          string
+	auditRedaction []string
+}
+
+// WithDefaultRetries makes workers retry the task up to retryCount times
+// unless its signature sets RetryCount
+func WithDefaultRetries(retryCount int) TaskOption {
+	return func(defaults *taskDefaults) {
+		defaults.retryCount = retryCount
+	}
+}
+
+// WithDefaultTimeout makes workers time out the task after the timeout,
+// rounded up to whole seconds, unless its signature sets TimeoutSeconds
+func WithDefaultTimeout(timeout time.Duration) TaskOption {
+	return func(defaults *taskDefaults) {
+		defaults.timeoutSeconds = int((timeout + time.Second - 1) / time.Second)
+	}
+}
+
+// WithDefaultQueue makes the server send the task to the queue unless its
+// signature sets RoutingKey. Unlike the other options it is applied when
+// the task is sent, so the task must be registered with the sending server.
+func WithDefaultQueue(queue string) TaskOption {
+	return func(defaults *taskDefaults) {
+		defaults.queue = queue
+	}
+}
+
+// newTaskDefaults returns the defaults set by the options, nil if none
+func newTaskDefaults(options []TaskOption) *taskDefaults {
+	if len(options) == 0 {
+		return nil
+	}
+	defaults := new(taskDefaults)
+	for _, option := range options {
+		option(defaults)
+	}
+	return defaults
+}
+
+// getTaskDefaults returns the defaults of the registered task, nil if none
+func (server *Server) getTaskDefaults(name string) *taskDefaults {
+	server.registeredTasksMu.RLock()
+	defer server.registeredTasksMu.RUnlock()
+	return server.taskDefaults[name]
+}
+
+// applySendDefaults sets the default queue of the task when it is sent
+func (server *Server) applySendDefaults(signature *tasks.Signature) {
+	defaults := server.getTaskDefaults(signature.Name)
+	if defaults != nil && signature.RoutingKey == "" {
+		signature.RoutingKey = defaults.queue
+	}
+}
+
+// applyProcessDefaults sets the default retries and timeout of the task when
+// it is processed. Retries are decremented as the task is retried, so the
+// default only applies until the task has been retried for the first time.
+func (server *Server) applyProcessDefaults(signature *tasks.Signature) {
+	defaults := server.getTaskDefaults(signature.Name)
+	if defaults == nil {
+		return
+	}
+	if signature.RetryCount == 0 && retries(signature) == 0 {
+		signature.RetryCount = defaults.retryCount
+	}
+	if signature.TimeoutSeconds == 0 {
+		signature.TimeoutSeconds = defaults.timeoutSeconds
+	}
+}
@@ -0,0 +1,149 @@
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// SignatureBuilder builds a task signature step by step, inferring types of
+// args from their values, e.g.:
+//
+//	signature, err := tasks.NewSignatureBuilder("add").Arg(1).Arg(2).Build()
+type SignatureBuilder struct {
+	signature *Signature
+	err       error
+}
+
+// NewSignatureBuilder creates a builder for signature of the named task
+func NewSignatureBuilder(name string) *SignatureBuilder {
+	return &SignatureBuilder{signature: &Signature{Name: name}}
+}
+
+// Arg appends an arg, its type is inferred from the value
+func (b *SignatureBuilder) Arg(value interface{}) *SignatureBuilder {
+	return b.NamedArg("", value)
+}
+
+// NamedArg appends a named arg, its type is inferred from the value
+func (b *SignatureBuilder) NamedArg(name string, value interface{}) *SignatureBuilder {
+	if value == nil {
+		b.setErr(fmt.Errorf("Arg %d is nil, its type cannot be inferred", len(b.signature.Args)))
+		return b
+	}
+
+	b.signature.Args = append(b.signature.Args, Arg{
+		Name:  name,
+		Type:  reflect.TypeOf(value).String(),
+		Value: value,
+	})
+	return b
+}
+
+// UUID sets the signature UUID, a new one is generated by Build otherwise
+func (b *SignatureBuilder) UUID(uuid string) *SignatureBuilder {
+	b.signature.UUID = uuid
+	return b
+}
+
+// RoutingKey sets the routing key
+func (b *SignatureBuilder) RoutingKey(routingKey string) *SignatureBuilder {
+	b.signature.RoutingKey = routingKey
+	return b
+}
+
+// ETA delays the task until the given time
+func (b *SignatureBuilder) ETA(eta time.Time) *SignatureBuilder {
+	b.signature.ETA = &eta
+	return b
+}
+
+// ExpiresAt drops the task if it has not started by the given time
+func (b *SignatureBuilder) ExpiresAt(expiresAt time.Time) *SignatureBuilder {
+	b.signature.ExpiresAt = &expiresAt
+	return b
+}
+
+// Header sets a header
+func (b *SignatureBuilder) Header(key string, value interface{}) *SignatureBuilder {
+	if b.signature.Headers == nil {
+		b.signature.Headers = make(Headers)
+	}
+	b.signature.Headers[key] = value
+	return b
+}
+
+// Immutable makes the task not pass its results to success callbacks
+func (b *SignatureBuilder) Immutable() *SignatureBuilder {
+	b.signature.Immutable = true
+	return b
+}
+
+// Retry sets how many times to retry the task and the initial retry timeout
+func (b *SignatureBuilder) Retry(count, timeout int) *SignatureBuilder {
+	b.signature.RetryCount = count
+	b.signature.RetryTimeout = timeout
+	return b
+}
+
+// ExponentialBackoff doubles the retry timeout for each retry, starting from
+// the initial retry timeout
+func (b *SignatureBuilder) ExponentialBackoff() *SignatureBuilder {
+	b.signature.RetryBackoff = RetryBackoffExponential
+	return b
+}
+
+// Timeout sets the task timeout in seconds
+func (b *SignatureBuilder) Timeout(seconds int) *SignatureBuilder {
+	b.signature.TimeoutSeconds = seconds
+	return b
+}
+
+// OnSuccess appends success callbacks
+func (b *SignatureBuilder) OnSuccess(signatures ...*Signature) *SignatureBuilder {
+	b.signature.OnSuccess = append(b.signature.OnSuccess, signatures...)
+	return b
+}
+
+// OnError appends error callbacks
+func (b *SignatureBuilder) OnError(signatures ...*Signature) *SignatureBuilder {
+	b.signature.OnError = append(b.signature.OnError, signatures...)
+	return b
+}
+
+// Build validates and returns the signature, it returns the first error
+// encountered while building the signature
+func (b *SignatureBuilder) Build() (*Signature, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	if b.signature.Name == "" {
+		return nil, errors.New("Task name is required")
+	}
+
+	// Make sure workers will be able to reflect the args
+	for i, arg := range b.signature.Args {
+		if _, err := ReflectValue(arg.Type, arg.Value); err != nil {
+			return nil, fmt.Errorf("Arg %d of type %s cannot be reflected: %s", i, arg.Type, err)
+		}
+	}
+
+	if b.signature.UUID == "" {
+		signature, err := NewSignature(b.signature.Name, b.signature.Args)
+		if err != nil {
+			return nil, err
+		}
+		b.signature.UUID = signature.UUID
+	}
+
+	return b.signature, nil
+}
+
+// setErr keeps the first error
+func (b *SignatureBuilder) setErr(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
@@ -0,0 +1,52 @@
+package tasks_test
+
+import (
+	"testing"
+
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignatureBuilder(t *testing.T) {
+	t.Parallel()
+
+	callback := &tasks.Signature{Name: "multiply"}
+	signature, err := tasks.NewSignatureBuilder("add").
+		Arg(1).
+		NamedArg("b", int64(2)).
+		Arg([]string{"foo"}).
+		OnSuccess(callback).
+		Immutable().
+		Retry(3, 5).
+		ExponentialBackoff().
+		Build()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.NotEmpty(t, signature.UUID)
+	assert.Equal(t, "add", signature.Name)
+	assert.Equal(t, []tasks.Arg{
+		{Type: "int", Value: 1},
+		{Name: "b", Type: "int64", Value: int64(2)},
+		{Type: "[]string", Value: []string{"foo"}},
+	}, signature.Args)
+	assert.Equal(t, []*tasks.Signature{callback}, signature.OnSuccess)
+	assert.True(t, signature.Immutable)
+	assert.Equal(t, 3, signature.RetryCount)
+	assert.Equal(t, 5, signature.RetryTimeout)
+	assert.Equal(t, tasks.RetryBackoffExponential, signature.RetryBackoff)
+}
+
+func TestSignatureBuilderErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := tasks.NewSignatureBuilder("").Build()
+	assert.EqualError(t, err, "Task name is required")
+
+	_, err = tasks.NewSignatureBuilder("add").Arg(1).Arg(nil).Build()
+	assert.EqualError(t, err, "Arg 1 is nil, its type cannot be inferred")
+
+	_, err = tasks.NewSignatureBuilder("add").Arg(struct{}{}).Build()
+	assert.EqualError(t, err, "Arg 0 of type struct {} cannot be reflected: struct {} is not one of supported types")
+}
@@ -9,6 +9,7 @@ import (
 type ErrRetryTaskLater struct {
 	name, msg string
 	retryIn   time.Duration
+	err       error
 }
 
 // RetryIn returns time.Duration from now when task should be retried
@@ -21,12 +22,41 @@ func (e ErrRetryTaskLater) Error() string {
 	return fmt.Sprintf("Task error: %s Will retry in: %s", e.msg, e.retryIn)
 }
 
+// Unwrap returns the error wrapped by RetryTaskLater, if any
+func (e ErrRetryTaskLater) Unwrap() error {
+	return e.err
+}
+
 // NewErrRetryTaskLater returns new ErrRetryTaskLater instance
 func NewErrRetryTaskLater(msg string, retryIn time.Duration) ErrRetryTaskLater {
 	return ErrRetryTaskLater{msg: msg, retryIn: retryIn}
 }
 
-// Retriable is interface that retriable errors should implement
+// RetryTaskLater wraps the error so the task is retried after the duration
+// instead of failing, the wrapped error can still be inspected with errors.Is
+// and errors.As
+func RetryTaskLater(err error, retryIn time.Duration) ErrRetryTaskLater {
+	return ErrRetryTaskLater{msg: err.Error(), retryIn: retryIn, err: err}
+}
+
+// Retriable is interface that retriable errors should implement, workers
+// retry tasks returning such an error, even a wrapped one, after RetryIn
 type Retriable interface {
 	RetryIn() time.Duration
 }
+
+// TaskError describes a failed task, it is passed to error callbacks as the
+// first argument (of type tasks.TaskError) when structured error callbacks
+// are enabled
+type TaskError struct {
+	Message  string `json:"Message"`
+	TaskName string `json:"TaskName"`
+	TaskUUID string `json:"TaskUUID"`
+	// Retries is how many times the task has been retried before failing
+	Retries int `json:"Retries"`
+}
+
+// Error implements the error interface
+func (e TaskError) Error() string {
+	return e.Message
+}
@@ -0,0 +1,91 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// RawHandler is a task taking the values of its args as a JSON array instead
+// of reflected values, e.g. [1, "foo"]. Workers call raw handlers without
+// reflecting their args and results, which makes them cheaper for tasks
+// processed at high throughput. The result, unless nil, is stored as the
+// only result of the task, so it should be of a supported type.
+type RawHandler func(args json.RawMessage) (interface{}, error)
+
+// asRawHandler returns the task func as RawHandler if it has its signature
+func asRawHandler(taskFunc interface{}) (RawHandler, bool) {
+	switch handler := taskFunc.(type) {
+	case RawHandler:
+		return handler, true
+	case func(json.RawMessage) (interface{}, error):
+		return handler, true
+	}
+	return nil, false
+}
+
+// newRawTask prepares the raw handler for invocation with the args
+func newRawTask(handler RawHandler, args []Arg) (*Task, error) {
+	rawArgs, err := encodeRawArgs(args)
+	if err != nil {
+		return nil, fmt.Errorf("Encode task args error: %s", err)
+	}
+
+	return &Task{
+		TaskFunc:   reflect.ValueOf(handler),
+		Context:    context.Background(),
+		rawHandler: handler,
+		rawArgs:    rawArgs,
+	}, nil
+}
+
+// callRaw invokes the raw handler, panics are recovered by Call
+func (t *Task) callRaw() ([]*TaskResult, error) {
+	result, err := t.rawHandler(t.rawArgs)
+
+	// If the context deadline passed while the task was running, the task
+	// timed out and whatever it returned is discarded
+	if t.Context.Err() == context.DeadlineExceeded {
+		return nil, t.Context.Err()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return []*TaskResult{}, nil
+	}
+	return []*TaskResult{{
+		Type:  reflect.TypeOf(result).String(),
+		Value: result,
+	}}, nil
+}
+
+// encodeRawArgs encodes the values of the args as a JSON array, numbers
+// decoded from messages as json.Number are copied as they are
+func encodeRawArgs(args []Arg) (json.RawMessage, error) {
+	rawArgs := make([]byte, 0, 64)
+	rawArgs = append(rawArgs, '[')
+	for i, arg := range args {
+		if i > 0 {
+			rawArgs = append(rawArgs, ',')
+		}
+		switch value := arg.Value.(type) {
+		case json.Number:
+			rawArgs = append(rawArgs, value...)
+		case bool:
+			rawArgs = strconv.AppendBool(rawArgs, value)
+		case nil:
+			rawArgs = append(rawArgs, "null"...)
+		default:
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+			rawArgs = append(rawArgs, encoded...)
+		}
+	}
+	return append(rawArgs, ']'), nil
+}
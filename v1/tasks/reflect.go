@@ -2,10 +2,16 @@ package tasks
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 var (
@@ -34,6 +40,7 @@ var (
 		"[]int64":   reflect.TypeOf(make([]int64, 0)),
 		"[]uint":    reflect.TypeOf(make([]uint, 0)),
 		"[]uint8":   reflect.TypeOf(make([]uint8, 0)),
+		"[]byte":    reflect.TypeOf(make([]byte, 0)),
 		"[]uint16":  reflect.TypeOf(make([]uint16, 0)),
 		"[]uint32":  reflect.TypeOf(make([]uint32, 0)),
 		"[]uint64":  reflect.TypeOf(make([]uint64, 0)),
@@ -42,11 +49,27 @@ var (
 		"[]string":  reflect.TypeOf([]string{""}),
 	}
 
+	// registeredTypes holds custom types registered with RegisterType
+	registeredTypes    = make(map[string]reflect.Type)
+	registeredTypesMux sync.RWMutex
+
 	ctxType = reflect.TypeOf((*context.Context)(nil)).Elem()
 
+	taskErrorType = reflect.TypeOf(TaskError{})
+
+	timeType = reflect.TypeOf(time.Time{})
+
+	// nilType is the type of args and results holding nil whose own type
+	// cannot be reflected, e.g. a nil interface
+	nilType = reflect.TypeOf((*interface{})(nil)).Elem()
+
 	typeConversionError = func(argValue interface{}, argTypeStr string) error {
 		return fmt.Errorf("%v is not %v", argValue, argTypeStr)
 	}
+
+	overflowError = func(argValue interface{}, argTypeStr string) error {
+		return fmt.Errorf("%v overflows %v", argValue, argTypeStr)
+	}
 )
 
 // ErrUnsupportedType ...
@@ -64,12 +87,73 @@ func (e ErrUnsupportedType) Error() string {
 	return fmt.Sprintf("%v is not one of supported types", e.valueType)
 }
 
+// RegisterType registers the type of the sample value, e.g. a struct, so
+// args and results of that type and of pointers to it can be reflected from
+// their JSON decoded values. It returns the type name to use as the arg
+// type, e.g. "mypkg.User". Types are registered for the whole process.
+func RegisterType(sample interface{}) (string, error) {
+	if sample == nil {
+		return "", errors.New("Value is nil, its type cannot be registered")
+	}
+
+	theType := reflect.TypeOf(sample)
+	if theType.Kind() == reflect.Ptr {
+		theType = theType.Elem()
+	}
+	if _, ok := typesMap[theType.String()]; ok || theType == taskErrorType || theType == timeType {
+		return "", fmt.Errorf("%v is already supported", theType)
+	}
+
+	registeredTypesMux.Lock()
+	defer registeredTypesMux.Unlock()
+	registeredTypes[theType.String()] = theType
+	registeredTypes[reflect.PtrTo(theType).String()] = reflect.PtrTo(theType)
+	return theType.String(), nil
+}
+
+// registeredType returns the type registered with RegisterType by its name
+func registeredType(valueType string) (reflect.Type, bool) {
+	registeredTypesMux.RLock()
+	defer registeredTypesMux.RUnlock()
+	theType, ok := registeredTypes[valueType]
+	return theType, ok
+}
+
 // ReflectValue converts interface{} to reflect.Value based on string type
 func ReflectValue(valueType string, value interface{}) (reflect.Value, error) {
-	if strings.HasPrefix(valueType, "[]") {
+	// Errors passed to error callbacks
+	if valueType == taskErrorType.String() {
+		return reflectTaskError(value)
+	}
+
+	// Times, encoded as RFC 3339 strings
+	if valueType == timeType.String() || valueType == reflect.PtrTo(timeType).String() {
+		return reflectTime(valueType, value)
+	}
+
+	// Types registered with RegisterType
+	if theType, ok := registeredType(valueType); ok {
+		return reflectRegisteredType(theType, value)
+	}
+
+	// Nil values without a type of their own
+	if valueType == nilType.String() {
+		if value != nil {
+			return reflect.Value{}, typeConversionError(value, "nil")
+		}
+		return reflect.Zero(nilType), nil
+	}
+
+	// Slices of base types
+	if _, ok := typesMap[valueType]; ok && strings.HasPrefix(valueType, "[]") {
 		return reflectValues(valueType, value)
 	}
 
+	// Nested slices and maps
+	if strings.HasPrefix(valueType, "[]") || strings.HasPrefix(valueType, "map[") {
+		return reflectComposite(valueType, value)
+	}
+
 	return reflectValue(valueType, value)
 }
 
@@ -99,6 +183,9 @@ func reflectValue(valueType string, value interface{}) (reflect.Value, error) {
 		if err != nil {
 			return reflect.Value{}, err
 		}
+		if theValue.Elem().OverflowInt(intValue) {
+			return reflect.Value{}, overflowError(value, theType.String())
+		}
 
 		theValue.Elem().SetInt(intValue)
 		return theValue.Elem(), err
@@ -110,6 +197,9 @@ func reflectValue(valueType string, value interface{}) (reflect.Value, error) {
 		if err != nil {
 			return reflect.Value{}, err
 		}
+		if theValue.Elem().OverflowUint(uintValue) {
+			return reflect.Value{}, overflowError(value, theType.String())
+		}
 
 		theValue.Elem().SetUint(uintValue)
 		return theValue.Elem(), err
@@ -121,6 +211,9 @@ func reflectValue(valueType string, value interface{}) (reflect.Value, error) {
 		if err != nil {
 			return reflect.Value{}, err
 		}
+		if theValue.Elem().OverflowFloat(floatValue) {
+			return reflect.Value{}, overflowError(value, theType.String())
+		}
 
 		theValue.Elem().SetFloat(floatValue)
 		return theValue.Elem(), err
@@ -182,6 +275,9 @@ func reflectValues(valueType string, value interface{}) (reflect.Value, error) {
 			if err != nil {
 				return reflect.Value{}, err
 			}
+			if theValue.Index(i).OverflowInt(intValue) {
+				return reflect.Value{}, overflowError(ints.Index(i).Interface(), theType.Elem().String())
+			}
 
 			theValue.Index(i).SetInt(intValue)
 		}
@@ -189,6 +285,16 @@ func reflectValues(valueType string, value interface{}) (reflect.Value, error) {
 		return theValue, nil
 	}
 
+	// Byte slices are encoded as base64 strings by encoding/json and the JSON
+	// encoders of most other languages
+	if str, ok := value.(string); ok && theType.Elem().Kind() == reflect.Uint8 {
+		bytes, err := base64.StdEncoding.DecodeString(str)
+		if err != nil {
+			return reflect.Value{}, typeConversionError(value, valueType)
+		}
+		return reflect.ValueOf(bytes), nil
+	}
+
 	// Unsigned integers
 	if strings.HasPrefix(theType.String(), "[]uint") {
 		uints := reflect.ValueOf(value)
@@ -199,6 +305,9 @@ func reflectValues(valueType string, value interface{}) (reflect.Value, error) {
 			if err != nil {
 				return reflect.Value{}, err
 			}
+			if theValue.Index(i).OverflowUint(uintValue) {
+				return reflect.Value{}, overflowError(uints.Index(i).Interface(), theType.Elem().String())
+			}
 
 			theValue.Index(i).SetUint(uintValue)
 		}
@@ -216,6 +325,9 @@ func reflectValues(valueType string, value interface{}) (reflect.Value, error) {
 			if err != nil {
 				return reflect.Value{}, err
 			}
+			if theValue.Index(i).OverflowFloat(floatValue) {
+				return reflect.Value{}, overflowError(floats.Index(i).Interface(), theType.Elem().String())
+			}
 
 			theValue.Index(i).SetFloat(floatValue)
 		}
@@ -243,6 +355,181 @@ func reflectValues(valueType string, value interface{}) (reflect.Value, error) {
 	return reflect.Value{}, NewErrUnsupportedType(valueType)
 }
 
+// reflectComposite converts interface{} to reflect.Value based on string type
+// representing a nested slice (e.g. [][]int) or a map with string keys
+// (e.g. map[string]int), building the value element by element
+func reflectComposite(valueType string, value interface{}) (reflect.Value, error) {
+	theType, err := reflectType(valueType)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	// For NULL we return an empty slice or map
+	if value == nil {
+		if theType.Kind() == reflect.Map {
+			return reflect.MakeMap(theType), nil
+		}
+		return reflect.MakeSlice(theType, 0, 0), nil
+	}
+
+	values := reflect.ValueOf(value)
+
+	if theType.Kind() == reflect.Map {
+		if values.Kind() != reflect.Map || values.Type().Key().Kind() != reflect.String {
+			return reflect.Value{}, typeConversionError(value, valueType)
+		}
+
+		elemType := mapElemTypeString(valueType)
+		theValue := reflect.MakeMapWithSize(theType, values.Len())
+		for _, key := range values.MapKeys() {
+			elemValue, err := ReflectValue(elemType, values.MapIndex(key).Interface())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+
+			theValue.SetMapIndex(reflect.ValueOf(key.String()), elemValue)
+		}
+
+		return theValue, nil
+	}
+
+	if values.Kind() != reflect.Slice {
+		return reflect.Value{}, typeConversionError(value, valueType)
+	}
+
+	elemType := strings.TrimPrefix(valueType, "[]")
+	theValue := reflect.MakeSlice(theType, values.Len(), values.Len())
+	for i := 0; i < values.Len(); i++ {
+		elemValue, err := ReflectValue(elemType, values.Index(i).Interface())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		theValue.Index(i).Set(elemValue)
+	}
+
+	return theValue, nil
+}
+
+// reflectTaskError converts interface{} to reflect.Value of TaskError, the
+// value is a map once decoded from JSON
+func reflectTaskError(value interface{}) (reflect.Value, error) {
+	if taskErr, ok := value.(TaskError); ok {
+		return reflect.ValueOf(taskErr), nil
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return reflect.Value{}, typeConversionError(value, taskErrorType.String())
+	}
+
+	var taskErr TaskError
+	if err := json.Unmarshal(encoded, &taskErr); err != nil {
+		return reflect.Value{}, typeConversionError(value, taskErrorType.String())
+	}
+	return reflect.ValueOf(taskErr), nil
+}
+
+// reflectTime converts interface{} to reflect.Value of time.Time or a pointer
+// to it, the value is an RFC 3339 string once decoded from JSON
+func reflectTime(valueType string, value interface{}) (reflect.Value, error) {
+	isPtr := strings.HasPrefix(valueType, "*")
+
+	var theTime time.Time
+	switch value := value.(type) {
+	case nil:
+		if isPtr {
+			return reflect.Zero(reflect.PtrTo(timeType)), nil
+		}
+		return reflect.Value{}, typeConversionError(value, valueType)
+	case time.Time:
+		theTime = value
+	case *time.Time:
+		if value == nil {
+			return reflectTime(valueType, nil)
+		}
+		theTime = *value
+	case string:
+		parsed, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return reflect.Value{}, typeConversionError(value, valueType)
+		}
+		theTime = parsed
+	default:
+		return reflect.Value{}, typeConversionError(value, valueType)
+	}
+
+	if isPtr {
+		return reflect.ValueOf(&theTime), nil
+	}
+	return reflect.ValueOf(theTime), nil
+}
+
+// reflectRegisteredType converts interface{} to reflect.Value of a type
+// registered with RegisterType, the value is a map once decoded from JSON
+func reflectRegisteredType(theType reflect.Type, value interface{}) (reflect.Value, error) {
+	if value == nil && theType.Kind() == reflect.Ptr {
+		return reflect.Zero(theType), nil
+	}
+	if value != nil && reflect.TypeOf(value) == theType {
+		return reflect.ValueOf(value), nil
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return reflect.Value{}, typeConversionError(value, theType.String())
+	}
+
+	elemType := theType
+	if theType.Kind() == reflect.Ptr {
+		elemType = theType.Elem()
+	}
+	theValue := reflect.New(elemType)
+	if err := json.Unmarshal(encoded, theValue.Interface()); err != nil {
+		return reflect.Value{}, typeConversionError(value, theType.String())
+	}
+
+	if theType.Kind() == reflect.Ptr {
+		return theValue, nil
+	}
+	return theValue.Elem(), nil
+}
+
+// reflectType returns reflect.Type for string type representing a base type,
+// time.Time, a (possibly nested) slice or a map with string keys
+func reflectType(valueType string) (reflect.Type, error) {
+	if theType, ok := typesMap[valueType]; ok {
+		return theType, nil
+	}
+	if valueType == timeType.String() {
+		return timeType, nil
+	}
+
+	if strings.HasPrefix(valueType, "[]") {
+		elemType, err := reflectType(strings.TrimPrefix(valueType, "[]"))
+		if err != nil {
+			return nil, NewErrUnsupportedType(valueType)
+		}
+		return reflect.SliceOf(elemType), nil
+	}
+
+	if strings.HasPrefix(valueType, "map[string]") {
+		elemType, err := reflectType(mapElemTypeString(valueType))
+		if err != nil {
+			return nil, NewErrUnsupportedType(valueType)
+		}
+		return reflect.MapOf(typesMap["string"], elemType), nil
+	}
+
+	return nil, NewErrUnsupportedType(valueType)
+}
+
+// mapElemTypeString returns the element type of string type representing a map
+// with string keys, e.g. "int" for "map[string]int"
+func mapElemTypeString(valueType string) string {
+	return strings.TrimPrefix(valueType, "map[string]")
+}
+
 func getBoolValue(theType string, value interface{}) (bool, error) {
 	b, ok := value.(bool)
 	if !ok {
@@ -256,67 +543,87 @@ func getIntValue(theType string, value interface{}) (int64, error) {
 	// We use https://golang.org/pkg/encoding/json/#Decoder.UseNumber when unmarshaling signatures.
 	// This is because JSON only supports 64-bit floating point numbers and we could lose precision
 	// when converting from float64 to signed integer
-	if strings.HasPrefix(fmt.Sprintf("%T", value), "json.Number") {
-		n, ok := value.(json.Number)
-		if !ok {
+	if n, ok := value.(json.Number); ok {
+		intVal, err := strconv.ParseInt(n.String(), 10, 64)
+		if err != nil {
 			return 0, typeConversionError(value, typesMap[theType].String())
 		}
 
-		return n.Int64()
+		return intVal, nil
 	}
 
-	n, ok := value.(int64)
-	if !ok {
-		return 0, typeConversionError(value, typesMap[theType].String())
+	// Other backends (e.g. MongoDB) decode numbers into native Go types
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() > math.MaxInt64 {
+			return 0, overflowError(value, typesMap[theType].String())
+		}
+		return int64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		if v.Float() != math.Trunc(v.Float()) || v.Float() >= math.MaxInt64 || v.Float() < math.MinInt64 {
+			return 0, typeConversionError(value, typesMap[theType].String())
+		}
+		return int64(v.Float()), nil
 	}
 
-	return n, nil
+	return 0, typeConversionError(value, typesMap[theType].String())
 }
 
 func getUintValue(theType string, value interface{}) (uint64, error) {
 	// We use https://golang.org/pkg/encoding/json/#Decoder.UseNumber when unmarshaling signatures.
 	// This is because JSON only supports 64-bit floating point numbers and we could lose precision
 	// when converting from float64 to unsigned integer
-	if strings.HasPrefix(fmt.Sprintf("%T", value), "json.Number") {
-		n, ok := value.(json.Number)
-		if !ok {
-			return 0, typeConversionError(value, typesMap[theType].String())
-		}
-
-		intVal, err := n.Int64()
+	if n, ok := value.(json.Number); ok {
+		uintVal, err := strconv.ParseUint(n.String(), 10, 64)
 		if err != nil {
-			return 0, err
+			return 0, typeConversionError(value, typesMap[theType].String())
 		}
 
-		return uint64(intVal), nil
+		return uintVal, nil
 	}
 
-	n, ok := value.(uint64)
-	if !ok {
-		return 0, typeConversionError(value, typesMap[theType].String())
+	// Other backends (e.g. MongoDB) decode numbers into native Go types
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() < 0 {
+			return 0, typeConversionError(value, typesMap[theType].String())
+		}
+		return uint64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		if v.Float() != math.Trunc(v.Float()) || v.Float() < 0 || v.Float() >= math.MaxUint64 {
+			return 0, typeConversionError(value, typesMap[theType].String())
+		}
+		return uint64(v.Float()), nil
 	}
 
-	return n, nil
+	return 0, typeConversionError(value, typesMap[theType].String())
 }
 
 func getFloatValue(theType string, value interface{}) (float64, error) {
 	// We use https://golang.org/pkg/encoding/json/#Decoder.UseNumber when unmarshaling signatures.
 	// This is because JSON only supports 64-bit floating point numbers and we could lose precision
-	if strings.HasPrefix(fmt.Sprintf("%T", value), "json.Number") {
-		n, ok := value.(json.Number)
-		if !ok {
-			return 0, typeConversionError(value, typesMap[theType].String())
-		}
-
+	if n, ok := value.(json.Number); ok {
 		return n.Float64()
 	}
 
-	f, ok := value.(float64)
-	if !ok {
-		return 0, typeConversionError(value, typesMap[theType].String())
+	// Other backends (e.g. MongoDB) decode numbers into native Go types
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
 	}
 
-	return f, nil
+	return 0, typeConversionError(value, typesMap[theType].String())
 }
 
 func getStringValue(theType string, value interface{}) (string, error) {
@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/RichardKnop/machinery/v1/tasks"
+	"github.com/stretchr/testify/assert"
 )
 
 var (
@@ -184,6 +186,44 @@ var (
 			expectedType:  "[]string",
 			expectedValue: []string{"foo", "bar"},
 		},
+		// nested slices
+		{
+			name:          "[][]int",
+			value:         []interface{}{[]interface{}{json.Number("1"), json.Number("2")}, []interface{}{}},
+			expectedType:  "[][]int",
+			expectedValue: [][]int{{1, 2}, {}},
+		},
+		{
+			name:          "[][]string",
+			value:         []interface{}{[]interface{}{"foo"}, []interface{}{"bar", "qux"}},
+			expectedType:  "[][]string",
+			expectedValue: [][]string{{"foo"}, {"bar", "qux"}},
+		},
+		// maps
+		{
+			name:          "map[string]int",
+			value:         map[string]interface{}{"foo": json.Number("1"), "bar": json.Number("2")},
+			expectedType:  "map[string]int",
+			expectedValue: map[string]int{"foo": 1, "bar": 2},
+		},
+		{
+			name:          "map[string]string",
+			value:         map[string]interface{}{"foo": "bar"},
+			expectedType:  "map[string]string",
+			expectedValue: map[string]string{"foo": "bar"},
+		},
+		{
+			name:          "map[string][]float64",
+			value:         map[string]interface{}{"foo": []interface{}{json.Number("0.5")}},
+			expectedType:  "map[string][]float64",
+			expectedValue: map[string][]float64{"foo": {0.5}},
+		},
+		{
+			name:          "[]map[string]bool",
+			value:         []interface{}{map[string]interface{}{"foo": true}},
+			expectedType:  "[]map[string]bool",
+			expectedValue: []map[string]bool{{"foo": true}},
+		},
 		// empty slices from NULL
 		{
 			name:          "[]bool",
@@ -215,6 +255,19 @@ var (
 			expectedType:  "[]string",
 			expectedValue: []string{},
 		},
+		{
+			name:          "[][]int",
+			value:         nil,
+			expectedType:  "[][]int",
+			expectedValue: [][]int{},
+		},
+		// empty maps from NULL
+		{
+			name:          "map[string]int",
+			value:         nil,
+			expectedType:  "map[string]int",
+			expectedValue: map[string]int{},
+		},
 	}
 )
 
@@ -241,3 +294,250 @@ func TestReflectValue(t *testing.T) {
 		})
 	}
 }
+
+func TestReflectValueNumericConversion(t *testing.T) {
+	t.Parallel()
+
+	value, err := tasks.ReflectValue("uint64", json.Number("18446744073709551615"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, uint64(18446744073709551615), value.Interface())
+	}
+
+	// Native Go numbers, e.g. decoded by MongoDB backend
+	value, err = tasks.ReflectValue("int", int32(123))
+	if assert.NoError(t, err) {
+		assert.Equal(t, int(123), value.Interface())
+	}
+
+	value, err = tasks.ReflectValue("int64", float64(123))
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(123), value.Interface())
+	}
+
+	value, err = tasks.ReflectValue("float32", int64(2))
+	if assert.NoError(t, err) {
+		assert.Equal(t, float32(2), value.Interface())
+	}
+
+	value, err = tasks.ReflectValue("[]uint16", []interface{}{float64(1), json.Number("2")})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []uint16{1, 2}, value.Interface())
+	}
+
+	_, err = tasks.ReflectValue("int8", json.Number("300"))
+	assert.EqualError(t, err, "300 overflows int8")
+
+	_, err = tasks.ReflectValue("uint8", json.Number("-1"))
+	assert.EqualError(t, err, "-1 is not uint8")
+
+	_, err = tasks.ReflectValue("[]int16", []interface{}{json.Number("1"), json.Number("40000")})
+	assert.EqualError(t, err, "40000 overflows int16")
+
+	_, err = tasks.ReflectValue("float32", json.Number("1e39"))
+	assert.EqualError(t, err, "1e39 overflows float32")
+
+	_, err = tasks.ReflectValue("int", float64(1.5))
+	assert.EqualError(t, err, "1.5 is not int")
+
+	_, err = tasks.ReflectValue("int", "123")
+	assert.EqualError(t, err, "123 is not int")
+}
+
+func TestReflectValueBase64Bytes(t *testing.T) {
+	t.Parallel()
+
+	// Byte slices are encoded as base64 strings, e.g. by encoding/json or by
+	// Python producers
+	encoded, err := json.Marshal([]byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, valueType := range []string{"[]uint8", "[]byte"} {
+		value, err := tasks.ReflectValue(valueType, decoded)
+		if assert.NoError(t, err) {
+			assert.Equal(t, []byte("hello"), value.Interface())
+		}
+	}
+
+	value, err := tasks.ReflectValue("[][]byte", []interface{}{"aGk=", "dGhlcmU="})
+	if assert.NoError(t, err) {
+		assert.Equal(t, [][]byte{[]byte("hi"), []byte("there")}, value.Interface())
+	}
+
+	value, err = tasks.ReflectValue("map[string][]byte", map[string]interface{}{"key": "aGk="})
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string][]byte{"key": []byte("hi")}, value.Interface())
+	}
+
+	_, err = tasks.ReflectValue("[]byte", "not base64!")
+	assert.EqualError(t, err, "not base64! is not []byte")
+}
+
+func TestReflectTime(t *testing.T) {
+	t.Parallel()
+
+	// roundTrip encodes the value to JSON and back like brokers do
+	roundTrip := func(value interface{}) interface{} {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded interface{}
+		if err := json.Unmarshal(encoded, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		return decoded
+	}
+
+	// The monotonic clock reading is dropped when encoding, the wall clock
+	// time is kept to the nanosecond
+	now := time.Now()
+	value, err := tasks.ReflectValue("time.Time", roundTrip(now))
+	if assert.NoError(t, err) {
+		assert.True(t, now.Round(0).Equal(value.Interface().(time.Time)))
+		assert.Equal(t, now.Round(0).UnixNano(), value.Interface().(time.Time).UnixNano())
+	}
+
+	// The zero time stays zero
+	value, err = tasks.ReflectValue("time.Time", roundTrip(time.Time{}))
+	if assert.NoError(t, err) {
+		assert.True(t, value.Interface().(time.Time).IsZero())
+	}
+
+	// The offset of other timezones is kept
+	zone := time.FixedZone("UTC+2", 2*60*60)
+	inZone := time.Date(2020, 1, 2, 10, 30, 0, 0, zone)
+	value, err = tasks.ReflectValue("time.Time", roundTrip(inZone))
+	if assert.NoError(t, err) {
+		reflected := value.Interface().(time.Time)
+		assert.True(t, inZone.Equal(reflected))
+		_, offset := reflected.Zone()
+		assert.Equal(t, 2*60*60, offset)
+		assert.Equal(t, 10, reflected.Hour())
+	}
+
+	// Pointers, including nil ones
+	value, err = tasks.ReflectValue("*time.Time", roundTrip(&inZone))
+	if assert.NoError(t, err) {
+		assert.True(t, inZone.Equal(*value.Interface().(*time.Time)))
+	}
+	value, err = tasks.ReflectValue("*time.Time", roundTrip((*time.Time)(nil)))
+	if assert.NoError(t, err) {
+		assert.Nil(t, value.Interface().(*time.Time))
+	}
+
+	// Times which have not been encoded, e.g. by the eager broker
+	value, err = tasks.ReflectValue("time.Time", inZone)
+	if assert.NoError(t, err) {
+		assert.Equal(t, inZone, value.Interface())
+	}
+
+	// Slices and maps of times
+	value, err = tasks.ReflectValue("[]time.Time", roundTrip([]time.Time{inZone}))
+	if assert.NoError(t, err) && assert.Len(t, value.Interface(), 1) {
+		assert.True(t, inZone.Equal(value.Interface().([]time.Time)[0]))
+	}
+	value, err = tasks.ReflectValue("map[string]time.Time", roundTrip(map[string]time.Time{"at": inZone}))
+	if assert.NoError(t, err) {
+		assert.True(t, inZone.Equal(value.Interface().(map[string]time.Time)["at"]))
+	}
+
+	argType, err := tasks.InferArgType(inZone)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "time.Time", argType)
+	}
+
+	_, err = tasks.ReflectValue("time.Time", "yesterday")
+	assert.EqualError(t, err, "yesterday is not time.Time")
+	_, err = tasks.ReflectValue("time.Time", nil)
+	assert.EqualError(t, err, "<nil> is not time.Time")
+
+	_, err = tasks.RegisterType(time.Time{})
+	assert.EqualError(t, err, "time.Time is already supported")
+}
+
+func TestReflectValueUnsupportedComposite(t *testing.T) {
+	t.Parallel()
+
+	_, err := tasks.ReflectValue("map[int]string", map[string]interface{}{"1": "foo"})
+	assert.EqualError(t, err, "map[int]string is not one of supported types")
+
+	_, err = tasks.ReflectValue("[][]complex64", []interface{}{})
+	assert.EqualError(t, err, "[][]complex64 is not one of supported types")
+
+	_, err = tasks.ReflectValue("map[string]int", []interface{}{json.Number("1")})
+	assert.Error(t, err)
+}
+
+func TestReflectTaskError(t *testing.T) {
+	t.Parallel()
+
+	expected := tasks.TaskError{Message: "oops", TaskName: "add", TaskUUID: "task_1", Retries: 2}
+
+	value, err := tasks.ReflectValue("tasks.TaskError", expected)
+	if assert.NoError(t, err) {
+		assert.Equal(t, expected, value.Interface())
+	}
+
+	// Decoded from JSON
+	value, err = tasks.ReflectValue("tasks.TaskError", map[string]interface{}{
+		"Message":  "oops",
+		"TaskName": "add",
+		"TaskUUID": "task_1",
+		"Retries":  json.Number("2"),
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, expected, value.Interface())
+	}
+
+	_, err = tasks.ReflectValue("tasks.TaskError", "oops")
+	assert.Error(t, err)
+}
+
+type reflectTestUser struct {
+	Name string
+	Age  int64
+}
+
+func TestRegisterType(t *testing.T) {
+	t.Parallel()
+
+	valueType, err := tasks.RegisterType(&reflectTestUser{})
+	assert.NoError(t, err)
+	assert.Equal(t, "tasks_test.reflectTestUser", valueType)
+
+	_, err = tasks.RegisterType(int64(1))
+	assert.Error(t, err)
+	_, err = tasks.RegisterType(nil)
+	assert.Error(t, err)
+
+	expected := reflectTestUser{Name: "Alice", Age: 30}
+	decoded := map[string]interface{}{"Name": "Alice", "Age": json.Number("30")}
+
+	value, err := tasks.ReflectValue("tasks_test.reflectTestUser", decoded)
+	if assert.NoError(t, err) {
+		assert.Equal(t, expected, value.Interface())
+	}
+
+	value, err = tasks.ReflectValue("*tasks_test.reflectTestUser", decoded)
+	if assert.NoError(t, err) {
+		assert.Equal(t, &expected, value.Interface())
+	}
+
+	value, err = tasks.ReflectValue("*tasks_test.reflectTestUser", nil)
+	if assert.NoError(t, err) {
+		assert.Nil(t, value.Interface())
+	}
+
+	_, err = tasks.ReflectValue("tasks_test.reflectTestUser", "Alice")
+	assert.Error(t, err)
+
+	argType, err := tasks.InferArgType(expected)
+	assert.NoError(t, err)
+	assert.Equal(t, "tasks_test.reflectTestUser", argType)
+}
@@ -1,7 +1,11 @@
 package tasks
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
 	"time"
 
 	"github.com/satori/go.uuid"
@@ -9,9 +13,9 @@ import (
 
 // Arg represents a single argument passed to invocation fo a task
 type Arg struct {
-	Name  string      `bson:"name"`
-	Type  string      `bson:"type"`
-	Value interface{} `bson:"value"`
+	Name  string      `bson:"name" json:"Name"`
+	Type  string      `bson:"type" json:"Type"`
+	Value interface{} `bson:"value" json:"Value"`
 }
 
 // Headers represents the headers which should be used to direct the task
@@ -41,22 +45,88 @@ func (h Headers) ForeachKey(handler func(key, val string) error) error {
 	return nil
 }
 
+// Count returns the header holding a count, e.g. of retries, or 0 if it is
+// not set. Headers decoded from JSON hold numbers as json.Number or float64,
+// decoded from protobuf as int64.
+func (h Headers) Count(key string) int {
+	switch value := h[key].(type) {
+	case int:
+		return value
+	case int64:
+		return int(value)
+	case float64:
+		return int(value)
+	case json.Number:
+		n, _ := value.Int64()
+		return int(n)
+	}
+	return 0
+}
+
+type headersContextKey struct{}
+
+// ContextWithHeaders returns a copy of the context carrying the task headers
+func ContextWithHeaders(ctx context.Context, headers Headers) context.Context {
+	return context.WithValue(ctx, headersContextKey{}, headers)
+}
+
+// HeadersFromContext returns the headers of the task being processed, it can
+// be used inside tasks which accept context.Context as their first argument
+func HeadersFromContext(ctx context.Context) Headers {
+	headers, _ := ctx.Value(headersContextKey{}).(Headers)
+	return headers
+}
+
+const (
+	// RetryBackoffFibonacci spaces out retries along the Fibonacci sequence,
+	// retrying after the next Fibonacci number of seconds above RetryTimeout
+	RetryBackoffFibonacci = "fibonacci"
+	// RetryBackoffExponential retries after RetryTimeout seconds first and
+	// doubles the timeout for each further retry
+	RetryBackoffExponential = "exponential"
+)
+
 // Signature represents a single task invocation
 type Signature struct {
-	UUID           string
-	Name           string
-	RoutingKey     string
-	ETA            *time.Time
-	GroupUUID      string
-	GroupTaskCount int
-	Args           []Arg
-	Headers        Headers
-	Immutable      bool
-	RetryCount     int
-	RetryTimeout   int
-	OnSuccess      []*Signature
-	OnError        []*Signature
-	ChordCallback  *Signature
+	UUID       string     `json:"UUID"`
+	Name       string     `json:"Name"`
+	RoutingKey string     `json:"RoutingKey"`
+	ETA        *time.Time `json:"ETA"`
+	// ExpiresAt when set makes workers drop the task in the EXPIRED state
+	// instead of processing it if it has not started by that time
+	ExpiresAt      *time.Time `json:"ExpiresAt,omitempty"`
+	GroupUUID      string     `json:"GroupUUID"`
+	GroupTaskCount int        `json:"GroupTaskCount"`
+	Args           []Arg      `json:"Args"`
+	Headers        Headers    `json:"Headers"`
+	Immutable      bool       `json:"Immutable"`
+	RetryCount     int        `json:"RetryCount"`
+	RetryTimeout   int        `json:"RetryTimeout"`
+	// RetryBackoff is how RetryTimeout grows between retries, it is one of
+	// RetryBackoffFibonacci (the default) or RetryBackoffExponential
+	RetryBackoff string `json:"RetryBackoff,omitempty"`
+	// TimeoutSeconds when greater than zero cancels the task context after the
+	// given number of seconds and fails the task if it has not finished by then
+	TimeoutSeconds int `json:"TimeoutSeconds"`
+	// Priority of the task, tasks with higher priority are consumed first,
+	// only supported by the AMQP broker with max priority configured
+	Priority      uint8        `json:"Priority"`
+	OnSuccess     []*Signature `json:"OnSuccess"`
+	OnError       []*Signature `json:"OnError"`
+	ChordCallback *Signature   `json:"ChordCallback"`
+	// ChordOnPartialFailure and ChordErrorCallback are set on the group tasks
+	// of a chord from its OnPartialFailure and ErrorCallback when it is sent
+	ChordOnPartialFailure string     `json:"ChordOnPartialFailure,omitempty"`
+	ChordErrorCallback    *Signature `json:"ChordErrorCallback,omitempty"`
+	// WebhookURL when set is notified with an HTTP POST once the task
+	// succeeds or fails, see config.WebhookConfig
+	WebhookURL string `json:"WebhookURL,omitempty"`
+	// WorkflowUUID is shared by all tasks of a chain, group, chord or nested
+	// workflow, it is set when the workflow is sent, see Server.RevokeWorkflow
+	WorkflowUUID string `json:"WorkflowUUID,omitempty"`
+	// EstimatedMemoryBytes is how much memory the task is expected to use
+	// while running, it counts against the MemoryBudgetBytes of workers
+	EstimatedMemoryBytes int `json:"EstimatedMemoryBytes,omitempty"`
 }
 
 // NewSignature creates a new task signature
@@ -74,3 +144,73 @@ func NewSignature(name string, args []Arg) (*Signature, error) {
 		Args: args,
 	}, nil
 }
+
+// WithRetries makes the task retried up to n times once it fails, spaced out
+// along the Fibonacci sequence from baseSeconds, i.e. the first retry waits
+// for the next Fibonacci number greater than baseSeconds, unless the backoff
+// is exponential. It returns the signature so calls can be chained.
+func (s *Signature) WithRetries(n, baseSeconds int) *Signature {
+	s.RetryCount = n
+	s.RetryTimeout = baseSeconds
+	return s
+}
+
+// WithExponentialBackoff makes retries of the task wait RetryTimeout seconds
+// before the first retry and twice as long before each further one. It
+// returns the signature so calls can be chained.
+func (s *Signature) WithExponentialBackoff() *Signature {
+	s.RetryBackoff = RetryBackoffExponential
+	return s
+}
+
+// WithDelay delays the task by d from now by setting its ETA, it returns the
+// signature so calls can be chained
+func (s *Signature) WithDelay(d time.Duration) *Signature {
+	eta := time.Now().UTC().Add(d)
+	s.ETA = &eta
+	return s
+}
+
+// InferArgType returns type of the arg value the way ReflectValue expects it,
+// it returns an error if the value is nil or of unsupported type
+func InferArgType(value interface{}) (string, error) {
+	if value == nil {
+		return "", errors.New("Value is nil, its type cannot be inferred")
+	}
+
+	valueType := reflect.TypeOf(value).String()
+	if _, err := ReflectValue(valueType, value); err != nil {
+		return "", err
+	}
+	return valueType, nil
+}
+
+// InferArgTypes sets types of args which have been left empty, including args
+// of callbacks, as workers cannot infer them from JSON decoded values
+func InferArgTypes(signature *Signature) error {
+	for i, arg := range signature.Args {
+		if arg.Type != "" {
+			continue
+		}
+		argType, err := InferArgType(arg.Value)
+		if err != nil {
+			return fmt.Errorf("Arg %d of task %s: %s", i, signature.Name, err)
+		}
+		signature.Args[i].Type = argType
+	}
+
+	callbacks := append(append([]*Signature{}, signature.OnSuccess...), signature.OnError...)
+	if signature.ChordCallback != nil {
+		callbacks = append(callbacks, signature.ChordCallback)
+	}
+	if signature.ChordErrorCallback != nil {
+		callbacks = append(callbacks, signature.ChordErrorCallback)
+	}
+	for _, callback := range callbacks {
+		if err := InferArgTypes(callback); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package tasks_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInferArgTypes(t *testing.T) {
+	t.Parallel()
+
+	callback := &tasks.Signature{
+		Name: "callback",
+		Args: []tasks.Arg{{Value: "foo"}},
+	}
+	signature := &tasks.Signature{
+		Name: "task",
+		Args: []tasks.Arg{
+			{Value: 1},
+			{Type: "int64", Value: 2},
+			{Value: []float64{1.5}},
+		},
+		OnSuccess: []*tasks.Signature{callback},
+	}
+	if assert.NoError(t, tasks.InferArgTypes(signature)) {
+		assert.Equal(t, "int", signature.Args[0].Type)
+		assert.Equal(t, "int64", signature.Args[1].Type)
+		assert.Equal(t, "[]float64", signature.Args[2].Type)
+		assert.Equal(t, "string", callback.Args[0].Type)
+	}
+
+	err := tasks.InferArgTypes(&tasks.Signature{
+		Name: "task",
+		Args: []tasks.Arg{{Value: nil}},
+	})
+	if assert.Error(t, err) {
+		assert.Equal(t, "Arg 0 of task task: Value is nil, its type cannot be inferred", err.Error())
+	}
+
+	err = tasks.InferArgTypes(&tasks.Signature{
+		Name:    "task",
+		OnError: []*tasks.Signature{{Name: "callback", Args: []tasks.Arg{{Value: struct{}{}}}}},
+	})
+	assert.Error(t, err)
+}
+
+func TestSignatureJSON(t *testing.T) {
+	t.Parallel()
+
+	eta := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	expiresAt := eta.Add(time.Hour)
+	signature := &tasks.Signature{
+		UUID:           "task_1",
+		Name:           "add",
+		RoutingKey:     "machinery_tasks",
+		ETA:            &eta,
+		ExpiresAt:      &expiresAt,
+		GroupUUID:      "group_1",
+		GroupTaskCount: 2,
+		Args:           []tasks.Arg{{Name: "a", Type: "string", Value: "foo"}},
+		Headers:        tasks.Headers{"key": "value"},
+		Immutable:      true,
+		RetryCount:     3,
+		RetryTimeout:   5,
+		TimeoutSeconds: 10,
+		Priority:       1,
+		OnSuccess:      []*tasks.Signature{{UUID: "task_2", Name: "multiply"}},
+	}
+
+	// The wire format must not change, workers of other versions decode it
+	expected := `{"UUID":"task_1","Name":"add","RoutingKey":"machinery_tasks",` +
+		`"ETA":"2018-06-01T12:00:00Z","ExpiresAt":"2018-06-01T13:00:00Z",` +
+		`"GroupUUID":"group_1","GroupTaskCount":2,` +
+		`"Args":[{"Name":"a","Type":"string","Value":"foo"}],"Headers":{"key":"value"},` +
+		`"Immutable":true,"RetryCount":3,"RetryTimeout":5,"TimeoutSeconds":10,"Priority":1,` +
+		`"OnSuccess":[{"UUID":"task_2","Name":"multiply","RoutingKey":"","ETA":null,` +
+		`"GroupUUID":"","GroupTaskCount":0,"Args":null,"Headers":null,"Immutable":false,` +
+		`"RetryCount":0,"RetryTimeout":0,"TimeoutSeconds":0,"Priority":0,"OnSuccess":null,` +
+		`"OnError":null,"ChordCallback":null}],"OnError":null,"ChordCallback":null}`
+
+	encoded, err := json.Marshal(signature)
+	if assert.NoError(t, err) {
+		assert.Equal(t, expected, string(encoded))
+	}
+
+	decoded := new(tasks.Signature)
+	if assert.NoError(t, json.Unmarshal([]byte(expected), decoded)) {
+		assert.Equal(t, signature, decoded)
+	}
+}
+
+func TestHeadersCount(t *testing.T) {
+	t.Parallel()
+
+	headers := tasks.Headers{
+		"int":       2,
+		"float":     float64(3),
+		"number":    json.Number("4"),
+		"malformed": "5",
+	}
+	assert.Equal(t, 2, headers.Count("int"))
+	assert.Equal(t, 3, headers.Count("float"))
+	assert.Equal(t, 4, headers.Count("number"))
+	assert.Equal(t, 0, headers.Count("malformed"))
+	assert.Equal(t, 0, headers.Count("missing"))
+	assert.Equal(t, 0, tasks.Headers(nil).Count("missing"))
+}
+
+func TestSignatureWithRetries(t *testing.T) {
+	t.Parallel()
+
+	signature := &tasks.Signature{Name: "add"}
+	assert.True(t, signature == signature.WithRetries(3, 10))
+	assert.Equal(t, 3, signature.RetryCount)
+	assert.Equal(t, 10, signature.RetryTimeout)
+}
+
+func TestSignatureWithExponentialBackoff(t *testing.T) {
+	t.Parallel()
+
+	signature := &tasks.Signature{Name: "add"}
+	assert.True(t, signature == signature.WithExponentialBackoff())
+	assert.Equal(t, tasks.RetryBackoffExponential, signature.RetryBackoff)
+}
+
+func TestSignatureWithDelay(t *testing.T) {
+	t.Parallel()
+
+	signature := &tasks.Signature{Name: "add"}
+	assert.True(t, signature == signature.WithDelay(5*time.Second))
+	if assert.NotNil(t, signature.ETA) {
+		assert.WithinDuration(t, time.Now().Add(5*time.Second), *signature.ETA, time.Second)
+	}
+}
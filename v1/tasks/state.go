@@ -15,15 +15,20 @@ const (
 	StateSuccess = "SUCCESS"
 	// StateFailure - when processing of the task fails
 	StateFailure = "FAILURE"
+	// StateExpired - when the task was dropped because it expired before it started
+	StateExpired = "EXPIRED"
+	// StateRevoked - when the task was dropped because it was revoked before it started
+	StateRevoked = "REVOKED"
 )
 
 // TaskState represents a state of a task
 type TaskState struct {
-	TaskUUID  string        `bson:"_id"`
-	State     string        `bson:"state"`
-	Results   []*TaskResult `bson:"results"`
-	Error     string        `bson:"error"`
-	CreatedAt time.Time     `bson:"created_at"`
+	TaskUUID    string        `bson:"_id"`
+	State       string        `bson:"state"`
+	Results     []*TaskResult `bson:"results"`
+	Error       string        `bson:"error"`
+	CreatedAt   time.Time     `bson:"created_at"`
+	CompletedAt time.Time     `bson:"completed_at"`
 }
 
 // GroupMeta stores useful metadata about tasks within the same group
@@ -65,18 +70,38 @@ func NewStartedTaskState(signature *Signature) *TaskState {
 // NewSuccessTaskState ...
 func NewSuccessTaskState(signature *Signature, results []*TaskResult) *TaskState {
 	return &TaskState{
-		TaskUUID: signature.UUID,
-		State:    StateSuccess,
-		Results:  results,
+		TaskUUID:    signature.UUID,
+		State:       StateSuccess,
+		Results:     results,
+		CompletedAt: time.Now().UTC(),
 	}
 }
 
 // NewFailureTaskState ...
 func NewFailureTaskState(signature *Signature, err string) *TaskState {
 	return &TaskState{
-		TaskUUID: signature.UUID,
-		State:    StateFailure,
-		Error:    err,
+		TaskUUID:    signature.UUID,
+		State:       StateFailure,
+		Error:       err,
+		CompletedAt: time.Now().UTC(),
+	}
+}
+
+// NewExpiredTaskState ...
+func NewExpiredTaskState(signature *Signature) *TaskState {
+	return &TaskState{
+		TaskUUID:    signature.UUID,
+		State:       StateExpired,
+		CompletedAt: time.Now().UTC(),
+	}
+}
+
+// NewRevokedTaskState ...
+func NewRevokedTaskState(signature *Signature) *TaskState {
+	return &TaskState{
+		TaskUUID:    signature.UUID,
+		State:       StateRevoked,
+		CompletedAt: time.Now().UTC(),
 	}
 }
 
@@ -88,10 +113,11 @@ func NewRetryTaskState(signature *Signature) *TaskState {
 	}
 }
 
-// IsCompleted returns true if state is SUCCESS or FAILURE,
-// i.e. the task has finished processing and either succeeded or failed.
+// IsCompleted returns true if state is SUCCESS, FAILURE, EXPIRED or REVOKED,
+// i.e. the task has finished processing and either succeeded or failed,
+// or it was dropped without being processed at all.
 func (taskState *TaskState) IsCompleted() bool {
-	return taskState.IsSuccess() || taskState.IsFailure()
+	return taskState.IsSuccess() || taskState.IsFailure() || taskState.IsExpired() || taskState.IsRevoked()
 }
 
 // IsSuccess returns true if state is SUCCESS
@@ -103,3 +129,13 @@ func (taskState *TaskState) IsSuccess() bool {
 func (taskState *TaskState) IsFailure() bool {
 	return taskState.State == StateFailure
 }
+
+// IsExpired returns true if state is EXPIRED
+func (taskState *TaskState) IsExpired() bool {
+	return taskState.State == StateExpired
+}
+
+// IsRevoked returns true if state is REVOKED
+func (taskState *TaskState) IsRevoked() bool {
+	return taskState.State == StateRevoked
+}
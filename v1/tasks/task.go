@@ -2,6 +2,7 @@ package tasks
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
@@ -20,15 +21,31 @@ var ErrTaskPanicked = errors.New("Invoking task caused a panic")
 // Task wraps a signature and methods used to reflect task arguments and
 // return values after invoking the task
 type Task struct {
-	TaskFunc   reflect.Value
-	UseContext bool
-	Context    context.Context
-	Args       []reflect.Value
+	// Name is the name the task is registered under, errors of the task
+	// mention it
+	Name           string
+	TaskFunc       reflect.Value
+	UseContext     bool
+	UseTaskContext bool
+	Context        context.Context
+	// TaskContext is passed to tasks taking *TaskContext as their first
+	// argument, using Context as its context
+	TaskContext *TaskContext
+	Args        []reflect.Value
+
+	// rawHandler is called instead of TaskFunc for raw handlers
+	rawHandler RawHandler
+	rawArgs    json.RawMessage
 }
 
 // New tries to use reflection to convert the function and arguments
-// into a reflect.Value and prepare it for invocation
+// into a reflect.Value and prepare it for invocation, a RawHandler gets its
+// arguments encoded as JSON instead
 func New(taskFunc interface{}, args []Arg) (*Task, error) {
+	if handler, ok := asRawHandler(taskFunc); ok {
+		return newRawTask(handler, args)
+	}
+
 	task := &Task{
 		TaskFunc: reflect.ValueOf(taskFunc),
 		Context:  context.Background(),
@@ -40,10 +57,17 @@ func New(taskFunc interface{}, args []Arg) (*Task, error) {
 		if IsContextType(arg0Type) {
 			task.UseContext = true
 		}
+		if IsTaskContextType(arg0Type) {
+			task.UseTaskContext = true
+		}
 	}
 
 	if err := task.ReflectArgs(args); err != nil {
-		return nil, fmt.Errorf("Reflect task args error: %s", err)
+		return nil, fmt.Errorf("Reflect task args error: %w", err)
+	}
+
+	if err := task.validateArgs(); err != nil {
+		return nil, fmt.Errorf("Validate task args error: %w", err)
 	}
 
 	return task, nil
@@ -53,7 +77,7 @@ func New(taskFunc interface{}, args []Arg) (*Task, error) {
 //
 // `err` is set in the return value in two cases:
 // 1. The reflected function invocation panics (e.g. due to a mismatched
-//    argument list).
+//    argument list), the error then wraps ErrTaskPanicked.
 // 2. The task func itself returns a non-nil error.
 func (t *Task) Call() (taskResults []*TaskResult, err error) {
 	// retrieve the span from the task's context and finish it as soon as this function returns
@@ -64,14 +88,7 @@ func (t *Task) Call() (taskResults []*TaskResult, err error) {
 	defer func() {
 		// Recover from panic and set err.
 		if e := recover(); e != nil {
-			switch e := e.(type) {
-			default:
-				err = ErrTaskPanicked
-			case error:
-				err = e
-			case string:
-				err = errors.New(e)
-			}
+			err = fmt.Errorf("%w: task %s: %v", ErrTaskPanicked, t.Name, e)
 
 			// mark the span as failed and dump the error and stack trace to the span
 			if span := opentracing.SpanFromContext(t.Context); span != nil {
@@ -87,6 +104,10 @@ func (t *Task) Call() (taskResults []*TaskResult, err error) {
 		}
 	}()
 
+	if t.rawHandler != nil {
+		return t.callRaw()
+	}
+
 	args := t.Args
 
 	if t.UseContext {
@@ -94,9 +115,23 @@ func (t *Task) Call() (taskResults []*TaskResult, err error) {
 		args = append([]reflect.Value{ctxValue}, args...)
 	}
 
+	if t.UseTaskContext {
+		taskContext := &TaskContext{}
+		if t.TaskContext != nil {
+			taskContext = t.TaskContext
+		}
+		args = append([]reflect.Value{reflect.ValueOf(taskContext.withContext(t.Context))}, args...)
+	}
+
 	// Invoke the task
 	results := t.TaskFunc.Call(args)
 
+	// If the context deadline passed while the task was running, the task
+	// timed out and whatever it returned is discarded
+	if t.Context.Err() == context.DeadlineExceeded {
+		return nil, t.Context.Err()
+	}
+
 	// Task must return at least a value
 	if len(results) == 0 {
 		return nil, ErrTaskReturnsNoValue
@@ -109,45 +144,62 @@ func (t *Task) Call() (taskResults []*TaskResult, err error) {
 	// is not the case, return error message, otherwise propagate the task error
 	// to the caller
 	if !lastResult.IsNil() {
-		// If the result implements Retriable interface, return instance of Retriable
-		retriableErrorInterface := reflect.TypeOf((*Retriable)(nil)).Elem()
-		if lastResult.Type().Implements(retriableErrorInterface) {
-			return nil, lastResult.Interface().(ErrRetryTaskLater)
-		}
-
-		// Otherwise, check that the result implements the standard error interface,
-		// if not, return ErrLastReturnValueMustBeError error
+		// Check that the result implements the standard error interface, if not,
+		// return ErrLastReturnValueMustBeError error. Retriable errors are
+		// propagated as they are, the worker decides when to retry them
 		errorInterface := reflect.TypeOf((*error)(nil)).Elem()
 		if !lastResult.Type().Implements(errorInterface) {
 			return nil, ErrLastReturnValueMustBeError
 		}
 
-		// Return the standard error
-		return nil, lastResult.Interface().(error)
+		// Mark the span as failed and return the standard error
+		err = lastResult.Interface().(error)
+		if span := opentracing.SpanFromContext(t.Context); span != nil {
+			opentracing_ext.Error.Set(span, true)
+			span.LogFields(opentracing_log.Error(err))
+		}
+		return nil, err
 	}
 
 	// Convert reflect values to task results
 	taskResults = make([]*TaskResult, len(results)-1)
 	for i := 0; i < len(results)-1; i++ {
-		val := results[i].Interface()
-		typeStr := reflect.TypeOf(val).String()
-		taskResults[i] = &TaskResult{
-			Type:  typeStr,
-			Value: val,
-		}
+		taskResults[i] = newTaskResult(results[i])
 	}
 
 	return taskResults, err
 }
 
-// ReflectArgs converts []TaskArg to []reflect.Value
+// newTaskResult converts a value returned by the task to TaskResult. A nil
+// interface has no type of its own, neither does a nil pointer, slice or map
+// of a type which cannot be reflected back, so they are converted to nil.
+func newTaskResult(result reflect.Value) *TaskResult {
+	switch result.Kind() {
+	case reflect.Interface, reflect.Ptr, reflect.Slice, reflect.Map, reflect.Chan, reflect.Func:
+		if result.IsNil() {
+			if _, err := ReflectValue(result.Type().String(), nil); err != nil {
+				return &TaskResult{Type: nilType.String()}
+			}
+			return &TaskResult{Type: result.Type().String(), Value: result.Interface()}
+		}
+	}
+
+	val := result.Interface()
+	return &TaskResult{
+		Type:  reflect.TypeOf(val).String(),
+		Value: val,
+	}
+}
+
+// ReflectArgs converts []TaskArg to []reflect.Value, errors name the index and
+// the declared type of the arg which could not be converted
 func (t *Task) ReflectArgs(args []Arg) error {
 	argValues := make([]reflect.Value, len(args))
 
 	for i, arg := range args {
 		argValue, err := ReflectValue(arg.Type, arg.Value)
 		if err != nil {
-			return err
+			return fmt.Errorf("Arg %d (type %q): %w", i, arg.Type, err)
 		}
 		argValues[i] = argValue
 	}
@@ -155,3 +207,40 @@ func (t *Task) ReflectArgs(args []Arg) error {
 	t.Args = argValues
 	return nil
 }
+
+// validateArgs makes sure reflected args match the parameters of the task
+// function, so a malformed signature fails with a descriptive error instead
+// of a panic when the task is invoked
+func (t *Task) validateArgs() error {
+	taskFuncType := t.TaskFunc.Type()
+
+	// Context is injected by the worker, it is not passed as an arg
+	offset := 0
+	if t.UseContext || t.UseTaskContext {
+		offset = 1
+	}
+	numIn := taskFuncType.NumIn() - offset
+
+	if taskFuncType.IsVariadic() {
+		if len(t.Args) < numIn-1 {
+			return fmt.Errorf("Task expects at least %d args, got %d", numIn-1, len(t.Args))
+		}
+	} else if len(t.Args) != numIn {
+		return fmt.Errorf("Task expects %d args, got %d", numIn, len(t.Args))
+	}
+
+	for i, arg := range t.Args {
+		var inType reflect.Type
+		if taskFuncType.IsVariadic() && i >= numIn-1 {
+			inType = taskFuncType.In(taskFuncType.NumIn() - 1).Elem()
+		} else {
+			inType = taskFuncType.In(i + offset)
+		}
+
+		if !arg.Type().AssignableTo(inType) {
+			return fmt.Errorf("Arg %d of type %s is not assignable to %s", i, arg.Type(), inType)
+		}
+	}
+
+	return nil
+}
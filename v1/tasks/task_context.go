@@ -0,0 +1,82 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"reflect"
+)
+
+// ErrDispatchNotSupported is returned by TaskContext.Dispatch when the task
+// context has no way of sending tasks
+var ErrDispatchNotSupported = errors.New("Task context does not support dispatching tasks")
+
+var taskContextType = reflect.TypeOf((*TaskContext)(nil))
+
+// DispatchFunc sends a task on behalf of the task being processed
+type DispatchFunc func(ctx context.Context, signature *Signature) error
+
+// DeliveryInfo describes the message a task was delivered in, brokers fill
+// in what they know about it and leave the rest empty
+type DeliveryInfo struct {
+	// Headers of the message, AMQP headers or SQS message attributes
+	Headers map[string]interface{}
+	// Redelivered is true if the message has been delivered before
+	Redelivered bool
+	// RoutingKey the message was published with, or the queue it was
+	// consumed from if the broker has no routing keys
+	RoutingKey string
+	// DeliveryCount is how many times the message has been delivered,
+	// including this time, 0 if the broker does not count deliveries
+	DeliveryCount int
+}
+
+// TaskContext is injected into tasks whose first parameter is *TaskContext,
+// like context.Context but carrying the task being processed and a way of
+// sending follow-up tasks without a global server
+type TaskContext struct {
+	context.Context
+	// UUID, Name and Headers of the task being processed
+	UUID    string
+	Name    string
+	Headers Headers
+	// Delivery describes the message the task was delivered in, it is empty
+	// unless the broker passes it to the worker
+	Delivery DeliveryInfo
+
+	dispatch DispatchFunc
+}
+
+// NewTaskContext returns a task context for the task, tasks sent with
+// Dispatch are passed to the dispatch function. Workers create task contexts
+// sending tasks through their server, tests can pass a function recording the
+// tasks instead.
+func NewTaskContext(ctx context.Context, signature *Signature, dispatch DispatchFunc) *TaskContext {
+	return &TaskContext{
+		Context:  ctx,
+		UUID:     signature.UUID,
+		Name:     signature.Name,
+		Headers:  signature.Headers,
+		dispatch: dispatch,
+	}
+}
+
+// Dispatch sends a follow-up task, it carries the headers and trace of the
+// task being processed
+func (c *TaskContext) Dispatch(signature *Signature) error {
+	if c.dispatch == nil {
+		return ErrDispatchNotSupported
+	}
+	return c.dispatch(c.Context, signature)
+}
+
+// withContext returns a copy of the task context using ctx
+func (c *TaskContext) withContext(ctx context.Context) *TaskContext {
+	taskContext := *c
+	taskContext.Context = ctx
+	return &taskContext
+}
+
+// IsTaskContextType checks to see if the type is a *TaskContext
+func IsTaskContextType(t reflect.Type) bool {
+	return t == taskContextType
+}
@@ -2,6 +2,7 @@ package tasks_test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"math"
 	"testing"
@@ -57,6 +58,59 @@ func TestTaskReflectArgs(t *testing.T) {
 	assert.Equal(t, "[]int64", task.Args[0].Type().String())
 }
 
+func TestTaskReflectArgsError(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		args []tasks.Arg
+		err  string
+	}{
+		{
+			name: "conversion",
+			args: []tasks.Arg{
+				{Type: "int64", Value: 1},
+				{Type: "int", Value: "foo"},
+			},
+			err: `Arg 1 (type "int"): foo is not int`,
+		},
+		{
+			name: "overflow",
+			args: []tasks.Arg{
+				{Type: "string", Value: "foo"},
+				{Type: "int64", Value: 1},
+				{Type: "int8", Value: 300},
+			},
+			err: `Arg 2 (type "int8"): 300 overflows int8`,
+		},
+		{
+			name: "unsupported type",
+			args: []tasks.Arg{{Type: "complex128", Value: 1}},
+			err:  `Arg 0 (type "complex128"): complex128 is not one of supported types`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			task := new(tasks.Task)
+			assert.EqualError(t, task.ReflectArgs(testCase.args), testCase.err)
+		})
+	}
+
+	// Errors of tasks.New name the failing arg too
+	_, err := tasks.New(func(a, b int64) error { return nil }, []tasks.Arg{
+		{Type: "int64", Value: 1},
+		{Type: "int64", Value: "two"},
+	})
+	assert.EqualError(t, err, `Reflect task args error: Arg 1 (type "int64"): two is not int64`)
+
+	// The conversion error is wrapped
+	_, err = tasks.New(func(a complex128) error { return nil }, []tasks.Arg{{Type: "complex128", Value: 1}})
+	var unsupported tasks.ErrUnsupportedType
+	assert.True(t, errors.As(err, &unsupported), err)
+}
+
 func TestTaskCallInvalidArgRobustnessError(t *testing.T) {
 	t.Parallel()
 
@@ -68,13 +122,41 @@ func TestTaskCallInvalidArgRobustnessError(t *testing.T) {
 		{Type: "bool", Value: true},
 	}
 
+	// Invalid args are caught before the task is invoked
 	task, err := tasks.New(f, args)
+	assert.Nil(t, task)
+	assert.EqualError(t, err, "Validate task args error: Arg 0 of type bool is not assignable to int")
+}
+
+func TestTaskNewArgCountError(t *testing.T) {
+	t.Parallel()
+
+	f := func(ctx context.Context, x, y int64) error { return nil }
+
+	task, err := tasks.New(f, []tasks.Arg{{Type: "int64", Value: int64(1)}})
+	assert.Nil(t, task)
+	assert.EqualError(t, err, "Validate task args error: Task expects 2 args, got 1")
+
+	variadic := func(prefix string, values ...int64) error { return nil }
+
+	task, err = tasks.New(variadic, []tasks.Arg{})
+	assert.Nil(t, task)
+	assert.EqualError(t, err, "Validate task args error: Task expects at least 1 args, got 0")
+
+	task, err = tasks.New(variadic, []tasks.Arg{
+		{Type: "string", Value: "foo"},
+		{Type: "int64", Value: int64(1)},
+		{Type: "int64", Value: int64(2)},
+	})
 	assert.NoError(t, err)
+	assert.NotNil(t, task)
 
-	// Invoke TryCall and validate error handling
-	results, err := task.Call()
-	assert.Equal(t, "reflect: Call using bool as type int", err.Error())
-	assert.Nil(t, results)
+	task, err = tasks.New(variadic, []tasks.Arg{
+		{Type: "string", Value: "foo"},
+		{Type: "string", Value: "bar"},
+	})
+	assert.Nil(t, task)
+	assert.EqualError(t, err, "Validate task args error: Arg 1 of type string is not assignable to int64")
 }
 
 func TestTaskCallInterfaceValuedResult(t *testing.T) {
@@ -92,6 +174,40 @@ func TestTaskCallInterfaceValuedResult(t *testing.T) {
 	assert.Equal(t, math.Pi, taskResults[0].Value)
 }
 
+func TestTaskCallNilResults(t *testing.T) {
+	t.Parallel()
+
+	type unregistered struct{}
+
+	testCases := []struct {
+		name         string
+		taskFunc     interface{}
+		expectedType string
+	}{
+		{"nil interface", func() (interface{}, error) { return nil, nil }, "interface {}"},
+		{"nil pointer", func() (*unregistered, error) { return nil, nil }, "interface {}"},
+		{"nil error", func() (error, error) { return nil, nil }, "interface {}"},
+		{"nil slice", func() ([]int64, error) { return nil, nil }, "[]int64"},
+	}
+
+	for _, testCase := range testCases {
+		task, err := tasks.New(testCase.taskFunc, []tasks.Arg{})
+		assert.NoError(t, err)
+
+		taskResults, err := task.Call()
+		if assert.NoError(t, err, testCase.name) && assert.Len(t, taskResults, 1, testCase.name) {
+			assert.Equal(t, testCase.expectedType, taskResults[0].Type, testCase.name)
+			assert.Nil(t, taskResults[0].Value, testCase.name)
+		}
+
+		// Results can be reflected back, e.g. when passed to callbacks
+		results, err := tasks.ReflectTaskResults(taskResults)
+		if assert.NoError(t, err, testCase.name) {
+			assert.Empty(t, results[0].Interface(), testCase.name)
+		}
+	}
+}
+
 func TestTaskCallWithContext(t *testing.T) {
 	t.Parallel()
 
@@ -106,3 +222,189 @@ func TestTaskCallWithContext(t *testing.T) {
 	assert.Equal(t, "float64", taskResults[0].Type)
 	assert.Equal(t, math.Pi, taskResults[0].Value)
 }
+
+func TestTaskCallWithTaskContext(t *testing.T) {
+	t.Parallel()
+
+	f := func(c *tasks.TaskContext, x int64) error {
+		assert.Equal(t, "task_1", c.UUID)
+		assert.Equal(t, "parent", c.Name)
+		return c.Dispatch(&tasks.Signature{Name: "child", Args: []tasks.Arg{{Type: "int64", Value: x}}})
+	}
+	task, err := tasks.New(f, []tasks.Arg{{Type: "int64", Value: int64(1)}})
+	assert.NoError(t, err)
+
+	type key struct{}
+	var dispatched []*tasks.Signature
+	task.Context = context.WithValue(task.Context, key{}, "value")
+	task.TaskContext = tasks.NewTaskContext(context.Background(), &tasks.Signature{UUID: "task_1", Name: "parent"},
+		func(ctx context.Context, signature *tasks.Signature) error {
+			// The task context uses the context of the task
+			assert.Equal(t, "value", ctx.Value(key{}))
+			dispatched = append(dispatched, signature)
+			return nil
+		})
+	_, err = task.Call()
+	assert.NoError(t, err)
+	if assert.Len(t, dispatched, 1) {
+		assert.Equal(t, "child", dispatched[0].Name)
+	}
+
+	// Without a task context tasks cannot be dispatched
+	f = func(c *tasks.TaskContext, x int64) error {
+		return c.Dispatch(&tasks.Signature{Name: "child"})
+	}
+	task, err = tasks.New(f, []tasks.Arg{{Type: "int64", Value: int64(1)}})
+	assert.NoError(t, err)
+	_, err = task.Call()
+	assert.Equal(t, tasks.ErrDispatchNotSupported, err)
+}
+
+func TestTaskCallPanic(t *testing.T) {
+	t.Parallel()
+
+	f := func() (interface{}, error) {
+		var m map[string]int
+		m["foo"] = 1
+		return nil, nil
+	}
+	task, err := tasks.New(f, []tasks.Arg{})
+	assert.NoError(t, err)
+	task.Name = "panicking_task"
+
+	taskResults, err := task.Call()
+	assert.Nil(t, taskResults)
+	assert.EqualError(t, err, "Invoking task caused a panic: task panicking_task: assignment to entry in nil map")
+	assert.True(t, errors.Is(err, tasks.ErrTaskPanicked))
+
+	f = func() (interface{}, error) {
+		panic(42)
+	}
+	task, err = tasks.New(f, []tasks.Arg{})
+	assert.NoError(t, err)
+	task.Name = "panicking_task"
+
+	taskResults, err = task.Call()
+	assert.Nil(t, taskResults)
+	assert.EqualError(t, err, "Invoking task caused a panic: task panicking_task: 42")
+	assert.True(t, errors.Is(err, tasks.ErrTaskPanicked))
+
+	f = func() (interface{}, error) {
+		panic(errors.New("oops"))
+	}
+	task, err = tasks.New(f, []tasks.Arg{})
+	assert.NoError(t, err)
+	task.Name = "panicking_task"
+
+	taskResults, err = task.Call()
+	assert.Nil(t, taskResults)
+	assert.EqualError(t, err, "Invoking task caused a panic: task panicking_task: oops")
+	assert.True(t, errors.Is(err, tasks.ErrTaskPanicked))
+}
+
+func TestTaskCallWithContextTimeout(t *testing.T) {
+	t.Parallel()
+
+	f := func(c context.Context) (interface{}, error) {
+		<-c.Done()
+		return math.Pi, nil
+	}
+	task, err := tasks.New(f, []tasks.Arg{})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(task.Context, 10*time.Millisecond)
+	defer cancel()
+	task.Context = ctx
+
+	taskResults, err := task.Call()
+	assert.Nil(t, taskResults)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestTaskCallRawHandler(t *testing.T) {
+	t.Parallel()
+
+	var received json.RawMessage
+	handler := func(args json.RawMessage) (interface{}, error) {
+		received = args
+		var values []int64
+		if err := json.Unmarshal(args, &values); err != nil {
+			return nil, err
+		}
+		return values[0] + values[1], nil
+	}
+
+	task, err := tasks.New(handler, []tasks.Arg{
+		{Type: "int64", Value: json.Number("1")},
+		{Type: "int64", Value: json.Number("2")},
+	})
+	assert.NoError(t, err)
+	results, err := task.Call()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[1, 2]`, string(received))
+	assert.Equal(t, []*tasks.TaskResult{{Type: "int64", Value: int64(3)}}, results)
+
+	// Errors and panics fail the task like for other tasks
+	task, err = tasks.New(tasks.RawHandler(func(args json.RawMessage) (interface{}, error) {
+		return nil, errors.New("some error")
+	}), nil)
+	assert.NoError(t, err)
+	_, err = task.Call()
+	assert.EqualError(t, err, "some error")
+
+	task, err = tasks.New(tasks.RawHandler(func(args json.RawMessage) (interface{}, error) {
+		panic("oops")
+	}), nil)
+	assert.NoError(t, err)
+	task.Name = "raw_task"
+	_, err = task.Call()
+	assert.EqualError(t, err, "Invoking task caused a panic: task raw_task: oops")
+
+	// A nil result is no result at all
+	task, err = tasks.New(tasks.RawHandler(func(args json.RawMessage) (interface{}, error) {
+		return nil, nil
+	}), nil)
+	assert.NoError(t, err)
+	results, err = task.Call()
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func BenchmarkTaskCall(b *testing.B) {
+	args := []tasks.Arg{
+		{Type: "int64", Value: json.Number("1")},
+		{Type: "int64", Value: json.Number("2")},
+	}
+
+	b.Run("reflection", func(b *testing.B) {
+		add := func(x, y int64) (int64, error) { return x + y, nil }
+		for i := 0; i < b.N; i++ {
+			task, err := tasks.New(add, args)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := task.Call(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("raw", func(b *testing.B) {
+		add := tasks.RawHandler(func(args json.RawMessage) (interface{}, error) {
+			var values [2]int64
+			if err := json.Unmarshal(args, &values); err != nil {
+				return nil, err
+			}
+			return values[0] + values[1], nil
+		})
+		for i := 0; i < b.N; i++ {
+			task, err := tasks.New(add, args)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := task.Call(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
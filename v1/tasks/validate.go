@@ -3,6 +3,8 @@ package tasks
 import (
 	"errors"
 	"reflect"
+	"runtime"
+	"strings"
 )
 
 var (
@@ -40,3 +42,17 @@ func ValidateTask(task interface{}) error {
 
 	return nil
 }
+
+// TaskName returns default name of a task function, which is the function
+// name qualified by the last element of its package path, e.g. "tasks.Add"
+// for function Add of package github.com/foo/tasks
+func TaskName(task interface{}) (string, error) {
+	if reflect.TypeOf(task).Kind() != reflect.Func {
+		return "", ErrTaskMustBeFunc
+	}
+
+	name := runtime.FuncForPC(reflect.ValueOf(task).Pointer()).Name()
+	// Strip the package path and the suffix of method values
+	name = name[strings.LastIndex(name, "/")+1:]
+	return strings.TrimSuffix(name, "-fm"), nil
+}
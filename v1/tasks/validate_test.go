@@ -30,3 +30,28 @@ func TestValidateTask(t *testing.T) {
 	err = tasks.ValidateTask(validTask)
 	assert.NoError(t, err)
 }
+
+type taskStruct struct{}
+
+func (taskStruct) Add(a, b int64) (int64, error) {
+	return a + b, nil
+}
+
+func multiply(a, b int64) (int64, error) {
+	return a * b, nil
+}
+
+func TestTaskName(t *testing.T) {
+	t.Parallel()
+
+	name, err := tasks.TaskName(multiply)
+	assert.NoError(t, err)
+	assert.Equal(t, "tasks_test.multiply", name)
+
+	name, err = tasks.TaskName(taskStruct{}.Add)
+	assert.NoError(t, err)
+	assert.Equal(t, "tasks_test.taskStruct.Add", name)
+
+	_, err = tasks.TaskName("multiply")
+	assert.Equal(t, tasks.ErrTaskMustBeFunc, err)
+}
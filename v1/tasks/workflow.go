@@ -1,6 +1,7 @@
 package tasks
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/satori/go.uuid"
@@ -17,11 +18,31 @@ type Group struct {
 	Tasks     []*Signature
 }
 
+const (
+	// ChordFailFast sends the error callback of the chord as soon as a task of
+	// the group fails, the chord callback is not sent
+	ChordFailFast = "fail-fast"
+	// ChordSkip sends the chord callback with results of the successful tasks
+	// once all tasks of the group finished
+	ChordSkip = "skip"
+	// ChordWait sends the error callback of the chord with errors of all the
+	// failed tasks once all tasks of the group finished, or the chord
+	// callback if none of them failed
+	ChordWait = "wait"
+)
+
 // Chord adds an optional callback to the group to be executed
 // after all tasks in the group finished
 type Chord struct {
 	Group    *Group
 	Callback *Signature
+	// OnPartialFailure decides what happens when some tasks of the group
+	// fail, it is one of ChordFailFast (the default), ChordSkip or ChordWait
+	OnPartialFailure string
+	// ErrorCallback is sent instead of the callback when tasks of the group
+	// fail, with the error passed as the first argument like to OnError
+	// callbacks
+	ErrorCallback *Signature
 }
 
 // GetUUIDs returns slice of task UUIDS
@@ -33,6 +54,30 @@ func (group *Group) GetUUIDs() []string {
 	return taskUUIDs
 }
 
+// StatesGetter gets states of many tasks in a single round trip, it is
+// implemented by result backends (see backends.StatesGetter)
+type StatesGetter interface {
+	GetStates(taskUUIDs ...string) (map[string]*TaskState, error)
+}
+
+// Completed returns how many tasks of the group have completed, i.e. they
+// succeeded, failed, expired or were revoked, according to their states in
+// the result backend
+func (group *Group) Completed(backend StatesGetter) (int, error) {
+	taskStates, err := backend.GetStates(group.GetUUIDs()...)
+	if err != nil {
+		return 0, err
+	}
+
+	completed := 0
+	for _, taskState := range taskStates {
+		if taskState.IsCompleted() {
+			completed++
+		}
+	}
+	return completed, nil
+}
+
 // NewChain creates a new chain of tasks to be processed one by one, passing
 // results unless task signatures are set to be immutable
 func NewChain(signatures ...*Signature) (*Chain, error) {
@@ -115,3 +160,269 @@ func NewChord(group *Group, callback *Signature) (*Chord, error) {
 
 	return &Chord{Group: group, Callback: callback}, nil
 }
+
+// ValidateChordPolicy returns an error unless policy is empty or one of
+// ChordFailFast, ChordSkip or ChordWait
+func ValidateChordPolicy(policy string) error {
+	switch policy {
+	case "", ChordFailFast, ChordSkip, ChordWait:
+		return nil
+	}
+	return fmt.Errorf("Unknown chord partial failure policy: %s", policy)
+}
+
+// Workflow is a task signature, chain, group or chord. Workflows can be nested
+// in chains, groups and chords built by NewWorkflowChain, NewWorkflowGroup and
+// NewWorkflowChord
+type Workflow interface {
+	// Heads returns signatures which are sent to start the workflow
+	Heads() []*Signature
+	// Terminals returns signatures which complete the workflow
+	Terminals() []*Signature
+	// then makes next start once the workflow has completed
+	then(next Workflow) error
+}
+
+// Heads returns the signature itself
+func (signature *Signature) Heads() []*Signature {
+	return []*Signature{signature}
+}
+
+// Terminals returns the signature itself
+func (signature *Signature) Terminals() []*Signature {
+	return []*Signature{signature}
+}
+
+func (signature *Signature) then(next Workflow) error {
+	signature.OnSuccess = append(signature.OnSuccess, next.Heads()...)
+	return nil
+}
+
+// Heads returns the first task of the chain
+func (chain *Chain) Heads() []*Signature {
+	return chain.Tasks[:1]
+}
+
+// Terminals returns the last task of the chain
+func (chain *Chain) Terminals() []*Signature {
+	return chain.Tasks[len(chain.Tasks)-1:]
+}
+
+func (chain *Chain) then(next Workflow) error {
+	return chain.Tasks[len(chain.Tasks)-1].then(next)
+}
+
+// Heads returns all tasks of the group
+func (group *Group) Heads() []*Signature {
+	return group.Tasks
+}
+
+// Terminals returns all tasks of the group
+func (group *Group) Terminals() []*Signature {
+	return group.Tasks
+}
+
+func (group *Group) then(next Workflow) error {
+	return setChordCallback(group.Tasks, next)
+}
+
+// Heads returns all tasks of the chord group
+func (chord *Chord) Heads() []*Signature {
+	return chord.Group.Tasks
+}
+
+// Terminals returns the chord callback
+func (chord *Chord) Terminals() []*Signature {
+	return []*Signature{chord.Callback}
+}
+
+func (chord *Chord) then(next Workflow) error {
+	return chord.Callback.then(next)
+}
+
+// workflowChain is a chain of nested workflows
+type workflowChain struct {
+	steps []Workflow
+}
+
+// NewWorkflowChain creates a chain of workflows to be processed one after
+// another, a group can only be followed by a workflow starting with a single
+// task which then becomes the chord callback of the group
+func NewWorkflowChain(steps ...Workflow) (Workflow, error) {
+	if len(steps) == 0 {
+		return nil, errors.New("Workflow chain requires at least one step")
+	}
+
+	for i := 1; i < len(steps); i++ {
+		if err := steps[i-1].then(steps[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return &workflowChain{steps: steps}, nil
+}
+
+func (chain *workflowChain) Heads() []*Signature {
+	return chain.steps[0].Heads()
+}
+
+func (chain *workflowChain) Terminals() []*Signature {
+	return chain.steps[len(chain.steps)-1].Terminals()
+}
+
+func (chain *workflowChain) then(next Workflow) error {
+	return chain.steps[len(chain.steps)-1].then(next)
+}
+
+// workflowGroup is a group of nested workflows processed in parallel
+type workflowGroup struct {
+	members []Workflow
+}
+
+// NewWorkflowGroup creates a group of workflows to be processed in parallel,
+// the group completes once terminal tasks of all its workflows have completed
+func NewWorkflowGroup(members ...Workflow) (Workflow, error) {
+	if len(members) == 0 {
+		return nil, errors.New("Workflow group requires at least one member")
+	}
+
+	groupUUID, err := uuid.NewV4()
+	if err != nil {
+		return nil, fmt.Errorf("Error generating group uuid: %s", err.Error())
+	}
+
+	group := &workflowGroup{members: members}
+	terminals := group.Terminals()
+	for _, signature := range terminals {
+		signature.GroupUUID = fmt.Sprintf("group_%v", groupUUID)
+		signature.GroupTaskCount = len(terminals)
+	}
+
+	return group, nil
+}
+
+func (group *workflowGroup) Heads() []*Signature {
+	var heads []*Signature
+	for _, member := range group.members {
+		heads = append(heads, member.Heads()...)
+	}
+	return heads
+}
+
+func (group *workflowGroup) Terminals() []*Signature {
+	var terminals []*Signature
+	for _, member := range group.members {
+		terminals = append(terminals, member.Terminals()...)
+	}
+	return terminals
+}
+
+func (group *workflowGroup) then(next Workflow) error {
+	return setChordCallback(group.Terminals(), next)
+}
+
+// NewWorkflowChord creates a chord of a group of workflows and a callback
+// workflow started once the group has completed, the callback must start with
+// a single task
+func NewWorkflowChord(group Workflow, callback Workflow) (Workflow, error) {
+	return NewWorkflowChain(group, callback)
+}
+
+// setChordCallback makes the first task of next the chord callback of a group
+func setChordCallback(groupTasks []*Signature, next Workflow) error {
+	heads := next.Heads()
+	if len(heads) != 1 {
+		return errors.New("A group can only be followed by a workflow starting with a single task")
+	}
+
+	for _, signature := range groupTasks {
+		if signature.ChordCallback != nil {
+			return fmt.Errorf("Task %s already has a chord callback", signature.Name)
+		}
+		signature.ChordCallback = heads[0]
+	}
+
+	return nil
+}
+
+// WorkflowSignatures returns all signatures of the workflow reachable from its
+// heads through success and chord callbacks, task UUIDs are auto generated if
+// needed
+func WorkflowSignatures(workflow Workflow) ([]*Signature, error) {
+	var (
+		signatures []*Signature
+		visited    = make(map[*Signature]bool)
+		queue      = workflow.Heads()
+	)
+	for len(queue) > 0 {
+		signature := queue[0]
+		queue = queue[1:]
+		if visited[signature] {
+			continue
+		}
+		visited[signature] = true
+
+		if signature.UUID == "" {
+			signatureID, err := uuid.NewV4()
+			if err != nil {
+				return nil, fmt.Errorf("Error generating signature id: %s", err.Error())
+			}
+			signature.UUID = fmt.Sprintf("task_%v", signatureID)
+		}
+		signatures = append(signatures, signature)
+
+		queue = append(queue, signature.OnSuccess...)
+		if signature.ChordCallback != nil {
+			queue = append(queue, signature.ChordCallback)
+		}
+	}
+
+	return signatures, nil
+}
+
+// TagWorkflow sets the WorkflowUUID of all signatures of the workflow reachable
+// from its heads through success, chord and chord error callbacks and returns
+// it. The workflow is identified by the group UUID of its first task if it
+// starts with a group, or by the UUID of its first task otherwise, signatures
+// already tagged, e.g. by an enclosing workflow, keep their WorkflowUUID.
+func TagWorkflow(workflow Workflow) string {
+	heads := workflow.Heads()
+	if len(heads) == 0 {
+		return ""
+	}
+
+	workflowUUID := heads[0].WorkflowUUID
+	if workflowUUID == "" {
+		workflowUUID = heads[0].GroupUUID
+	}
+	if workflowUUID == "" {
+		workflowUUID = heads[0].UUID
+	}
+
+	var (
+		visited = make(map[*Signature]bool)
+		queue   = heads
+	)
+	for len(queue) > 0 {
+		signature := queue[0]
+		queue = queue[1:]
+		if visited[signature] {
+			continue
+		}
+		visited[signature] = true
+
+		if signature.WorkflowUUID == "" {
+			signature.WorkflowUUID = workflowUUID
+		}
+
+		queue = append(queue, signature.OnSuccess...)
+		if signature.ChordCallback != nil {
+			queue = append(queue, signature.ChordCallback)
+		}
+		if signature.ChordErrorCallback != nil {
+			queue = append(queue, signature.ChordErrorCallback)
+		}
+	}
+
+	return workflowUUID
+}
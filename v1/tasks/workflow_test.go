@@ -1,8 +1,10 @@
 package tasks_test
 
 import (
+	"errors"
 	"testing"
 
+	"github.com/RichardKnop/machinery/v1/backends"
 	"github.com/RichardKnop/machinery/v1/tasks"
 	"github.com/stretchr/testify/assert"
 )
@@ -59,3 +61,118 @@ func TestNewChain(t *testing.T) {
 	assert.Equal(t, "bar", firstTask.OnSuccess[0].Name)
 	assert.Equal(t, "qux", firstTask.OnSuccess[0].OnSuccess[0].Name)
 }
+
+func TestGroupCompleted(t *testing.T) {
+	t.Parallel()
+
+	group, err := tasks.NewGroup(
+		&tasks.Signature{Name: "succeeded"},
+		&tasks.Signature{Name: "failed"},
+		&tasks.Signature{Name: "started"},
+		&tasks.Signature{Name: "pending"},
+		&tasks.Signature{Name: "not_sent"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend := backends.NewEagerBackend()
+	if err := backend.InitGroup(group.GroupUUID, group.GetUUIDs()); err != nil {
+		t.Fatal(err)
+	}
+
+	completed, err := group.Completed(backend.(tasks.StatesGetter))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, completed)
+
+	assert.NoError(t, backend.SetStateSuccess(group.Tasks[0], nil))
+	assert.NoError(t, backend.SetStateFailure(group.Tasks[1], "some error"))
+	assert.NoError(t, backend.SetStateStarted(group.Tasks[2]))
+	assert.NoError(t, backend.SetStatePending(group.Tasks[3]))
+
+	// Tasks without a state yet do not count as completed
+	completed, err = group.Completed(backend.(tasks.StatesGetter))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, completed)
+}
+
+func TestGroupCompletedError(t *testing.T) {
+	t.Parallel()
+
+	group, err := tasks.NewGroup(&tasks.Signature{Name: "foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = group.Completed(failingStatesGetter{})
+	assert.EqualError(t, err, "backend unavailable")
+}
+
+type failingStatesGetter struct{}
+
+func (failingStatesGetter) GetStates(taskUUIDs ...string) (map[string]*tasks.TaskState, error) {
+	return nil, errors.New("backend unavailable")
+}
+
+func TestNewWorkflow(t *testing.T) {
+	t.Parallel()
+
+	var (
+		a = &tasks.Signature{Name: "a"}
+		b = &tasks.Signature{Name: "b"}
+		c = &tasks.Signature{Name: "c"}
+		d = &tasks.Signature{Name: "d"}
+		e = &tasks.Signature{Name: "e"}
+	)
+
+	// a -> (b | c -> d) -> e
+	inner, err := tasks.NewWorkflowChain(c, d)
+	if !assert.NoError(t, err) {
+		return
+	}
+	group, err := tasks.NewWorkflowGroup(b, inner)
+	if !assert.NoError(t, err) {
+		return
+	}
+	workflow, err := tasks.NewWorkflowChain(a, group, e)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, []*tasks.Signature{a}, workflow.Heads())
+	assert.Equal(t, []*tasks.Signature{e}, workflow.Terminals())
+	assert.Equal(t, []*tasks.Signature{b, c}, a.OnSuccess)
+	assert.Equal(t, []*tasks.Signature{d}, c.OnSuccess)
+
+	// Terminal tasks of the group members make up the group
+	assert.Empty(t, c.GroupUUID)
+	assert.NotEmpty(t, b.GroupUUID)
+	assert.Equal(t, b.GroupUUID, d.GroupUUID)
+	assert.Equal(t, 2, d.GroupTaskCount)
+	assert.Equal(t, e, b.ChordCallback)
+	assert.Equal(t, e, d.ChordCallback)
+
+	signatures, err := tasks.WorkflowSignatures(workflow)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []*tasks.Signature{a, b, c, e, d}, signatures)
+		for _, signature := range signatures {
+			assert.NotEmpty(t, signature.UUID)
+		}
+	}
+}
+
+func TestNewWorkflowGroupFollowedByGroup(t *testing.T) {
+	t.Parallel()
+
+	first, err := tasks.NewWorkflowGroup(&tasks.Signature{Name: "a"}, &tasks.Signature{Name: "b"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	second, err := tasks.NewWorkflowGroup(&tasks.Signature{Name: "c"}, &tasks.Signature{Name: "d"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = tasks.NewWorkflowChain(first, second)
+	assert.Error(t, err)
+}
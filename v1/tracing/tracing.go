@@ -1,6 +1,7 @@
 package tracing
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/RichardKnop/machinery/v1/tasks"
@@ -21,12 +22,18 @@ var (
 // StartSpanFromHeaders will extract a span from the signature headers
 // and start a new span with the given operation name.
 func StartSpanFromHeaders(headers tasks.Headers, operationName string) opentracing.Span {
+	return StartSpanFromHeadersWithTracer(opentracing.GlobalTracer(), headers, operationName)
+}
+
+// StartSpanFromHeadersWithTracer is like StartSpanFromHeaders but uses the
+// given tracer instead of the global tracer
+func StartSpanFromHeadersWithTracer(tracer opentracing.Tracer, headers tasks.Headers, operationName string) opentracing.Span {
 	// Try to extract the span context from the carrier.
-	spanContext, err := opentracing.GlobalTracer().Extract(opentracing.TextMap, headers)
+	spanContext, err := tracer.Extract(opentracing.TextMap, headers)
 
 	// Create a new span from the span context if found or start a new trace with the function name.
 	// For clarity add the machinery component tag.
-	span := opentracing.StartSpan(
+	span := tracer.StartSpan(
 		operationName,
 		ConsumerOption(spanContext),
 		MachineryTag,
@@ -47,13 +54,23 @@ func HeadersWithSpan(headers tasks.Headers, span opentracing.Span) tasks.Headers
 		headers = make(tasks.Headers)
 	}
 
-	if err := opentracing.GlobalTracer().Inject(span.Context(), opentracing.TextMap, headers); err != nil {
+	// Inject the span with the tracer which started it
+	if err := span.Tracer().Inject(span.Context(), opentracing.TextMap, headers); err != nil {
 		span.LogFields(opentracing_log.Error(err))
 	}
 
 	return headers
 }
 
+// StartSpanFromContext starts a span with the given tracer, the span found in
+// the context, if any, is its parent
+func StartSpanFromContext(ctx context.Context, tracer opentracing.Tracer, operationName string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	if parent := opentracing.SpanFromContext(ctx); parent != nil {
+		opts = append(opts, opentracing.ChildOf(parent.Context()))
+	}
+	return tracer.StartSpan(operationName, opts...)
+}
+
 type consumerOption struct {
 	producerContext opentracing.SpanContext
 }
@@ -0,0 +1,181 @@
+package machinery
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/RichardKnop/machinery/v1/config"
+	"github.com/RichardKnop/machinery/v1/log"
+	"github.com/RichardKnop/machinery/v1/retry"
+	"github.com/RichardKnop/machinery/v1/tasks"
+)
+
+const (
+	defaultWebhookTimeout     = 10
+	defaultWebhookMaxRetries  = 3
+	defaultWebhookConcurrency = 10
+	// webhookQueueSize is how many notifications may wait for a free sender
+	// per sender before further ones are dropped
+	webhookQueueSize = 100
+)
+
+// WebhookPayload is the JSON body posted to the WebhookURL of a task once it
+// succeeds or fails
+type WebhookPayload struct {
+	TaskUUID    string              `json:"task_uuid"`
+	TaskName    string              `json:"task_name"`
+	State       string              `json:"state"`
+	Results     []*tasks.TaskResult `json:"results,omitempty"`
+	Error       string              `json:"error,omitempty"`
+	CompletedAt time.Time           `json:"completed_at"`
+}
+
+// webhookRequest is a notification waiting to be posted
+type webhookRequest struct {
+	url     string
+	payload WebhookPayload
+}
+
+// notifyWebhook queues a notification of the completed task for its
+// WebhookURL, if any. Notifications are posted by a bounded pool of senders,
+// so a slow endpoint does not hold back processing tasks, once too many of
+// them are waiting further ones are dropped.
+func (server *Server) notifyWebhook(signature *tasks.Signature, state string, results []*tasks.TaskResult, taskErr error) {
+	if signature.WebhookURL == "" {
+		return
+	}
+
+	request := webhookRequest{
+		url: signature.WebhookURL,
+		payload: WebhookPayload{
+			TaskUUID:    signature.UUID,
+			TaskName:    signature.Name,
+			State:       state,
+			Results:     results,
+			CompletedAt: time.Now().UTC(),
+		},
+	}
+	if taskErr != nil {
+		request.payload.Error = taskErr.Error()
+	}
+
+	server.webhooksMu.Lock()
+	defer server.webhooksMu.Unlock()
+	if server.webhooksStopped {
+		log.WARNING.Printf("Webhooks have been stopped, dropping notification of task %s (%s)", signature.Name, signature.UUID)
+		return
+	}
+	if server.webhooks == nil {
+		server.startWebhookSenders()
+	}
+
+	select {
+	case server.webhooks <- request:
+	default:
+		log.ERROR.Printf("Too many webhook notifications waiting, dropping notification of task %s (%s)", signature.Name, signature.UUID)
+	}
+}
+
+// StopWebhooks stops the senders of webhook notifications once they have
+// posted the notifications still waiting, retries included, and returns
+// afterwards. Notifications of tasks completing later are dropped. Call it
+// before the process exits, notifications waiting at exit are lost otherwise.
+func (server *Server) StopWebhooks() {
+	server.webhooksMu.Lock()
+	if !server.webhooksStopped {
+		server.webhooksStopped = true
+		if server.webhooks != nil {
+			close(server.webhooks)
+		}
+	}
+	server.webhooksMu.Unlock()
+
+	server.webhooksWG.Wait()
+}
+
+// startWebhookSenders starts the pool of senders posting notifications until
+// the queue of notifications is closed by StopWebhooks
+func (server *Server) startWebhookSenders() {
+	cnf := server.config.Webhook
+	if cnf == nil {
+		cnf = new(config.WebhookConfig)
+	}
+
+	timeout, maxRetries, concurrency := cnf.Timeout, cnf.MaxRetries, cnf.Concurrency
+	if timeout == 0 {
+		timeout = defaultWebhookTimeout
+	}
+	if maxRetries == 0 {
+		maxRetries = defaultWebhookMaxRetries
+	}
+	if concurrency == 0 {
+		concurrency = defaultWebhookConcurrency
+	}
+
+	client := &http.Client{
+		Timeout:   time.Duration(timeout) * time.Second,
+		Transport: &http.Transport{TLSClientConfig: cnf.TLSConfig, Proxy: http.ProxyFromEnvironment},
+	}
+	server.webhooks = make(chan webhookRequest, concurrency*webhookQueueSize)
+	server.webhooksWG.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer server.webhooksWG.Done()
+			for request := range server.webhooks {
+				postWebhook(client, request, maxRetries)
+			}
+		}()
+	}
+}
+
+// postWebhook posts the notification, retrying with Fibonacci backoff when
+// the request fails or the endpoint responds with a server error
+func postWebhook(client *http.Client, request webhookRequest, maxRetries int) {
+	body, err := json.Marshal(request.payload)
+	if err != nil {
+		log.ERROR.Printf("Marshal webhook payload of task %s error: %s", request.payload.TaskUUID, err)
+		return
+	}
+
+	backoff := retry.Fibonacci()
+	for attempt := 0; ; attempt++ {
+		retriable, err := sendWebhook(client, request.url, body)
+		if err == nil {
+			return
+		}
+		if !retriable || attempt >= maxRetries {
+			log.ERROR.Printf("Webhook of task %s error: %s", request.payload.TaskUUID, err)
+			return
+		}
+
+		delay := time.Duration(backoff()) * time.Second
+		log.WARNING.Printf("Webhook of task %s error: %s. Going to retry in %s.", request.payload.TaskUUID, err, delay)
+		time.Sleep(delay)
+	}
+}
+
+// sendWebhook posts the body to the URL once, it returns whether a failed
+// request is worth retrying
+func sendWebhook(client *http.Client, url string, body []byte) (bool, error) {
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return true, err
+	}
+	// Drain the body so the connection can be reused
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return true, fmt.Errorf("Webhook responded with %s", resp.Status)
+	default:
+		return false, fmt.Errorf("Webhook responded with %s", resp.Status)
+	}
+}
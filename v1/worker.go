@@ -1,16 +1,21 @@
 package machinery
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/opentracing/opentracing-go"
 
 	"github.com/RichardKnop/machinery/v1/backends"
+	"github.com/RichardKnop/machinery/v1/brokers"
+	"github.com/RichardKnop/machinery/v1/config"
 	"github.com/RichardKnop/machinery/v1/log"
 	"github.com/RichardKnop/machinery/v1/retry"
 	"github.com/RichardKnop/machinery/v1/tasks"
@@ -19,10 +24,22 @@ import (
 
 // Worker represents a single worker process
 type Worker struct {
-	server       *Server
-	ConsumerTag  string
-	Concurrency  int
-	errorHandler func(err error)
+	// processedTasks, activeTasks, lastHeartbeat and running are accessed
+	// atomically, keep the 64-bit ones aligned
+	processedTasks int64
+	activeTasks    int64
+	lastHeartbeat  int64
+	running        int32
+	server         *Server
+	ConsumerTag    string
+	Concurrency    int
+	Queue          string
+	Queues         []string
+	// QueueSettings overrides the concurrency and prefetch count of some of
+	// the Queues, currently only supported by AMQP
+	QueueSettings map[string]brokers.QueueSettings
+	errorHandler  func(err error)
+	idleHandler   func()
 }
 
 // Launch starts a new worker process. The worker subscribes
@@ -40,21 +57,56 @@ func (worker *Worker) LaunchAsync(errorsChan chan<- error) {
 	cnf := worker.server.GetConfig()
 	broker := worker.server.GetBroker()
 
-	// Log some useful information about woorker configuration
-	log.INFO.Printf("Launching a worker with the following settings:")
-	log.INFO.Printf("- Broker: %s", cnf.Broker)
-	log.INFO.Printf("- DefaultQueue: %s", cnf.DefaultQueue)
-	log.INFO.Printf("- ResultBackend: %s", cnf.ResultBackend)
+	// Log some useful information about worker configuration as one event
+	fields := log.Fields{
+		"broker":         cnf.Broker,
+		"default_queue":  cnf.DefaultQueue,
+		"result_backend": cnf.ResultBackend,
+		"concurrency":    worker.Concurrency,
+	}
+	if worker.ConsumerTag != "" {
+		fields["consumer_tag"] = worker.ConsumerTag
+	}
+	if worker.Queue != "" {
+		fields["custom_queue"] = worker.Queue
+	}
+	if len(worker.Queues) > 0 {
+		fields["custom_queues"] = strings.Join(worker.Queues, ", ")
+	}
 	if cnf.AMQP != nil {
-		log.INFO.Printf("- AMQP: %s", cnf.AMQP.Exchange)
-		log.INFO.Printf("  - Exchange: %s", cnf.AMQP.Exchange)
-		log.INFO.Printf("  - ExchangeType: %s", cnf.AMQP.ExchangeType)
-		log.INFO.Printf("  - BindingKey: %s", cnf.AMQP.BindingKey)
-		log.INFO.Printf("  - PrefetchCount: %d", cnf.AMQP.PrefetchCount)
+		fields["amqp_exchange"] = cnf.AMQP.Exchange
+		fields["amqp_exchange_type"] = cnf.AMQP.ExchangeType
+		fields["amqp_binding_key"] = cnf.AMQP.BindingKey
+		fields["amqp_prefetch_count"] = cnf.AMQP.PrefetchCount
+	}
+	log.Event(log.LevelInfo, "Launching a worker with the following settings:", fields)
+
+	if len(worker.QueueSettings) > 0 && !brokers.IsAMQP(broker) {
+		log.WARNING.Print("Queue settings are not supported by the broker, all queues share the worker concurrency")
+	}
+	if cnf.EnableDeduplication {
+		if _, ok := worker.server.GetBackend().(backends.Deduplicator); !ok {
+			log.WARNING.Print("Deduplication is enabled but not supported by the result backend")
+		}
+	}
+	// stopped is closed once the worker stops consuming
+	stopped := make(chan struct{})
+	if cnf.WorkerHeartbeatInterval > 0 {
+		if registry, ok := worker.server.GetBackend().(backends.WorkerRegistry); ok {
+			go worker.recordHeartbeats(registry, stopped)
+		} else {
+			log.WARNING.Print("Worker heartbeats are enabled but not supported by the result backend")
+		}
+	}
+	if cnf.IdleTimeout > 0 {
+		go worker.watchIdle(stopped)
 	}
 
 	// Goroutine to start broker consumption and handle retries when broker connection dies
+	atomic.StoreInt32(&worker.running, 1)
 	go func() {
+		defer atomic.StoreInt32(&worker.running, 0)
+		defer close(stopped)
 		for {
 			retry, err := broker.StartConsuming(worker.ConsumerTag, worker.Concurrency, worker)
 
@@ -105,85 +157,467 @@ func (worker *Worker) Quit() {
 	worker.server.GetBroker().StopConsuming()
 }
 
+// Pause stops the worker from taking new tasks off the queue without
+// disconnecting from the broker, tasks being processed are finished. The
+// broker is shared by all workers of the server, so they are paused too.
+func (worker *Worker) Pause() {
+	if pauser, ok := worker.server.GetBroker().(brokers.Pauser); ok {
+		pauser.PauseConsuming()
+	}
+}
+
+// Resume lets a paused worker take tasks off the queue again
+func (worker *Worker) Resume() {
+	if pauser, ok := worker.server.GetBroker().(brokers.Pauser); ok {
+		pauser.ResumeConsuming()
+	}
+}
+
+// IsPaused returns true if the worker is paused
+func (worker *Worker) IsPaused() bool {
+	pauser, ok := worker.server.GetBroker().(brokers.Pauser)
+	return ok && pauser.IsConsumingPaused()
+}
+
+// CustomQueue returns the queue the worker consumes from instead of the
+// default queue, empty unless the worker was created by NewCustomQueueWorker
+func (worker *Worker) CustomQueue() string {
+	return worker.Queue
+}
+
+// CustomQueues returns the queues the worker consumes from at once, empty
+// unless the worker was created by NewMultiQueueWorker
+func (worker *Worker) CustomQueues() []string {
+	return worker.Queues
+}
+
+// CustomQueueSettings returns the settings of queues consumed with settings
+// of their own
+func (worker *Worker) CustomQueueSettings() map[string]brokers.QueueSettings {
+	return worker.QueueSettings
+}
+
+// ConnectionOpened is called by the broker once a connection consumers use is
+// opened, it is reported to Metrics implementing ConnectionMetrics and sent
+// as a connection event
+func (worker *Worker) ConnectionOpened() {
+	worker.connectionsChanged(1)
+	worker.server.emitConnectionEvent(EventConnectionOpened, nil, 0)
+}
+
+// ConnectionClosed is called by the broker once consuming over a connection
+// stopped, err is the reason or nil if consuming was stopped
+func (worker *Worker) ConnectionClosed(err error) {
+	worker.connectionsChanged(-1)
+	worker.server.emitConnectionEvent(EventConnectionClosed, err, 0)
+}
+
+// ReconnectAttempt is called by the broker before each attempt to connect
+// again after a connection failed or was lost
+func (worker *Worker) ReconnectAttempt(attempt int) {
+	if metrics, ok := worker.server.GetMetrics().(ConnectionMetrics); ok {
+		metrics.BrokerReconnectAttempt()
+	}
+	worker.server.emitConnectionEvent(EventReconnectAttempt, nil, attempt)
+}
+
+// ChannelError is called by the broker when it closes a channel of an open
+// connection because of an error
+func (worker *Worker) ChannelError(err error) {
+	if metrics, ok := worker.server.GetMetrics().(ConnectionMetrics); ok {
+		metrics.BrokerChannelError()
+	}
+	worker.server.emitConnectionEvent(EventChannelError, err, 0)
+}
+
+// connectionsChanged counts connections of all workers of the server which
+// are open and reports them
+func (worker *Worker) connectionsChanged(delta int32) {
+	open := atomic.AddInt32(&worker.server.brokerConnections, delta)
+	if metrics, ok := worker.server.GetMetrics().(ConnectionMetrics); ok {
+		metrics.BrokerConnections(int(open))
+	}
+}
+
 // Process handles received tasks and triggers success/error callbacks
 func (worker *Worker) Process(signature *tasks.Signature) error {
+	return worker.ProcessDelivery(signature, tasks.DeliveryInfo{})
+}
+
+// ProcessDelivery is like Process, it passes details about the message the
+// task was delivered in to tasks taking *tasks.TaskContext
+func (worker *Worker) ProcessDelivery(signature *tasks.Signature, delivery tasks.DeliveryInfo) error {
+	worker.heartbeat()
+	atomic.AddInt64(&worker.activeTasks, 1)
+	defer func() {
+		atomic.AddInt64(&worker.activeTasks, -1)
+		worker.heartbeat()
+	}()
+
 	// If the task is not registered with this worker, do not continue
 	// but only return nil as we do not want to restart the worker process
-	if !worker.server.IsTaskRegistered(signature.Name) {
-		return nil
+	taskFunc, err := worker.server.GetRegisteredTask(signature.Name)
+	if err != nil || taskFunc == nil {
+		return worker.unknownTask(signature)
 	}
+	worker.server.applyProcessDefaults(signature)
 
-	taskFunc, err := worker.server.GetRegisteredTask(signature.Name)
-	if err != nil {
+	// Drop tasks which were not started before their expiration time
+	if signature.ExpiresAt != nil && time.Now().UTC().After(*signature.ExpiresAt) {
+		return worker.taskExpired(signature)
+	}
+
+	// Send the task back to the queue if too many instances of it are running
+	release, ok := worker.server.acquireTaskSlot(signature.Name)
+	if !ok {
+		return worker.concurrencyLimitReached(signature)
+	}
+	defer release()
+
+	// Send the task back to the queue if its memory does not fit the budget
+	releaseMemory, ok := worker.server.acquireMemory(signature.EstimatedMemoryBytes)
+	if !ok {
+		return worker.memoryBudgetReached(signature)
+	}
+	defer releaseMemory()
+
+	// Send the task back to the queue while its circuit breaker is open
+	breaker := worker.server.circuitBreakers[signature.Name]
+	var probe, called bool
+	if breaker != nil {
+		var (
+			wait    time.Duration
+			allowed bool
+		)
+		if probe, wait, allowed = breaker.allow(time.Now()); !allowed {
+			return worker.circuitOpen(signature, wait)
+		}
+		// A probe which is not called after all lets another task probe
+		defer func() {
+			if probe && !called {
+				breaker.cancelProbe()
+			}
+		}()
+	}
+
+	// Skip tasks which have already been received, e.g. when the message
+	// was redelivered after a worker crashed before acknowledging it
+	if !worker.markTaskSeen(signature) {
+		log.WARNING.Printf("Skipping task %s (%s), it has already been received", signature.Name, signature.UUID)
 		return nil
 	}
 
 	// Update task state to RECEIVED
 	if err = worker.server.GetBackend().SetStateReceived(signature); err != nil {
+		worker.unmarkTaskSeen(signature)
 		return fmt.Errorf("Set state received error: %s", err)
 	}
+	worker.server.emitEvent(signature, tasks.StateReceived)
+
+	// Drop tasks which were revoked before they started, this is checked once
+	// the task is RECEIVED so a revoke seeing it still PENDING is never missed
+	if worker.isTaskRevoked(signature) {
+		return worker.taskRevoked(signature)
+	}
+
+	// Wait until the task can be started within its rate limit
+	if err = worker.server.waitRateLimit(signature.Name); err != nil {
+		worker.unmarkTaskSeen(signature)
+		return fmt.Errorf("Rate limit error: %s", err)
+	}
 
 	// Prepare task for processing
 	task, err := tasks.New(taskFunc, signature.Args)
 	// if this failed, it means the task is malformed, probably has invalid
-	// signature, go directly to task failed without checking whether to retry
+	// signature, go directly to task failed without checking whether to retry.
+	// The message has been handled then, it is not handed back to the broker.
 	if err != nil {
-		worker.taskFailed(signature, err)
-		return err
+		return worker.taskFailed(signature, fmt.Errorf("Prepare task %s error: %w", signature.Name, err))
 	}
+	task.Name = signature.Name
 
 	// try to extract trace span from headers and add it to the function context
 	// so it can be used inside the function if it has context.Context as the first
 	// argument. Start a new span if it isn't found.
-	taskSpan := tracing.StartSpanFromHeaders(signature.Headers, signature.Name)
+	taskSpan := tracing.StartSpanFromHeadersWithTracer(worker.server.GetTracer(), signature.Headers, signature.Name)
 	tracing.AnnotateSpanWithSignatureInfo(taskSpan, signature)
 	task.Context = opentracing.ContextWithSpan(task.Context, taskSpan)
+	task.Context = tasks.ContextWithHeaders(task.Context, signature.Headers)
+	task.TaskContext = worker.newTaskContext(signature, delivery)
+
+	// Cancel the task context once the signature timeout elapses
+	if signature.TimeoutSeconds > 0 {
+		var cancel context.CancelFunc
+		task.Context, cancel = context.WithTimeout(task.Context, time.Duration(signature.TimeoutSeconds)*time.Second)
+		defer cancel()
+	}
 
 	// Update task state to STARTED
 	if err = worker.server.GetBackend().SetStateStarted(signature); err != nil {
+		worker.unmarkTaskSeen(signature)
 		return fmt.Errorf("Set state started error: %s", err)
 	}
+	worker.server.emitEvent(signature, tasks.StateStarted)
+	defer worker.countProcessedTask()
+
+	// Call the task wrapped in the middleware
+	handler := func(ctx context.Context, signature *tasks.Signature) ([]*tasks.TaskResult, error) {
+		task := task
+		if len(worker.server.middleware) > 0 {
+			// The middleware might have changed the args, prepare the task again
+			var err error
+			if task, err = tasks.New(taskFunc, signature.Args); err != nil {
+				return nil, fmt.Errorf("Prepare task %s error: %w", signature.Name, err)
+			}
+			task.Name = signature.Name
+			task.TaskContext = worker.newTaskContext(signature, delivery)
+		}
+		task.Context = ctx
 
-	// Call the task
-	results, err := task.Call()
+		metrics := worker.server.GetMetrics()
+		metrics.TaskStarted(signature.Name)
+		start := time.Now()
+		results, err := worker.callTask(task, signature)
+		if err != nil {
+			metrics.TaskFailed(signature.Name, time.Since(start))
+		} else {
+			metrics.TaskSucceeded(signature.Name, time.Since(start))
+		}
+		return results, err
+	}
+	startedAt := time.Now()
+	results, err := worker.server.wrapTaskHandler(handler)(task.Context, signature)
+	if breaker != nil {
+		called = true
+		breaker.record(probe, err != nil, time.Now())
+	}
 	if err != nil {
-		// If a tasks.ErrRetryTaskLater was returned from the task,
-		// retry the task after specified duration
-		retriableErr, ok := interface{}(err).(tasks.ErrRetryTaskLater)
-		if ok {
+		// With AckLate timed out tasks are handed back to the broker to be
+		// redelivered instead of failing
+		if worker.server.GetConfig().AckLate && errors.Is(err, context.DeadlineExceeded) {
+			worker.unmarkTaskSeen(signature)
+			worker.audit(signature, startedAt, tasks.StateRetry, err)
+			return fmt.Errorf("Task %s (%s) timed out: %w", signature.Name, signature.UUID, err)
+		}
+
+		// If a tasks.Retriable error such as tasks.ErrRetryTaskLater was
+		// returned from the task, retry the task after specified duration
+		var retriableErr tasks.Retriable
+		if errors.As(err, &retriableErr) {
+			worker.audit(signature, startedAt, tasks.StateRetry, err)
 			return worker.retryTaskIn(signature, retriableErr.RetryIn())
 		}
 
 		// Otherwise, execute default retry logic based on signature.RetryCount
 		// and signature.RetryTimeout values
 		if signature.RetryCount > 0 {
+			worker.audit(signature, startedAt, tasks.StateRetry, err)
 			return worker.taskRetry(signature)
 		}
 
+		worker.audit(signature, startedAt, tasks.StateFailure, err)
 		return worker.taskFailed(signature, err)
 	}
 
+	worker.audit(signature, startedAt, tasks.StateSuccess, nil)
 	return worker.taskSucceeded(signature, results)
 }
 
+// countProcessedTask counts tasks processed by the worker and stops it once
+// MaxTasksPerWorker tasks have been processed
+func (worker *Worker) countProcessedTask() {
+	maxTasks := worker.server.GetConfig().MaxTasksPerWorker
+	if maxTasks <= 0 {
+		return
+	}
+
+	if atomic.AddInt64(&worker.processedTasks, 1) != int64(maxTasks) {
+		return
+	}
+
+	log.INFO.Printf("Worker processed %d tasks, stopping it", maxTasks)
+	// Quit waits for tasks being processed to finish, including this one
+	go worker.Quit()
+}
+
+// watchIdle stops the worker once it has not processed any task for
+// IdleTimeout, or calls its idle handler instead if one is set, until stop is
+// closed. Tasks being processed keep the worker from being idle.
+func (worker *Worker) watchIdle(stop <-chan struct{}) {
+	timeout := time.Duration(worker.server.GetConfig().IdleTimeout) * time.Second
+	idleSince := time.Now()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-timer.C:
+		}
+
+		if lastHeartbeat := time.Unix(0, atomic.LoadInt64(&worker.lastHeartbeat)); lastHeartbeat.After(idleSince) {
+			idleSince = lastHeartbeat
+		}
+		if atomic.LoadInt64(&worker.activeTasks) > 0 {
+			idleSince = time.Now()
+		}
+		if idle := time.Since(idleSince); idle < timeout {
+			timer.Reset(timeout - idle)
+			continue
+		}
+
+		if worker.idleHandler != nil {
+			worker.idleHandler()
+			idleSince = time.Now()
+			timer.Reset(timeout)
+			continue
+		}
+
+		log.INFO.Printf("Worker has been idle for %s, stopping it", timeout)
+		worker.Quit()
+		return
+	}
+}
+
+// callTask calls the task, if the signature has a timeout the worker stops
+// waiting for the task once the timeout elapses, even if the task ignores the
+// cancelled context and keeps running in the background
+func (worker *Worker) callTask(task *tasks.Task, signature *tasks.Signature) ([]*tasks.TaskResult, error) {
+	if signature.TimeoutSeconds <= 0 {
+		return task.Call()
+	}
+
+	type callResult struct {
+		results []*tasks.TaskResult
+		err     error
+	}
+	// Buffered so the orphaned goroutine does not block once the worker moved on
+	resultChan := make(chan callResult, 1)
+	go func() {
+		results, err := task.Call()
+		resultChan <- callResult{results: results, err: err}
+	}()
+
+	select {
+	case result := <-resultChan:
+		return result.results, result.err
+	case <-task.Context.Done():
+		go func() {
+			result := <-resultChan
+			log.WARNING.Printf("Task %s (%s) returned after timing out, its results are discarded: %v", signature.Name, signature.UUID, result.err)
+		}()
+		return nil, task.Context.Err()
+	}
+}
+
+// broadcastHeader marks tasks sent with Broadcast, which every worker
+// processes a copy of
+const broadcastHeader = "broadcast"
+
+// isBroadcast returns true if the task was sent with Broadcast
+func isBroadcast(signature *tasks.Signature) bool {
+	broadcast, _ := signature.Headers[broadcastHeader].(bool)
+	return broadcast
+}
+
+// retriesHeader holds how many times the task has been retried
+const retriesHeader = "retries"
+
+// retries returns how many times the task has been retried
+func retries(signature *tasks.Signature) int {
+	return signature.Headers.Count(retriesHeader)
+}
+
+// firstFailedAtHeader holds when the task failed for the first time, in RFC
+// 3339 format, it is set once the task is retried
+const firstFailedAtHeader = "first_failed_at"
+
+// countRetry increments the number of times the task has been retried
+func countRetry(signature *tasks.Signature) {
+	if signature.Headers == nil {
+		signature.Headers = make(tasks.Headers)
+	}
+	signature.Headers[retriesHeader] = retries(signature) + 1
+	if _, ok := signature.Headers[firstFailedAtHeader]; !ok {
+		signature.Headers[firstFailedAtHeader] = time.Now().UTC().Format(time.RFC3339)
+	}
+}
+
+// newTaskContext returns the context passed to tasks taking *tasks.TaskContext,
+// it carries the delivery of the task, tasks dispatched through it are sent by
+// the server of the worker and inherit headers of the task like callbacks do
+func (worker *Worker) newTaskContext(signature *tasks.Signature, delivery tasks.DeliveryInfo) *tasks.TaskContext {
+	taskContext := tasks.NewTaskContext(context.Background(), signature, func(ctx context.Context, child *tasks.Signature) error {
+		inheritHeaders(child, signature)
+		_, err := worker.server.SendTaskWithContext(ctx, child)
+		return err
+	})
+	taskContext.Delivery = delivery
+	return taskContext
+}
+
+// inheritHeaders copies the headers of a task into its callback so metadata
+// such as correlation IDs flows through a whole workflow. Headers already set
+// on the callback win and bookkeeping headers of the task are left out.
+func inheritHeaders(callback, signature *tasks.Signature) {
+	for k, v := range signature.Headers {
+		if isBookkeepingHeader(k) {
+			continue
+		}
+		if _, ok := callback.Headers[k]; ok {
+			continue
+		}
+		if callback.Headers == nil {
+			callback.Headers = make(tasks.Headers, len(signature.Headers))
+		}
+		callback.Headers[k] = v
+	}
+}
+
+// isBookkeepingHeader returns true for headers tracking how a single task was
+// processed, which are not inherited by its callbacks
+func isBookkeepingHeader(key string) bool {
+	switch key {
+	case retriesHeader, firstFailedAtHeader, broadcastHeader, brokers.RedeliveriesHeader:
+		return true
+	}
+	return strings.HasPrefix(key, "dead_letter_")
+}
+
 // retryTask decrements RetryCount counter and republishes the task to the queue
 func (worker *Worker) taskRetry(signature *tasks.Signature) error {
 	// Update task state to RETRY
 	if err := worker.server.GetBackend().SetStateRetry(signature); err != nil {
 		return fmt.Errorf("Set state retry error: %s", err)
 	}
+	worker.server.emitEvent(signature, tasks.StateRetry)
+
+	countRetry(signature)
 
 	// Decrement the retry counter, when it reaches 0, we won't retry again
 	signature.RetryCount--
 
-	// Increase retry timeout
-	signature.RetryTimeout = retry.FibonacciNext(signature.RetryTimeout)
+	// Increase retry timeout, exponential backoff waits for RetryTimeout
+	// itself before the first retry
+	if signature.RetryBackoff == tasks.RetryBackoffExponential {
+		if retries(signature) > 1 || signature.RetryTimeout < 1 {
+			signature.RetryTimeout = retry.ExponentialNext(signature.RetryTimeout)
+		}
+	} else {
+		signature.RetryTimeout = retry.FibonacciNext(signature.RetryTimeout)
+	}
 
-	// Delay task by signature.RetryTimeout seconds
-	eta := time.Now().UTC().Add(time.Second * time.Duration(signature.RetryTimeout))
+	// Delay task by signature.RetryTimeout seconds, jittered so that tasks
+	// failing together do not all retry at the same time
+	retryIn := worker.server.retryDelay(time.Second * time.Duration(signature.RetryTimeout))
+	eta := time.Now().UTC().Add(retryIn)
 	signature.ETA = &eta
 
-	log.WARNING.Printf("Task %s failed. Going to retry in %d seconds.", signature.UUID, signature.RetryTimeout)
+	log.WARNING.Printf("Task %s failed. Going to retry in %v.", signature.UUID, retryIn)
+
+	// The retried task has the same UUID, let it pass deduplication
+	worker.unmarkTaskSeen(signature)
 
 	// Send the task back to the queue
 	_, err := worker.server.SendTask(signature)
@@ -196,6 +630,9 @@ func (worker *Worker) retryTaskIn(signature *tasks.Signature, retryIn time.Durat
 	if err := worker.server.GetBackend().SetStateRetry(signature); err != nil {
 		return fmt.Errorf("Set state retry error: %s", err)
 	}
+	worker.server.emitEvent(signature, tasks.StateRetry)
+
+	countRetry(signature)
 
 	// Delay task by retryIn duration
 	eta := time.Now().UTC().Add(retryIn)
@@ -203,11 +640,93 @@ func (worker *Worker) retryTaskIn(signature *tasks.Signature, retryIn time.Durat
 
 	log.WARNING.Printf("Task %s failed. Going to retry in %.0f seconds.", signature.UUID, retryIn.Seconds())
 
+	// The retried task has the same UUID, let it pass deduplication
+	worker.unmarkTaskSeen(signature)
+
 	// Send the task back to the queue
 	_, err := worker.server.SendTask(signature)
 	return err
 }
 
+// requeueTask sends the task back to the queue with a delay without changing
+// its state, e.g. when the task's concurrency limit has been reached
+func (worker *Worker) requeueTask(signature *tasks.Signature, delay time.Duration) error {
+	eta := time.Now().UTC().Add(delay)
+	signature.ETA = &eta
+
+	if err := worker.server.GetBroker().Publish(signature); err != nil {
+		return fmt.Errorf("Requeue task error: %s", err)
+	}
+	return nil
+}
+
+// unknownTaskRequeueDelay is how long tasks which are not registered are
+// delayed when sent back to the queue with OnUnknownTaskRequeue
+const unknownTaskRequeueDelay = time.Second
+
+// unknownTask handles a task which is not registered with this worker as
+// configured by OnUnknownTask, the message is acknowledged either way
+func (worker *Worker) unknownTask(signature *tasks.Signature) error {
+	action := worker.server.GetConfig().OnUnknownTask
+	if action == "" {
+		return nil
+	}
+	worker.server.emitUnknownTaskEvent(signature, action)
+
+	// In eager mode publishing processes the task again straight away
+	if _, ok := worker.server.GetBroker().(brokers.EagerMode); ok || action == config.OnUnknownTaskDrop {
+		log.WARNING.Printf("Dropping task %s (%s), it is not registered with this worker", signature.Name, signature.UUID)
+		return nil
+	}
+
+	if action == config.OnUnknownTaskDeadLetter {
+		log.WARNING.Printf("Dead lettering task %s (%s), it is not registered with this worker", signature.Name, signature.UUID)
+		worker.deadLetter(signature, fmt.Errorf("Task %s is not registered", signature.Name))
+		return nil
+	}
+
+	log.WARNING.Printf("Requeueing task %s (%s) in %s, it is not registered with this worker", signature.Name, signature.UUID, unknownTaskRequeueDelay)
+	return worker.requeueTask(signature, unknownTaskRequeueDelay)
+}
+
+// circuitOpen sends the task back to the queue until its circuit breaker
+// lets it be started again
+func (worker *Worker) circuitOpen(signature *tasks.Signature, wait time.Duration) error {
+	// In eager mode publishing processes the task again straight away
+	if _, ok := worker.server.GetBroker().(brokers.EagerMode); ok {
+		return fmt.Errorf("Circuit breaker of task %s is open", signature.Name)
+	}
+
+	log.INFO.Printf("Circuit breaker of task %s is open, requeueing %s in %s", signature.Name, signature.UUID, wait)
+	return worker.requeueTask(signature, wait)
+}
+
+// concurrencyLimitReached sends the task back to the queue until one of the
+// slots of its concurrency limit is free
+func (worker *Worker) concurrencyLimitReached(signature *tasks.Signature) error {
+	// In eager mode publishing processes the task again straight away
+	if _, ok := worker.server.GetBroker().(brokers.EagerMode); ok {
+		return fmt.Errorf("Concurrency limit of task %s reached", signature.Name)
+	}
+
+	delay := worker.server.taskConcurrencyRequeueDelay()
+	log.INFO.Printf("Concurrency limit of task %s reached, requeueing %s in %s", signature.Name, signature.UUID, delay)
+	return worker.requeueTask(signature, delay)
+}
+
+// memoryBudgetReached sends the task back to the queue until its estimated
+// memory fits in the memory budget
+func (worker *Worker) memoryBudgetReached(signature *tasks.Signature) error {
+	// In eager mode publishing processes the task again straight away
+	if _, ok := worker.server.GetBroker().(brokers.EagerMode); ok {
+		return fmt.Errorf("Memory budget reached, task %s is estimated to use %d bytes", signature.Name, signature.EstimatedMemoryBytes)
+	}
+
+	delay := worker.server.taskConcurrencyRequeueDelay()
+	log.INFO.Printf("Memory budget reached, requeueing task %s (%s) estimated to use %d bytes in %s", signature.Name, signature.UUID, signature.EstimatedMemoryBytes, delay)
+	return worker.requeueTask(signature, delay)
+}
+
 // taskSucceeded updates the task state and triggers success callbacks or a
 // chord callback if this was the last task of a group with a chord callback
 func (worker *Worker) taskSucceeded(signature *tasks.Signature, taskResults []*tasks.TaskResult) error {
@@ -215,6 +734,8 @@ func (worker *Worker) taskSucceeded(signature *tasks.Signature, taskResults []*t
 	if err := worker.server.GetBackend().SetStateSuccess(signature, taskResults); err != nil {
 		return fmt.Errorf("Set state success error: %s", err)
 	}
+	worker.server.emitEvent(signature, tasks.StateSuccess)
+	worker.server.notifyWebhook(signature, tasks.StateSuccess, taskResults, nil)
 
 	// Log human readable results of the processed task
 	var debugResults = "[]"
@@ -226,6 +747,12 @@ func (worker *Worker) taskSucceeded(signature *tasks.Signature, taskResults []*t
 	}
 	log.INFO.Printf("Processed task %s. Results = %s", signature.UUID, debugResults)
 
+	// Steps following the task are not sent once its workflow is revoked
+	if worker.isWorkflowRevoked(signature) {
+		worker.revokeWorkflowSteps(signature)
+		return nil
+	}
+
 	// Trigger success callbacks
 
 	for _, successTask := range signature.OnSuccess {
@@ -239,14 +766,39 @@ func (worker *Worker) taskSucceeded(signature *tasks.Signature, taskResults []*t
 			}
 		}
 
+		inheritHeaders(successTask, signature)
 		worker.server.SendTask(successTask)
 	}
 
+	return worker.groupTaskFinished(signature, nil)
+}
+
+// groupTaskFinished triggers the chord callback, or the chord error callback,
+// of the group of the task once the outcome of the chord is decided by its
+// OnPartialFailure policy, taskErr is the error of the task if it failed
+func (worker *Worker) groupTaskFinished(signature *tasks.Signature, taskErr error) error {
 	// If the task was not part of a group, just return
 	if signature.GroupUUID == "" {
 		return nil
 	}
 
+	policy := signature.ChordOnPartialFailure
+	if policy == "" {
+		policy = tasks.ChordFailFast
+	}
+
+	// Failing fast does not wait for the rest of the group
+	if taskErr != nil && policy == tasks.ChordFailFast && signature.ChordCallback != nil {
+		shouldTrigger, err := worker.server.GetBackend().TriggerChord(signature.GroupUUID)
+		if err != nil {
+			return fmt.Errorf("Trigger chord error: %s", err)
+		}
+		if shouldTrigger {
+			worker.sendChordErrorCallback(signature, worker.errorArg(signature, taskErr))
+		}
+		return nil
+	}
+
 	// Check if all task in the group has completed
 	groupCompleted, err := worker.server.GetBackend().GroupCompleted(
 		signature.GroupUUID,
@@ -292,9 +844,11 @@ func (worker *Worker) taskSucceeded(signature *tasks.Signature, taskResults []*t
 	}
 
 	// Append group tasks' return values to chord task if it's not immutable
+	var failures []*tasks.TaskState
 	for _, taskState := range taskStates {
 		if !taskState.IsSuccess() {
-			return nil
+			failures = append(failures, taskState)
+			continue
 		}
 
 		if signature.ChordCallback.Immutable == false {
@@ -308,7 +862,29 @@ func (worker *Worker) taskSucceeded(signature *tasks.Signature, taskResults []*t
 		}
 	}
 
+	if len(failures) > 0 {
+		switch policy {
+		case tasks.ChordSkip:
+			log.WARNING.Printf("Sending chord callback %s without results of %d failed group tasks", signature.ChordCallback.Name, len(failures))
+		case tasks.ChordWait:
+			worker.sendChordErrorCallback(signature, worker.groupErrorArg(failures, len(taskStates)))
+			return nil
+		default:
+			// A failed task sent the error callback already when failing
+			// fast, tasks which finished otherwise, e.g. expired or revoked
+			// ones, fail the chord once the group finished
+			for _, taskState := range failures {
+				if taskState.IsFailure() {
+					return nil
+				}
+			}
+			worker.sendChordErrorCallback(signature, worker.groupErrorArg(failures, len(taskStates)))
+			return nil
+		}
+	}
+
 	// Send the chord task
+	inheritHeaders(signature.ChordCallback, signature)
 	_, err = worker.server.SendTask(signature.ChordCallback)
 	if err != nil {
 		return err
@@ -317,33 +893,286 @@ func (worker *Worker) taskSucceeded(signature *tasks.Signature, taskResults []*t
 	return nil
 }
 
+// sendChordErrorCallback sends the chord error callback of the group task, if
+// any, passing errorArg as the first argument
+func (worker *Worker) sendChordErrorCallback(signature *tasks.Signature, errorArg tasks.Arg) {
+	errorTask := signature.ChordErrorCallback
+	if errorTask == nil {
+		log.WARNING.Printf("Chord of group %s failed and has no error callback", signature.GroupUUID)
+		return
+	}
+
+	errorTask.Args = append([]tasks.Arg{errorArg}, errorTask.Args...)
+	inheritHeaders(errorTask, signature)
+	worker.server.SendTask(errorTask)
+}
+
+// groupErrorArg returns the error passed to the chord error callback when
+// the failed tasks out of taskCount tasks of the group have finished
+func (worker *Worker) groupErrorArg(failed []*tasks.TaskState, taskCount int) tasks.Arg {
+	messages := make([]string, len(failed))
+	for i, taskState := range failed {
+		message := taskState.Error
+		if message == "" {
+			message = taskState.State
+		}
+		messages[i] = fmt.Sprintf("%s: %s", taskState.TaskUUID, message)
+	}
+	message := fmt.Sprintf("%d of %d group tasks failed: %s", len(failed), taskCount, strings.Join(messages, "; "))
+
+	if worker.server.GetConfig().StructuredErrorCallbacks {
+		return tasks.Arg{
+			Type:  "tasks.TaskError",
+			Value: tasks.TaskError{Message: message, TaskUUID: failed[0].TaskUUID},
+		}
+	}
+	return tasks.Arg{Type: "string", Value: message}
+}
+
+// taskExpired updates the task state to EXPIRED without triggering any
+// callbacks but those of its chord, the message is still acknowledged so it is
+// not redelivered
+func (worker *Worker) taskExpired(signature *tasks.Signature) error {
+	log.WARNING.Printf("Dropping task %s (%s), it expired at %s", signature.Name, signature.UUID, signature.ExpiresAt)
+
+	// Backends which cannot record the EXPIRED state record a failure instead
+	backend := worker.server.GetBackend()
+	if expirer, ok := backend.(backends.Expirer); ok {
+		if err := expirer.SetStateExpired(signature); err != nil {
+			return fmt.Errorf("Set state expired error: %s", err)
+		}
+	} else if err := backend.SetStateFailure(signature, fmt.Sprintf("Task expired at %s", signature.ExpiresAt)); err != nil {
+		return fmt.Errorf("Set state failure error: %s", err)
+	}
+	worker.server.emitEvent(signature, tasks.StateExpired)
+	worker.server.GetMetrics().TaskExpired(signature.Name)
+
+	// The chord must not wait for the expired task forever
+	if signature.ChordCallback == nil {
+		return nil
+	}
+	if err := worker.groupTaskFinished(signature, backends.ErrTaskExpired); err != nil {
+		log.ERROR.Printf("Failed finishing chord of group %s after task %s (%s) expired: %s", signature.GroupUUID, signature.Name, signature.UUID, err)
+	}
+	return nil
+}
+
+// isTaskRevoked returns true if the task, or the workflow it is part of, has
+// been revoked
+func (worker *Worker) isTaskRevoked(signature *tasks.Signature) bool {
+	return worker.isRevoked(signature.UUID) || worker.isWorkflowRevoked(signature)
+}
+
+// isWorkflowRevoked returns true if the task is part of a workflow which has
+// been revoked
+func (worker *Worker) isWorkflowRevoked(signature *tasks.Signature) bool {
+	return signature.WorkflowUUID != "" && worker.isRevoked(workflowRevocationID(signature.WorkflowUUID))
+}
+
+// isRevoked returns true if the task or workflow with the UUID has been revoked
+func (worker *Worker) isRevoked(uuid string) bool {
+	revoker, ok := worker.server.GetBackend().(backends.Revoker)
+	if !ok {
+		return false
+	}
+
+	revoked, err := revoker.IsTaskRevoked(uuid)
+	if err != nil {
+		// Rather process the task than drop it by mistake
+		log.WARNING.Printf("Check task %s revoked error: %s", uuid, err)
+		return false
+	}
+	return revoked
+}
+
+// taskRevoked updates the task state to REVOKED without triggering any
+// callbacks but those of its chord, unless the whole workflow was revoked, the
+// message is still acknowledged so it is not redelivered
+func (worker *Worker) taskRevoked(signature *tasks.Signature) error {
+	log.WARNING.Printf("Dropping task %s (%s), it has been revoked", signature.Name, signature.UUID)
+
+	if err := worker.server.GetBackend().(backends.Revoker).SetStateRevoked(signature); err != nil {
+		return fmt.Errorf("Set state revoked error: %s", err)
+	}
+	worker.server.emitEvent(signature, tasks.StateRevoked)
+
+	if worker.isWorkflowRevoked(signature) {
+		worker.revokeWorkflowSteps(signature)
+		return nil
+	}
+
+	// The chord must not wait for the revoked task forever
+	if signature.ChordCallback == nil {
+		return nil
+	}
+	if err := worker.groupTaskFinished(signature, backends.ErrTaskRevoked); err != nil {
+		log.ERROR.Printf("Failed finishing chord of group %s after task %s (%s) was revoked: %s", signature.GroupUUID, signature.Name, signature.UUID, err)
+	}
+	return nil
+}
+
+// revokeWorkflowSteps updates the state of the steps of a revoked workflow
+// following the task to REVOKED, as they are never going to be sent
+func (worker *Worker) revokeWorkflowSteps(signature *tasks.Signature) {
+	revoker := worker.server.GetBackend().(backends.Revoker)
+
+	var (
+		visited = map[*tasks.Signature]bool{signature: true}
+		queue   = nextWorkflowSteps(signature)
+	)
+	for len(queue) > 0 {
+		step := queue[0]
+		queue = queue[1:]
+		if visited[step] {
+			continue
+		}
+		visited[step] = true
+
+		if err := revoker.SetStateRevoked(step); err != nil {
+			log.WARNING.Printf("Set state revoked error: %s", err)
+			continue
+		}
+		worker.server.emitEvent(step, tasks.StateRevoked)
+		queue = append(queue, nextWorkflowSteps(step)...)
+	}
+}
+
+// nextWorkflowSteps returns the success, chord and chord error callbacks of
+// the task which are part of the same workflow
+func nextWorkflowSteps(signature *tasks.Signature) []*tasks.Signature {
+	steps := append([]*tasks.Signature{}, signature.OnSuccess...)
+	if signature.ChordCallback != nil {
+		steps = append(steps, signature.ChordCallback)
+	}
+	if signature.ChordErrorCallback != nil {
+		steps = append(steps, signature.ChordErrorCallback)
+	}
+
+	var sameWorkflow []*tasks.Signature
+	for _, step := range steps {
+		if step.WorkflowUUID == signature.WorkflowUUID {
+			sameWorkflow = append(sameWorkflow, step)
+		}
+	}
+	return sameWorkflow
+}
+
 // taskFailed updates the task state and triggers error callbacks
 func (worker *Worker) taskFailed(signature *tasks.Signature, taskErr error) error {
 	// Update task state to FAILURE
 	if err := worker.server.GetBackend().SetStateFailure(signature, taskErr.Error()); err != nil {
 		return fmt.Errorf("Set state failure error: %s", err)
 	}
+	worker.server.emitEvent(signature, tasks.StateFailure)
+	worker.server.notifyWebhook(signature, tasks.StateFailure, nil, taskErr)
 
 	if worker.errorHandler != nil {
 		worker.errorHandler(taskErr)
 	} else {
-		log.ERROR.Printf("Failed processing %s. Error = %v", signature.UUID, taskErr)
+		log.ERROR.Printf("Failed processing task %s (%s). Error = %v", signature.Name, signature.UUID, taskErr)
 	}
 
 	// Trigger error callbacks
 	for _, errorTask := range signature.OnError {
 		// Pass error as a first argument to error callbacks
-		args := append([]tasks.Arg{{
-			Type:  "string",
-			Value: taskErr.Error(),
-		}}, errorTask.Args...)
+		errorArg := worker.errorArg(signature, taskErr)
+		args := append([]tasks.Arg{errorArg}, errorTask.Args...)
 		errorTask.Args = args
+		inheritHeaders(errorTask, signature)
 		worker.server.SendTask(errorTask)
 	}
 
+	if worker.server.GetConfig().DeadLetterQueue != "" {
+		worker.deadLetter(signature, taskErr)
+	}
+
+	// Failed tasks of plain groups leave the group alone, failed tasks of a
+	// chord can trigger its callback or error callback. The task has been
+	// handled already, so errors doing that are only logged.
+	if signature.ChordCallback == nil {
+		return nil
+	}
+	if err := worker.groupTaskFinished(signature, taskErr); err != nil {
+		log.ERROR.Printf("Failed finishing chord of group %s after task %s (%s) failed: %s", signature.GroupUUID, signature.Name, signature.UUID, err)
+	}
 	return nil
 }
 
+// errorArg returns the error of the failed task passed as the first argument
+// to its error callbacks
+func (worker *Worker) errorArg(signature *tasks.Signature, taskErr error) tasks.Arg {
+	if worker.server.GetConfig().StructuredErrorCallbacks {
+		return tasks.Arg{
+			Type: "tasks.TaskError",
+			Value: tasks.TaskError{
+				Message:  taskErr.Error(),
+				TaskName: signature.Name,
+				TaskUUID: signature.UUID,
+				Retries:  retries(signature),
+			},
+		}
+	}
+	return tasks.Arg{
+		Type:  "string",
+		Value: taskErr.Error(),
+	}
+}
+
+// deadLetter publishes a copy of the failed task to the dead letter queue so
+// it can be inspected and replayed later
+func (worker *Worker) deadLetter(signature *tasks.Signature, taskErr error) {
+	// In eager mode publishing processes the task again straight away
+	if _, ok := worker.server.GetBroker().(brokers.EagerMode); ok {
+		return
+	}
+
+	deadLetter := brokers.NewDeadLetter(signature, worker.server.GetConfig().DeadLetterQueue, taskErr)
+	if err := worker.server.GetBroker().Publish(deadLetter); err != nil {
+		log.ERROR.Printf("Failed publishing task %s (%s) to dead letter queue. Error = %v", signature.Name, signature.UUID, err)
+	}
+}
+
+// markTaskSeen returns false if deduplication is enabled and the task has
+// already been received within the deduplication window, broadcast tasks
+// are never deduplicated as every worker receives the same task
+func (worker *Worker) markTaskSeen(signature *tasks.Signature) bool {
+	cnf := worker.server.GetConfig()
+	if !cnf.EnableDeduplication || isBroadcast(signature) {
+		return true
+	}
+
+	deduplicator, ok := worker.server.GetBackend().(backends.Deduplicator)
+	if !ok {
+		return true
+	}
+
+	window := time.Duration(cnf.DedupWindow) * time.Second
+	if window == 0 {
+		window = time.Hour
+	}
+
+	firstSeen, err := deduplicator.MarkTaskSeen(signature.UUID, window)
+	if err != nil {
+		// Rather process the task again than not at all
+		log.WARNING.Printf("Mark task %s seen error: %s", signature.UUID, err)
+		return true
+	}
+	return firstSeen
+}
+
+// unmarkTaskSeen lets the task pass deduplication again
+func (worker *Worker) unmarkTaskSeen(signature *tasks.Signature) {
+	if !worker.server.GetConfig().EnableDeduplication {
+		return
+	}
+
+	if deduplicator, ok := worker.server.GetBackend().(backends.Deduplicator); ok {
+		if err := deduplicator.UnmarkTaskSeen(signature.UUID); err != nil {
+			log.WARNING.Printf("Unmark task %s seen error: %s", signature.UUID, err)
+		}
+	}
+}
+
 // Returns true if the worker uses AMQP backend
 func (worker *Worker) hasAMQPBackend() bool {
 	_, ok := worker.server.GetBackend().(*backends.AMQPBackend)
@@ -355,3 +1184,10 @@ func (worker *Worker) hasAMQPBackend() bool {
 func (worker *Worker) ErrorHandler(handler func(err error)) {
 	worker.errorHandler = handler
 }
+
+// IdleHandler sets a handler called when the worker has been idle for
+// IdleTimeout, instead of stopping the worker, e.g. to scale it down from
+// outside, it is called again after every further IdleTimeout of idleness
+func (worker *Worker) IdleHandler(handler func()) {
+	worker.idleHandler = handler
+}